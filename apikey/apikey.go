@@ -0,0 +1,130 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/efecan/vatansoft-case/models"
+	"gorm.io/gorm"
+)
+
+// Prefix starts every key, so keys are easy to recognize in headers and to
+// find with secret scanners.
+const Prefix = "vsk_"
+
+// touchInterval is how stale LastUsedAt may get before a request refreshes it.
+const touchInterval = time.Minute
+
+var (
+	ErrInvalid      = errors.New("apikey: invalid key")
+	ErrRevoked      = errors.New("apikey: key revoked")
+	ErrExpired      = errors.New("apikey: key expired")
+	ErrIPNotAllowed = errors.New("apikey: client IP not allowed")
+)
+
+// IsKey reports whether token looks like an API key rather than a JWT.
+func IsKey(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
+
+// Generate returns a new key as handed to the client, the public prefix that
+// identifies it and the hash to store. The key reads vsk_<prefix>_<secret>.
+func Generate() (key, prefix, hash string, err error) {
+	b := make([]byte, 4+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(b[:4])
+	secret := hex.EncodeToString(b[4:])
+	return Prefix + prefix + "_" + secret, prefix, hashSecret(secret), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeIPs turns single addresses into /32 or /128 ranges and validates
+// CIDR ranges. It returns the first invalid entry, if any.
+func NormalizeIPs(entries []string) ([]string, string) {
+	normalized := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			entry = (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}).String()
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, entry
+		}
+		normalized = append(normalized, network.String())
+	}
+	return normalized, ""
+}
+
+// Authenticate looks up the key, checks it is usable from ip and records the
+// use.
+func Authenticate(db *gorm.DB, key, ip string) (models.APIKey, error) {
+	var k models.APIKey
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(key, Prefix), "_")
+	if !ok || !IsKey(key) || prefix == "" || secret == "" {
+		return k, ErrInvalid
+	}
+	err := db.Where("prefix = ?", prefix).First(&k).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return k, ErrInvalid
+	}
+	if err != nil {
+		return k, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(k.SecretHash)) != 1 {
+		return k, ErrInvalid
+	}
+
+	now := time.Now().UTC()
+	if k.RevokedAt != nil {
+		return k, ErrRevoked
+	}
+	if k.ExpiresAt != nil && now.After(*k.ExpiresAt) {
+		return k, ErrExpired
+	}
+	if !allowed(k.AllowedIPs, ip) {
+		return k, ErrIPNotAllowed
+	}
+
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= touchInterval || k.LastUsedIP != ip {
+		k.LastUsedAt, k.LastUsedIP = &now, ip
+		// Only bookkeeping; a failed update mustn't fail the request.
+		if err := db.Model(&k).Select("last_used_at", "last_used_ip").Updates(&k).Error; err != nil {
+			log.Printf("⚠️ Could not update API key %d: %v", k.ID, err)
+		}
+	}
+	return k, nil
+}
+
+func allowed(ranges []string, ip string) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, r := range ranges {
+		if _, network, err := net.ParseCIDR(r); err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package apperrors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Kind classifies an error and decides the HTTP status it is reported with.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindInvalid
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindUnprocessable
+	KindTooManyRequests
+	KindPreconditionFailed
+	KindPreconditionRequired
+)
+
+func (k Kind) Status() int {
+	switch k {
+	case KindInvalid:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case KindTooManyRequests:
+		return http.StatusTooManyRequests
+	case KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case KindPreconditionRequired:
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Postgres error codes we translate into client errors.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgNotNullViolation    = "23502"
+)
+
+// FieldError describes a problem with a single request field. Message is
+// filled in the caller's language when the error is written.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	validation validator.FieldError
+	key        string
+	params     map[string]string
+}
+
+func newFieldError(field, code, key string, params map[string]string) FieldError {
+	if params == nil {
+		params = map[string]string{}
+	}
+	params["field"] = field
+	return FieldError{Field: field, Code: code, Message: i18n.Message(i18n.English, key, params), key: key, params: params}
+}
+
+// localize renders the field message in lang using the validator or message catalog.
+func (f FieldError) localize(lang string) FieldError {
+	switch {
+	case f.validation != nil:
+		if trans := i18n.Translator(lang); trans != nil {
+			f.Message = f.validation.Translate(trans)
+		}
+	case f.key != "":
+		f.Message = i18n.Message(lang, f.key, f.params)
+	}
+	return f
+}
+
+// Error is the error type handlers hand to Abort. Code is a stable,
+// machine-readable identifier; Message is safe to show to clients. Err keeps
+// the underlying cause for logs and is never sent in a response.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// LocalizedFields returns the field errors with messages in lang.
+func (e *Error) LocalizedFields(lang string) []FieldError {
+	var fields []FieldError
+	for _, f := range e.Fields {
+		fields = append(fields, f.localize(lang))
+	}
+	return fields
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func Wrap(err error, kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, Err: err}
+}
+
+func BadRequest(code, message string) *Error {
+	return New(KindInvalid, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+func Unprocessable(code, message string) *Error {
+	return New(KindUnprocessable, code, message)
+}
+
+func TooManyRequests(code, message string) *Error {
+	return New(KindTooManyRequests, code, message)
+}
+
+func PreconditionFailed(code, message string) *Error {
+	return New(KindPreconditionFailed, code, message)
+}
+
+func PreconditionRequired(code, message string) *Error {
+	return New(KindPreconditionRequired, code, message)
+}
+
+// InvalidField reports a single invalid field as a validation error. key is
+// the catalog key of the field message, which may use {field}.
+func InvalidField(field, code, key string) *Error {
+	return &Error{
+		Kind:    KindInvalid,
+		Code:    "validation_failed",
+		Message: "One or more fields are invalid",
+		Fields:  []FieldError{newFieldError(field, code, key, nil)},
+	}
+}
+
+// FieldProblem describes a problem with field for InvalidFields. key is the
+// catalog key of the message, which may use {field} and the given params.
+func FieldProblem(field, code, key string, params map[string]string) FieldError {
+	return newFieldError(field, code, key, params)
+}
+
+// InvalidFields reports several field problems as one 400 with its own code.
+func InvalidFields(code, message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindInvalid, Code: code, Message: message, Fields: fields}
+}
+
+func Internal(err error, code, message string) *Error {
+	return Wrap(err, KindInternal, code, message)
+}
+
+// Validation converts errors returned by ShouldBindJSON and friends into a
+// 400 carrying one FieldError per failed validation rule.
+func Validation(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:      fieldPath(fe),
+				Code:       fe.Tag(),
+				Message:    fe.Error(),
+				validation: fe,
+			})
+		}
+		return &Error{
+			Kind:    KindInvalid,
+			Code:    "validation_failed",
+			Message: "One or more fields are invalid",
+			Fields:  fields,
+			Err:     err,
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &Error{
+			Kind:    KindInvalid,
+			Code:    "validation_failed",
+			Message: "One or more fields are invalid",
+			Fields:  []FieldError{newFieldError(typeErr.Field, "type", "field_type", map[string]string{"type": typeErr.Type.String()})},
+			Err:     err,
+		}
+	}
+	if errors.As(err, &syntaxErr) {
+		return Wrap(err, KindInvalid, "malformed_body", "Request body is not valid JSON")
+	}
+
+	return Wrap(err, KindInvalid, "invalid_request", "Invalid request")
+}
+
+// fieldPath drops the root struct name from the validator namespace, so
+// "NewUserRequest.email" becomes "email" and nested fields keep their path.
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+// FromDB maps a GORM/Postgres error onto an Error. Unique violations become a
+// 409 naming the offending field, missing records a 404, and anything we don't
+// recognise a 500 with the given message.
+func FromDB(err error, code, message string) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Wrap(err, KindNotFound, "not_found", "Record not found")
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		field := constraintField(pgErr)
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			e := Wrap(err, KindConflict, "already_exists", "A record with the same value already exists")
+			if field != "" {
+				e.Fields = []FieldError{newFieldError(field, "unique", "field_unique", nil)}
+			}
+			return e
+		case pgForeignKeyViolation:
+			e := Wrap(err, KindInvalid, "invalid_reference", "A referenced record does not exist")
+			if field != "" {
+				e.Fields = []FieldError{newFieldError(field, "reference", "field_reference", nil)}
+			}
+			return e
+		case pgNotNullViolation:
+			e := Wrap(err, KindInvalid, "validation_failed", "One or more fields are invalid")
+			if pgErr.ColumnName != "" {
+				e.Fields = []FieldError{newFieldError(pgErr.ColumnName, "required", "field_required", nil)}
+			}
+			return e
+		}
+	}
+
+	return Internal(err, code, message)
+}
+
+// constraintField guesses the column behind a constraint from GORM's naming
+// scheme, e.g. "uni_users_email" or "idx_users_email" on table "users".
+func constraintField(pgErr *pgconn.PgError) string {
+	if pgErr.ColumnName != "" {
+		return pgErr.ColumnName
+	}
+	name := pgErr.ConstraintName
+	for _, prefix := range []string{"uni_", "idx_", "fk_"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	if pgErr.TableName != "" {
+		name = strings.TrimPrefix(name, pgErr.TableName+"_")
+	}
+	// Partial unique indexes over non-deleted rows are named <column>_active.
+	name = strings.TrimSuffix(name, "_active")
+	if name == pgErr.ConstraintName {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,59 @@
+package apperrors
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body written for every error response.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Abort writes err as application/problem+json and stops the handler chain.
+// Errors that aren't *Error are treated as internal and their text is only logged.
+// Detail and field messages are localized from the catalog entry for the code,
+// falling back to the message the error was created with.
+func Abort(c *gin.Context, err error) {
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		appErr = Internal(err, "internal_error", "Internal server error")
+	}
+
+	status := appErr.Kind.Status()
+	if status >= http.StatusInternalServerError && appErr.Err != nil {
+		log.Printf("❌ %s %s: %v", c.Request.Method, c.Request.URL.Path, appErr)
+	}
+
+	lang := i18n.Lang(c)
+	detail, ok := i18n.Lookup(lang, appErr.Code)
+	if !ok {
+		detail = appErr.Message
+	}
+	fields := appErr.LocalizedFields(lang)
+
+	c.Error(appErr)
+	c.Header("Content-Type", ProblemContentType)
+	c.Header("Content-Language", lang)
+	c.AbortWithStatusJSON(status, Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+		Code:     appErr.Code,
+		Errors:   fields,
+	})
+}
@@ -0,0 +1,362 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// advisoryLockClass namespaces the per-hospital advisory locks that serialize
+// writes to a hospital's hash chain.
+const advisoryLockClass = 0x61756474 // "audt"
+
+// Entry describes one change to be recorded. Before and After are snapshots of
+// the entity (usually the model struct) and may be nil for creates and deletes.
+// ActorID and HospitalID default to the authenticated user when left zero, and
+// ImpersonatorID to the platform admin impersonating them and APIKeyID to the
+// API key used, if any.
+type Entry struct {
+	Action         string
+	EntityType     string
+	EntityID       uint
+	Before         interface{}
+	After          interface{}
+	ActorID        uint
+	HospitalID     uint
+	ImpersonatorID uint
+	APIKeyID       uint
+}
+
+// hashInput lists everything covered by an entry's hash. New fields must be
+// tagged omitempty so hashes of older entries stay valid.
+type hashInput struct {
+	PrevHash   string          `json:"prev_hash"`
+	CreatedAt  string          `json:"created_at"`
+	ActorID    uint            `json:"actor_id"`
+	HospitalID uint            `json:"hospital_id"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   uint            `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Diff       json.RawMessage `json:"diff,omitempty"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+	// Added with impersonation.
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
+	// Added with API keys.
+	APIKeyID uint `json:"api_key_id,omitempty"`
+}
+
+// Record appends an entry to the audit log using tx, so it commits or rolls
+// back together with the change it describes.
+func Record(tx *gorm.DB, c *gin.Context, e Entry) error {
+	if e.ActorID == 0 {
+		e.ActorID = uint(c.GetInt("userID"))
+	}
+	if e.HospitalID == 0 {
+		e.HospitalID = uint(c.GetInt("hospitalID"))
+	}
+	if e.ImpersonatorID == 0 {
+		e.ImpersonatorID = uint(c.GetInt("impersonatorID"))
+	}
+	if e.APIKeyID == 0 {
+		e.APIKeyID = uint(c.GetInt("apiKeyID"))
+	}
+	return record(tx, e, c.ClientIP(), c.GetString("requestID"))
+}
+
+// RecordSystem appends an entry for a change made outside of a request, such
+// as a scheduled job. ActorID is left as given, zero meaning the system.
+func RecordSystem(tx *gorm.DB, e Entry) error {
+	return record(tx, e, "", "")
+}
+
+// RecordDetached appends an entry for work a request started but that runs
+// after it, like a background import. The entry carries the actor, hospital,
+// impersonator and API key it is given, and the IP address and request ID of
+// the original request.
+func RecordDetached(tx *gorm.DB, e Entry, ip, requestID string) error {
+	return record(tx, e, ip, requestID)
+}
+
+func record(tx *gorm.DB, e Entry, ip, requestID string) error {
+	beforeFields, err := snapshot(e.Before)
+	if err != nil {
+		return fmt.Errorf("audit: snapshot before: %w", err)
+	}
+	afterFields, err := snapshot(e.After)
+	if err != nil {
+		return fmt.Errorf("audit: snapshot after: %w", err)
+	}
+	// Diff the plain values: sealing the same value twice gives different
+	// ciphertexts.
+	changes := diffSnapshots(beforeFields, afterFields)
+
+	s, err := newSealer(tx, e, beforeFields, afterFields)
+	if err != nil {
+		return fmt.Errorf("audit: load subject key: %w", err)
+	}
+	if err := s.sealSnapshot(beforeFields); err != nil {
+		return fmt.Errorf("audit: seal before: %w", err)
+	}
+	if err := s.sealSnapshot(afterFields); err != nil {
+		return fmt.Errorf("audit: seal after: %w", err)
+	}
+	if err := s.sealDiff(changes); err != nil {
+		return fmt.Errorf("audit: seal diff: %w", err)
+	}
+	before, err := encode(beforeFields)
+	if err != nil {
+		return fmt.Errorf("audit: encode before: %w", err)
+	}
+	after, err := encode(afterFields)
+	if err != nil {
+		return fmt.Errorf("audit: encode after: %w", err)
+	}
+	var diff json.RawMessage
+	if changes != nil {
+		if diff, err = json.Marshal(changes); err != nil {
+			return fmt.Errorf("audit: encode diff: %w", err)
+		}
+	}
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", advisoryLockClass, e.HospitalID).Error; err != nil {
+		return fmt.Errorf("audit: lock chain: %w", err)
+	}
+
+	var prev models.AuditLog
+	prevHash := ""
+	err = tx.Where("hospital_id = ?", e.HospitalID).Order("id DESC").Select("hash").Take(&prev).Error
+	if err == nil {
+		prevHash = prev.Hash
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("audit: load previous entry: %w", err)
+	}
+
+	entry := models.AuditLog{
+		CreatedAt:      time.Now().UTC().Truncate(time.Microsecond),
+		ActorID:        e.ActorID,
+		HospitalID:     e.HospitalID,
+		Action:         e.Action,
+		EntityType:     e.EntityType,
+		EntityID:       e.EntityID,
+		Before:         before,
+		After:          after,
+		Diff:           diff,
+		IP:             ip,
+		RequestID:      requestID,
+		PrevHash:       prevHash,
+		ImpersonatorID: e.ImpersonatorID,
+		APIKeyID:       e.APIKeyID,
+	}
+	entry.Hash, err = computeHash(entry)
+	if err != nil {
+		return fmt.Errorf("audit: hash entry: %w", err)
+	}
+
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("audit: insert entry: %w", err)
+	}
+	return nil
+}
+
+// VerifyResult reports whether a hospital's chain is intact. BrokenAt is the
+// ID of the first entry whose hash or link doesn't match.
+type VerifyResult struct {
+	Valid    bool  `json:"valid"`
+	Checked  int64 `json:"checked"`
+	BrokenAt uint  `json:"broken_at,omitempty"`
+}
+
+// Verify walks a hospital's audit chain in insertion order and recomputes every hash.
+func Verify(db *gorm.DB, hospitalID uint) (VerifyResult, error) {
+	result := VerifyResult{Valid: true}
+	prevHash := ""
+
+	var batch []models.AuditLog
+	err := db.Where("hospital_id = ?", hospitalID).Order("id").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			result.Checked++
+			hash, err := computeHash(entry)
+			if err != nil {
+				return err
+			}
+			if entry.PrevHash != prevHash || entry.Hash != hash {
+				result.Valid = false
+				result.BrokenAt = entry.ID
+				return errChainBroken
+			}
+			prevHash = entry.Hash
+		}
+		return nil
+	}).Error
+	if errors.Is(err, errChainBroken) {
+		return result, nil
+	}
+	return result, err
+}
+
+var errChainBroken = errors.New("audit chain broken")
+
+func computeHash(entry models.AuditLog) (string, error) {
+	var err error
+	input := hashInput{
+		PrevHash:       entry.PrevHash,
+		CreatedAt:      entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ActorID:        entry.ActorID,
+		HospitalID:     entry.HospitalID,
+		Action:         entry.Action,
+		EntityType:     entry.EntityType,
+		EntityID:       entry.EntityID,
+		IP:             entry.IP,
+		RequestID:      entry.RequestID,
+		ImpersonatorID: entry.ImpersonatorID,
+		APIKeyID:       entry.APIKeyID,
+	}
+	// Postgres stores jsonb in its own normalized form, so hash a canonical
+	// encoding rather than the bytes we happened to insert.
+	if input.Before, err = canonical(entry.Before); err != nil {
+		return "", err
+	}
+	if input.After, err = canonical(entry.After); err != nil {
+		return "", err
+	}
+	if input.Diff, err = canonical(entry.Diff); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func canonical(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// ignoredFields are left out of snapshots: bookkeeping that changes on every
+// save and would only add noise to diffs.
+var ignoredFields = map[string]bool{
+	"UpdatedAt": true,
+}
+
+// personalFields lists, per entity type, the fields holding personal data.
+// Audit entries can never be changed, so these are sealed with a key of the
+// data subject that erasing them destroys (see ForgetSubject).
+var personalFields = map[string]map[string]bool{
+	"user":    {"name": true, "surname": true, "email": true, "tckn": true, "phone": true},
+	"doctor":  {"name": true, "email": true},
+	"session": {"device": true, "user_agent": true, "ip": true},
+}
+
+// snapshot flattens v into its scalar fields as they encode to JSON. Nested
+// structs and slices (preloaded associations) are dropped; foreign key IDs
+// already identify them.
+func snapshot(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for k, value := range fields {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			delete(fields, k)
+			continue
+		}
+		if ignoredFields[k] {
+			delete(fields, k)
+		}
+	}
+	return fields, nil
+}
+
+// encode marshals a snapshot, leaving nil ones out.
+func encode(fields map[string]interface{}) (json.RawMessage, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	return json.Marshal(fields)
+}
+
+type change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// diffSnapshots lists the fields whose values differ between two snapshots.
+func diffSnapshots(b, a map[string]interface{}) map[string]change {
+	if b == nil || a == nil {
+		return nil
+	}
+
+	changes := map[string]change{}
+	for k, to := range a {
+		if from, ok := b[k]; !ok || !reflect.DeepEqual(from, to) {
+			changes[k] = change{From: b[k], To: to}
+		}
+	}
+	for k, from := range b {
+		if _, ok := a[k]; !ok {
+			changes[k] = change{From: from}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// InstallTriggers makes audit_logs append-only at the database level, so rows
+// can't be changed or removed even by code that bypasses this package.
+func InstallTriggers(db *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_logs is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs`,
+		`CREATE TRIGGER audit_logs_append_only
+			BEFORE UPDATE OR DELETE ON audit_logs
+			FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only()`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package audit
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/efecan/vatansoft-case/encryption"
+	"github.com/efecan/vatansoft-case/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErasedValue replaces personal data whose subject was erased when entries
+// are read back.
+const ErasedValue = "[erased]"
+
+// sealedPrefix marks a personal field sealed with its subject's key, as
+// "pii:<subject type>:<subject id>:<base64 nonce and ciphertext>".
+const sealedPrefix = "pii:"
+
+// subjectOf returns the data subject whose personal data a snapshot of an
+// entityType entity holds, or an empty type if there is none.
+func subjectOf(entityType string, entityID uint, fields map[string]interface{}) (string, uint) {
+	switch entityType {
+	case "user", "doctor":
+		return entityType, entityID
+	case "session":
+		userID, _ := fields["user_id"].(float64)
+		return "user", uint(userID)
+	}
+	return "", 0
+}
+
+// subjectKey returns the key of a data subject, creating it if create is set.
+// A nil key without error means there is none: the subject was erased.
+func subjectKey(tx *gorm.DB, subjectType string, subjectID uint, create bool) ([]byte, error) {
+	if create {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		k := models.AuditSubjectKey{SubjectType: subjectType, SubjectID: subjectID, Key: base64.StdEncoding.EncodeToString(raw)}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&k).Error; err != nil {
+			return nil, err
+		}
+	}
+	var k models.AuditSubjectKey
+	err := tx.Where("subject_type = ? AND subject_id = ?", subjectType, subjectID).Take(&k).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(k.Key)
+}
+
+// ForgetSubject destroys the key of a data subject, so the personal data
+// audit entries hold about them can no longer be read. Call it when the
+// subject is erased.
+func ForgetSubject(tx *gorm.DB, subjectType string, subjectID uint) error {
+	return tx.Where("subject_type = ? AND subject_id = ?", subjectType, subjectID).Delete(&models.AuditSubjectKey{}).Error
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealer protects the personal fields of one entry's snapshots.
+type sealer struct {
+	fields      map[string]bool
+	subjectType string
+	subjectID   uint
+	aead        cipher.AEAD
+}
+
+func newSealer(tx *gorm.DB, e Entry, before, after map[string]interface{}) (*sealer, error) {
+	s := &sealer{fields: personalFields[e.EntityType]}
+	// Entries without snapshots, like those of an erasure, mustn't bring a
+	// destroyed key back.
+	if len(s.fields) == 0 || (before == nil && after == nil) {
+		return s, nil
+	}
+	fields := after
+	if fields == nil {
+		fields = before
+	}
+	s.subjectType, s.subjectID = subjectOf(e.EntityType, e.EntityID, fields)
+	if s.subjectID == 0 {
+		return s, nil
+	}
+	key, err := subjectKey(tx, s.subjectType, s.subjectID, true)
+	if err != nil {
+		return nil, err
+	}
+	s.aead, err = newGCM(key)
+	return s, err
+}
+
+// seal encrypts value of field with the subject's key. Without a subject it
+// falls back to a keyed fingerprint.
+func (s *sealer) seal(field string, value interface{}) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok || !s.fields[field] || str == "" {
+		return value, nil
+	}
+	if s.aead == nil {
+		if index := encryption.BlindIndex(field, str); index != nil {
+			return "hmac:" + (*index)[:16], nil
+		}
+		return value, nil
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(str), []byte(field))
+	return fmt.Sprintf("%s%s:%d:%s", sealedPrefix, s.subjectType, s.subjectID, base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+func (s *sealer) sealSnapshot(fields map[string]interface{}) error {
+	for k, v := range fields {
+		sealed, err := s.seal(k, v)
+		if err != nil {
+			return err
+		}
+		fields[k] = sealed
+	}
+	return nil
+}
+
+func (s *sealer) sealDiff(changes map[string]change) error {
+	for k, ch := range changes {
+		from, err := s.seal(k, ch.From)
+		if err != nil {
+			return err
+		}
+		to, err := s.seal(k, ch.To)
+		if err != nil {
+			return err
+		}
+		changes[k] = change{From: from, To: to}
+	}
+	return nil
+}
+
+// MaskFunc lets a caller mask a decrypted personal field of a subject it may
+// not see in full.
+type MaskFunc func(subjectType string, subjectID uint, field, value string) string
+
+// opener decrypts sealed fields, loading each subject's key once.
+type opener struct {
+	db   *gorm.DB
+	mask MaskFunc
+	keys map[string]cipher.AEAD
+}
+
+func (o *opener) open(field, value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, sealedPrefix)
+	if !ok {
+		return value, nil
+	}
+	parts := strings.SplitN(rest, ":", 3)
+	if len(parts) != 3 {
+		return ErasedValue, nil
+	}
+	subject := parts[0] + ":" + parts[1]
+	aead, seen := o.keys[subject]
+	if !seen {
+		id, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return ErasedValue, nil
+		}
+		key, err := subjectKey(o.db, parts[0], uint(id), false)
+		if err != nil {
+			return "", err
+		}
+		if key != nil {
+			if aead, err = newGCM(key); err != nil {
+				return "", err
+			}
+		}
+		o.keys[subject] = aead
+	}
+	if aead == nil {
+		return ErasedValue, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return ErasedValue, nil
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return ErasedValue, nil
+	}
+	if o.mask != nil {
+		id, _ := strconv.ParseUint(parts[1], 10, 64)
+		return o.mask(parts[0], uint(id), field, string(plain)), nil
+	}
+	return string(plain), nil
+}
+
+// openJSON decrypts the sealed fields of a snapshot or diff. field is the
+// field the value at hand belongs to.
+func (o *opener) openJSON(field string, v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case string:
+		return o.open(field, value)
+	case map[string]interface{}:
+		for k, inner := range value {
+			// Diffs nest the values of a field under from and to.
+			name := k
+			if k == "from" || k == "to" {
+				name = field
+			}
+			opened, err := o.openJSON(name, inner)
+			if err != nil {
+				return nil, err
+			}
+			value[k] = opened
+		}
+	}
+	return v, nil
+}
+
+func (o *opener) openRaw(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || !strings.Contains(string(raw), sealedPrefix) {
+		return raw, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	v, err := o.openJSON("", v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// Reveal decrypts the personal data in entries for display, passing it
+// through mask if given and replacing that of erased subjects with
+// ErasedValue. The entries are changed in place and no longer match their
+// hashes, so never verify or store them afterwards.
+func Reveal(db *gorm.DB, entries []models.AuditLog, mask MaskFunc) error {
+	o := &opener{db: db, mask: mask, keys: map[string]cipher.AEAD{}}
+	for i := range entries {
+		var err error
+		if entries[i].Before, err = o.openRaw(entries[i].Before); err != nil {
+			return err
+		}
+		if entries[i].After, err = o.openRaw(entries[i].After); err != nil {
+			return err
+		}
+		if entries[i].Diff, err = o.openRaw(entries[i].Diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package authn checks login credentials against a chain of authenticators,
+// such as a hospital's LDAP directory followed by local bcrypt passwords.
+package authn
+
+import (
+	"context"
+	"errors"
+
+	"github.com/efecan/vatansoft-case/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSkip tells the chain an authenticator doesn't handle the user, so
+	// the next one should try.
+	ErrSkip = errors.New("authn: not applicable")
+	// ErrInvalidCredentials ends the chain: the user is known but the
+	// password is wrong.
+	ErrInvalidCredentials = errors.New("authn: invalid credentials")
+	// ErrNoRole means the user authenticated but none of their groups maps
+	// to a role and there is no default role.
+	ErrNoRole = errors.New("authn: no role maps to the user")
+	// ErrUnavailable means the directory that owns the user can't be
+	// reached. Such users can't fall back to a local password.
+	ErrUnavailable = errors.New("authn: directory unavailable")
+)
+
+// Account is a user's account at an external directory, as reported when they
+// signed in there. The caller links it to a user, or provisions one.
+type Account struct {
+	Provider string
+	Issuer   string
+	Subject  string
+	Email    string
+	Name     string
+	Surname  string
+	Phone    string
+	// Role is the user's role here, mapped from their groups.
+	Role       string
+	HospitalID uint
+	// AutoProvision creates a user with the default profession group and
+	// title for accounts that don't match one yet.
+	AutoProvision     bool
+	ProfessionGroupID uint
+	TitleID           uint
+}
+
+// Result is what a successful authenticator found: a local user, or an
+// external account.
+type Result struct {
+	User    *models.User
+	Account *Account
+}
+
+// Authenticator checks an email and password. It returns ErrSkip for users it
+// doesn't handle.
+type Authenticator interface {
+	Authenticate(ctx context.Context, email, password string) (Result, error)
+}
+
+// Chain tries its authenticators in order until one handles the user.
+type Chain []Authenticator
+
+// Authenticate returns the result of the first authenticator that doesn't
+// skip the user, or ErrInvalidCredentials if all of them do.
+func (ch Chain) Authenticate(ctx context.Context, email, password string) (Result, error) {
+	for _, a := range ch {
+		res, err := a.Authenticate(ctx, email, password)
+		if errors.Is(err, ErrSkip) {
+			continue
+		}
+		return res, err
+	}
+	return Result{}, ErrInvalidCredentials
+}
+
+// Local checks the bcrypt password hashes of active users, and of admins
+// whose hospital registration isn't verified yet.
+type Local struct {
+	DB *gorm.DB
+}
+
+// Authenticate implements Authenticator. Pending users haven't set a password
+// yet; they log in once they accept their invitation.
+func (l Local) Authenticate(ctx context.Context, email, password string) (Result, error) {
+	var user models.User
+	statuses := []string{models.UserStatusActive, models.UserStatusUnverified}
+	err := l.DB.WithContext(ctx).Where("email = ? AND status IN ?", email, statuses).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Result{}, ErrSkip
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return Result{}, ErrInvalidCredentials
+	}
+	return Result{User: &user}, nil
+}
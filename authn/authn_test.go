@@ -0,0 +1,88 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/efecan/vatansoft-case/models"
+)
+
+// fakeAuthenticator returns a fixed result and counts its calls.
+type fakeAuthenticator struct {
+	res   Result
+	err   error
+	calls *int
+}
+
+func (f fakeAuthenticator) Authenticate(ctx context.Context, email, password string) (Result, error) {
+	*f.calls++
+	return f.res, f.err
+}
+
+func TestChain(t *testing.T) {
+	local := &models.User{Email: "local@example.com"}
+	account := &Account{Provider: ProviderLDAP, Email: "ayse@example.com"}
+	unavailable := errors.New("directory down")
+	tests := []struct {
+		name      string
+		results   []fakeAuthenticator
+		wantCalls []int
+		want      Result
+		wantErr   error
+	}{
+		{
+			name:      "falls back to local on ErrSkip",
+			results:   []fakeAuthenticator{{err: ErrSkip}, {res: Result{User: local}}},
+			wantCalls: []int{1, 1},
+			want:      Result{User: local},
+		},
+		{
+			name:      "first match wins",
+			results:   []fakeAuthenticator{{res: Result{Account: account}}, {res: Result{User: local}}},
+			wantCalls: []int{1, 0},
+			want:      Result{Account: account},
+		},
+		{
+			name:      "invalid credentials end the chain",
+			results:   []fakeAuthenticator{{err: ErrInvalidCredentials}, {res: Result{User: local}}},
+			wantCalls: []int{1, 0},
+			wantErr:   ErrInvalidCredentials,
+		},
+		{
+			name:      "other errors end the chain",
+			results:   []fakeAuthenticator{{err: unavailable}, {res: Result{User: local}}},
+			wantCalls: []int{1, 0},
+			wantErr:   unavailable,
+		},
+		{
+			name:      "everyone skips",
+			results:   []fakeAuthenticator{{err: ErrSkip}, {err: ErrSkip}},
+			wantCalls: []int{1, 1},
+			wantErr:   ErrInvalidCredentials,
+		},
+		{
+			name:    "empty chain",
+			wantErr: ErrInvalidCredentials,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make([]int, len(tt.results))
+			var chain Chain
+			for i, f := range tt.results {
+				f.calls = &calls[i]
+				chain = append(chain, f)
+			}
+			res, err := chain.Authenticate(context.Background(), "user@example.com", "secret")
+			if !errors.Is(err, tt.wantErr) || res != tt.want {
+				t.Fatalf("got %+v, %v; want %+v, %v", res, err, tt.want, tt.wantErr)
+			}
+			for i, want := range tt.wantCalls {
+				if calls[i] != want {
+					t.Fatalf("authenticator %d called %d times, want %d", i, calls[i], want)
+				}
+			}
+		})
+	}
+}
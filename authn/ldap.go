@@ -0,0 +1,262 @@
+package authn
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/netguard"
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+// ProviderLDAP names directory logins in models.ExternalIdentity.
+const ProviderLDAP = "ldap"
+
+// DefaultUserFilter finds Active Directory users by mail or UPN.
+const DefaultUserFilter = "(&(objectClass=user)(|(mail={email})(userPrincipalName={email})))"
+
+const defaultTimeout = 10 * time.Second
+
+var errNoEntry = errors.New("authn: no directory entry")
+
+// LDAP authenticates staff of hospitals with a directory by binding as them
+// with their password. Users the directory doesn't know are skipped, so local
+// accounts such as the hospital's first admin keep working. Users already
+// linked to the directory are not: when it can't find them or can't be
+// reached, their old local password mustn't let them in.
+type LDAP struct {
+	DB *gorm.DB
+	// Timeout bounds connecting to and each request to a directory.
+	Timeout time.Duration
+}
+
+// Authenticate implements Authenticator.
+func (l LDAP) Authenticate(ctx context.Context, email, password string) (Result, error) {
+	email = strings.TrimSpace(email)
+	db := l.DB.WithContext(ctx)
+	d, linked, err := directoryFor(db, email)
+	if err != nil || d == nil {
+		return Result{}, err
+	}
+	// A bind with an empty password is an anonymous one, which directories
+	// happily accept.
+	if password == "" {
+		return Result{}, ErrInvalidCredentials
+	}
+
+	timeout := l.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	unavailable := func(err error) (Result, error) {
+		if linked {
+			return Result{}, fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		log.Printf("⚠️ LDAP directory of hospital %d failed, trying a local login: %v", d.HospitalID, err)
+		return Result{}, ErrSkip
+	}
+
+	conn, err := Dial(*d, timeout)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer conn.Close()
+
+	entry, err := findEntry(conn, *d, email, timeout)
+	switch {
+	case errors.Is(err, errNoEntry) && linked:
+		return Result{}, ErrInvalidCredentials
+	case errors.Is(err, errNoEntry):
+		return Result{}, ErrSkip
+	case err != nil:
+		return unavailable(err)
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return Result{}, ErrInvalidCredentials
+		}
+		return unavailable(err)
+	}
+
+	attrs := attributes(d.Attributes)
+	role, err := MapRole(d.RoleMapping, d.DefaultRole, entry.GetAttributeValues(attrs.Groups))
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Account: &Account{
+		Provider:          ProviderLDAP,
+		Issuer:            fmt.Sprintf("hospital:%d", d.HospitalID),
+		Subject:           entryID(entry, attrs.ID),
+		Email:             email,
+		Name:              strings.TrimSpace(entry.GetAttributeValue(attrs.Name)),
+		Surname:           strings.TrimSpace(entry.GetAttributeValue(attrs.Surname)),
+		Phone:             strings.TrimSpace(entry.GetAttributeValue(attrs.Phone)),
+		Role:              role,
+		HospitalID:        d.HospitalID,
+		AutoProvision:     d.AutoProvision,
+		ProfessionGroupID: d.DefaultProfessionGroupID,
+		TitleID:           d.DefaultTitleID,
+	}}, nil
+}
+
+// directoryFor returns the enabled directory the user with email logs in
+// against, if any, and whether they are linked to it already. Users we don't
+// know yet are routed by their email domain when the directory provisions
+// users.
+func directoryFor(db *gorm.DB, email string) (*models.LDAPDirectory, bool, error) {
+	var d models.LDAPDirectory
+	var user models.User
+	err := db.Where("email = ?", email).First(&user).Error
+	switch {
+	case err == nil:
+		err = db.Where("hospital_id = ? AND enabled = ?", user.HospitalID, true).First(&d).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		_, domain, ok := strings.Cut(strings.ToLower(email), "@")
+		if !ok || domain == "" {
+			return nil, false, nil
+		}
+		err = db.Where("enabled = ? AND auto_provision = ? AND email_domains @> ?", true, true, fmt.Sprintf("[%q]", domain)).
+			Order("id").First(&d).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if user.ID == 0 {
+		return &d, false, nil
+	}
+
+	var links int64
+	err = db.Model(&models.ExternalIdentity{}).Where("user_id = ? AND provider = ?", user.ID, ProviderLDAP).Count(&links).Error
+	return &d, links > 0, err
+}
+
+// Dial connects to the directory d and binds as its service account, if one
+// is set. Directories on private or local networks are refused unless
+// INTERNAL_NETWORKS_ALLOWED lists them.
+func Dial(d models.LDAPDirectory, timeout time.Duration) (*ldap.Conn, error) {
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	if d.CACertificate != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(d.CACertificate)) {
+			return nil, errors.New("authn: no certificates in the CA bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	conn, err := ldap.DialURL(d.URL, ldap.DialWithDialer(netguard.Dialer(timeout)), ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+	if d.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if d.BindDN != "" {
+		if err := conn.Bind(d.BindDN, d.BindPassword); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func findEntry(conn *ldap.Conn, d models.LDAPDirectory, email string, timeout time.Duration) (*ldap.Entry, error) {
+	filter := d.UserFilter
+	if filter == "" {
+		filter = DefaultUserFilter
+	}
+	filter = strings.ReplaceAll(filter, "{email}", ldap.EscapeFilter(email))
+
+	attrs := attributes(d.Attributes)
+	res, err := conn.Search(ldap.NewSearchRequest(
+		d.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(timeout.Seconds()), false,
+		filter, []string{attrs.ID, attrs.Name, attrs.Surname, attrs.Phone, attrs.Groups}, nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+	switch len(res.Entries) {
+	case 0:
+		return nil, errNoEntry
+	case 1:
+		return res.Entries[0], nil
+	default:
+		return nil, fmt.Errorf("authn: %q matches more than one directory entry", email)
+	}
+}
+
+func attributes(m models.LDAPAttributeMapping) models.LDAPAttributeMapping {
+	or := func(name, standard string) string {
+		if name != "" {
+			return name
+		}
+		return standard
+	}
+	return models.LDAPAttributeMapping{
+		ID:      or(m.ID, "objectGUID"),
+		Name:    or(m.Name, "givenName"),
+		Surname: or(m.Surname, "sn"),
+		Phone:   or(m.Phone, "mobile"),
+		Groups:  or(m.Groups, "memberOf"),
+	}
+}
+
+// entryID identifies entry by its id attribute, hex encoded as objectGUID is
+// binary, or by its DN if the directory has no such attribute.
+func entryID(entry *ldap.Entry, attr string) string {
+	if id := entry.GetRawAttributeValue(attr); len(id) > 0 {
+		return hex.EncodeToString(id)
+	}
+	return strings.ToLower(entry.DN)
+}
+
+// MapRole picks the role of a user in groups, given by DN, using mapping,
+// whose keys may be group DNs or CNs. It falls back to defaultRole.
+func MapRole(mapping map[string]string, defaultRole string, groups []string) (string, error) {
+	lookup := make(map[string]string, len(mapping))
+	for group, role := range mapping {
+		lookup[strings.ToLower(group)] = role
+	}
+	for _, group := range groups {
+		keys := []string{strings.ToLower(group)}
+		if dn, err := ldap.ParseDN(group); err == nil && len(dn.RDNs) > 0 {
+			for _, attr := range dn.RDNs[0].Attributes {
+				if strings.EqualFold(attr.Type, "cn") {
+					keys = append(keys, strings.ToLower(attr.Value))
+				}
+			}
+		}
+		for _, key := range keys {
+			if role := lookup[key]; role != "" {
+				return strings.ToLower(role), nil
+			}
+		}
+	}
+	if defaultRole != "" {
+		return strings.ToLower(defaultRole), nil
+	}
+	return "", ErrNoRole
+}
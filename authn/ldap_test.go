@@ -0,0 +1,461 @@
+package authn
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/encryption"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/netguard"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testBaseDN       = "dc=example,dc=com"
+	testBindDN       = "cn=svc,ou=services,dc=example,dc=com"
+	testBindPassword = "svc-secret"
+)
+
+// dirEntry is a user of the stub directory.
+type dirEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// stubDirectory is an in-process LDAP server answering simple binds and
+// searches for entries by mail, enough for Dial and LDAP.Authenticate.
+type stubDirectory struct {
+	ln      net.Listener
+	entries []dirEntry
+
+	mu       sync.Mutex
+	binds    []string
+	attempts int
+}
+
+func newStubDirectory(t *testing.T, entries ...dirEntry) *stubDirectory {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubDirectory{ln: ln, entries: entries}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	// The stub runs on loopback, which netguard refuses otherwise.
+	t.Setenv("INTERNAL_NETWORKS_ALLOWED", "127.0.0.1")
+	return s
+}
+
+func (s *stubDirectory) url() string {
+	return "ldap://" + s.ln.Addr().String()
+}
+
+// bindAttempts counts the binds tried, successful or not.
+func (s *stubDirectory) bindAttempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts
+}
+
+// bound reports whether dn bound to the directory.
+func (s *stubDirectory) bound(dn string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.binds {
+		if b == dn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *stubDirectory) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *stubDirectory) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		p, err := ber.ReadPacket(conn)
+		if err != nil || len(p.Children) < 2 {
+			return
+		}
+		id, _ := p.Children[0].Value.(int64)
+		op := p.Children[1]
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			responses = append(responses, s.bind(op))
+		case ldap.ApplicationSearchRequest:
+			responses = s.search(op)
+		case ldap.ApplicationUnbindRequest:
+			return
+		default:
+			responses = append(responses, ldapResult(ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform))
+		}
+		for _, r := range responses {
+			msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+			msg.AppendChild(r)
+			if _, err := conn.Write(msg.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func ldapResult(tag ber.Tag, code uint16) *ber.Packet {
+	r := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	r.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	r.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	r.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return r
+}
+
+func (s *stubDirectory) bind(op *ber.Packet) *ber.Packet {
+	dn := strings.ToLower(ber.DecodeString(op.Children[1].Data.Bytes()))
+	password := ber.DecodeString(op.Children[2].Data.Bytes())
+	s.mu.Lock()
+	s.attempts++
+	s.mu.Unlock()
+	// Like real directories, an empty password is an anonymous bind.
+	ok := password == "" || (dn == testBindDN && password == testBindPassword)
+	for _, e := range s.entries {
+		if strings.ToLower(e.dn) == dn && e.password == password {
+			ok = true
+		}
+	}
+	if !ok {
+		return ldapResult(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials)
+	}
+	s.mu.Lock()
+	s.binds = append(s.binds, dn)
+	s.mu.Unlock()
+	return ldapResult(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess)
+}
+
+// search returns the entries under the base DN whose mail the filter asks
+// for.
+func (s *stubDirectory) search(op *ber.Packet) []*ber.Packet {
+	base := strings.ToLower(ber.DecodeString(op.Children[0].Data.Bytes()))
+	filter, err := ldap.DecompileFilter(op.Children[6])
+	if err != nil {
+		return []*ber.Packet{ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError)}
+	}
+	var responses []*ber.Packet
+	for _, e := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(e.dn), ","+base) {
+			continue
+		}
+		if !strings.Contains(filter, "(mail="+ldap.EscapeFilter(e.attrs["mail"][0])+")") {
+			continue
+		}
+		r := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Entry")
+		r.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "DN"))
+		attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+		for name, values := range e.attrs {
+			attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, v := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+			}
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+		r.AppendChild(attrs)
+		responses = append(responses, r)
+	}
+	return append(responses, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+}
+
+var (
+	ayseEntry = dirEntry{
+		dn:       "cn=Ayse Yilmaz,ou=staff,dc=example,dc=com",
+		password: "ayse-pass",
+		attrs: map[string][]string{
+			"mail":       {"ayse@example.com"},
+			"objectGUID": {"\x01\x02\x03\x04"},
+			"givenName":  {"Ayşe"},
+			"sn":         {"Yılmaz"},
+			"mobile":     {"+905551112233"},
+			"memberOf":   {"cn=Staff,ou=groups,dc=example,dc=com", "CN=Doctors,OU=Groups,DC=example,DC=com"},
+		},
+	}
+	newEntry = dirEntry{
+		dn:       "cn=Ali Kaya,ou=staff,dc=example,dc=com",
+		password: "ali-pass",
+		attrs: map[string][]string{
+			"mail":     {"ali@example.com"},
+			"memberOf": {"cn=Staff,ou=groups,dc=example,dc=com"},
+		},
+	}
+	strangerEntry = dirEntry{
+		dn:       "cn=Stranger,ou=staff,dc=example,dc=com",
+		password: "stranger-pass",
+		attrs:    map[string][]string{"mail": {"stranger@other.org"}},
+	}
+)
+
+func testDirectory(s *stubDirectory, hospitalID uint) models.LDAPDirectory {
+	return models.LDAPDirectory{
+		HospitalID:   hospitalID,
+		Enabled:      true,
+		URL:          s.url(),
+		BindDN:       testBindDN,
+		BindPassword: testBindPassword,
+		BaseDN:       testBaseDN,
+		UserFilter:   "(&(objectClass=person)(mail={email}))",
+		EmailDomains: []string{"example.com"},
+		RoleMapping:  map[string]string{"doctors": "Doctor", "cn=admins,ou=groups,dc=example,dc=com": "admin"},
+		DefaultRole:  "staff",
+	}
+}
+
+func TestDial(t *testing.T) {
+	s := newStubDirectory(t, ayseEntry)
+	d := testDirectory(s, 1)
+
+	conn, err := Dial(d, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if !s.bound(testBindDN) {
+		t.Fatal("Dial didn't bind as the service account")
+	}
+
+	d.BindPassword = "wrong"
+	if _, err := Dial(d, time.Second); !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		t.Fatalf("wrong service password: got %v", err)
+	}
+
+	t.Setenv("INTERNAL_NETWORKS_ALLOWED", "")
+	if _, err := Dial(testDirectory(s, 1), time.Second); !errors.Is(err, netguard.ErrForbidden) {
+		t.Fatalf("loopback directory: got %v, want netguard.ErrForbidden", err)
+	}
+}
+
+func TestFindEntry(t *testing.T) {
+	s := newStubDirectory(t, ayseEntry, strangerEntry)
+	d := testDirectory(s, 1)
+	conn, err := Dial(d, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	entry, err := findEntry(conn, d, "ayse@example.com", time.Second)
+	if err != nil || entry.DN != ayseEntry.dn {
+		t.Fatalf("got %v, %v; want %s", entry, err, ayseEntry.dn)
+	}
+	// Emails are escaped, so they can't widen the filter.
+	for _, email := range []string{"nobody@example.com", "*", "*)(mail=*"} {
+		if _, err := findEntry(conn, d, email, time.Second); !errors.Is(err, errNoEntry) {
+			t.Fatalf("%q: got %v, want errNoEntry", email, err)
+		}
+	}
+	// Entries outside the base DN aren't found.
+	d.BaseDN = "ou=other,dc=example,dc=com"
+	if _, err := findEntry(conn, d, "ayse@example.com", time.Second); !errors.Is(err, errNoEntry) {
+		t.Fatalf("outside the base DN: got %v, want errNoEntry", err)
+	}
+}
+
+func TestMapRole(t *testing.T) {
+	mapping := map[string]string{
+		"Doctors":                               "Doctor",
+		"cn=Admins,ou=Groups,dc=example,dc=com": "admin",
+		"disabled":                              "",
+	}
+	tests := []struct {
+		name        string
+		defaultRole string
+		groups      []string
+		want        string
+		wantErr     error
+	}{
+		{name: "by CN, case-insensitively", groups: []string{"CN=doctors,OU=Groups,DC=example,DC=com"}, want: "doctor"},
+		{name: "by DN", groups: []string{"cn=admins,ou=groups,dc=example,dc=com"}, want: "admin"},
+		{name: "first mapped group wins", groups: []string{"cn=Other,dc=example,dc=com", "cn=Admins,ou=Groups,dc=example,dc=com", "cn=Doctors,dc=example,dc=com"}, want: "admin"},
+		{name: "CN of another OU", groups: []string{"cn=Admins,ou=Other,dc=example,dc=com"}, wantErr: ErrNoRole},
+		{name: "empty mappings are skipped", groups: []string{"cn=Disabled,dc=example,dc=com"}, wantErr: ErrNoRole},
+		{name: "plain group names", groups: []string{"doctors"}, want: "doctor"},
+		{name: "default role", defaultRole: "Staff", groups: []string{"cn=Other,dc=example,dc=com"}, want: "staff"},
+		{name: "no groups", wantErr: ErrNoRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := MapRole(mapping, tt.defaultRole, tt.groups)
+			if !errors.Is(err, tt.wantErr) || role != tt.want {
+				t.Fatalf("got %q, %v; want %q, %v", role, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testDB opens the Postgres database of TEST_DATABASE_URL in a transaction
+// that is rolled back when the test ends. Tests needing it are skipped
+// without one.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if err := encryption.Init("", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	tx := db.Begin()
+	t.Cleanup(func() {
+		tx.Rollback()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return tx
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	tx := testDB(t)
+	s := newStubDirectory(t, ayseEntry, newEntry, strangerEntry)
+
+	hospital := models.Hospital{Name: "Ankara", Status: models.HospitalStatusActive}
+	if err := tx.Create(&hospital).Error; err != nil {
+		t.Fatal(err)
+	}
+	d := testDirectory(s, hospital.ID)
+	d.AutoProvision = true
+	d.DefaultProfessionGroupID = 3
+	d.DefaultTitleID = 4
+	if err := tx.Create(&d).Error; err != nil {
+		t.Fatal(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("local-pass"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := []models.User{
+		{Email: "ayse@example.com", Role: "staff", HospitalID: hospital.ID, Status: models.UserStatusActive},
+		{Email: "local@example.com", Password: string(hash), Role: "admin", HospitalID: hospital.ID, Status: models.UserStatusActive},
+		{Email: "linked@example.com", Password: string(hash), Role: "staff", HospitalID: hospital.ID, Status: models.UserStatusActive},
+	}
+	if err := tx.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	link := models.ExternalIdentity{UserID: users[2].ID, Provider: ProviderLDAP, Issuer: "hospital", Subject: "gone"}
+	if err := tx.Create(&link).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	l := LDAP{DB: tx, Timeout: time.Second}
+	chain := Chain{l, Local{DB: tx}}
+	ctx := context.Background()
+
+	t.Run("bind succeeds", func(t *testing.T) {
+		res, err := l.Authenticate(ctx, " ayse@example.com ", "ayse-pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := res.Account
+		if a == nil || a.Provider != ProviderLDAP || a.HospitalID != hospital.ID || a.Email != "ayse@example.com" ||
+			a.Subject != hex.EncodeToString([]byte{1, 2, 3, 4}) || a.Name != "Ayşe" || a.Surname != "Yılmaz" ||
+			a.Phone != "+905551112233" || a.Role != "doctor" {
+			t.Fatalf("got account %+v", a)
+		}
+	})
+	t.Run("bind fails", func(t *testing.T) {
+		if _, err := l.Authenticate(ctx, "ayse@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("got %v, want ErrInvalidCredentials", err)
+		}
+		// The directory owns the user, so the chain doesn't try a local password.
+		if _, err := chain.Authenticate(ctx, "ayse@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("chain: got %v, want ErrInvalidCredentials", err)
+		}
+	})
+	t.Run("empty password", func(t *testing.T) {
+		attempts := s.bindAttempts()
+		if _, err := l.Authenticate(ctx, "ayse@example.com", ""); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("got %v, want ErrInvalidCredentials", err)
+		}
+		if s.bindAttempts() != attempts {
+			t.Fatal("an empty password was sent to the directory, which takes it for an anonymous bind")
+		}
+	})
+	t.Run("unknown user routed by email domain", func(t *testing.T) {
+		res, err := l.Authenticate(ctx, "ali@example.com", "ali-pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := res.Account
+		if a == nil || a.HospitalID != hospital.ID || !a.AutoProvision || a.ProfessionGroupID != 3 || a.TitleID != 4 ||
+			a.Role != "staff" || a.Subject != strings.ToLower(newEntry.dn) {
+			t.Fatalf("got account %+v", a)
+		}
+	})
+	t.Run("unknown email domain", func(t *testing.T) {
+		if _, err := l.Authenticate(ctx, "stranger@other.org", "stranger-pass"); !errors.Is(err, ErrSkip) {
+			t.Fatalf("got %v, want ErrSkip", err)
+		}
+	})
+	t.Run("no routing without auto_provision", func(t *testing.T) {
+		if err := tx.Model(&d).Update("auto_provision", false).Error; err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Model(&d).Update("auto_provision", true)
+		if _, err := l.Authenticate(ctx, "ali@example.com", "ali-pass"); !errors.Is(err, ErrSkip) {
+			t.Fatalf("got %v, want ErrSkip", err)
+		}
+	})
+	t.Run("local user falls back to the local password", func(t *testing.T) {
+		if _, err := l.Authenticate(ctx, "local@example.com", "local-pass"); !errors.Is(err, ErrSkip) {
+			t.Fatalf("got %v, want ErrSkip", err)
+		}
+		res, err := chain.Authenticate(ctx, "local@example.com", "local-pass")
+		if err != nil || res.User == nil || res.User.ID != users[1].ID {
+			t.Fatalf("chain: got %+v, %v; want local user %d", res, err, users[1].ID)
+		}
+	})
+	t.Run("linked user gone from the directory", func(t *testing.T) {
+		if _, err := chain.Authenticate(ctx, "linked@example.com", "local-pass"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("got %v, want ErrInvalidCredentials", err)
+		}
+	})
+	t.Run("directory unavailable", func(t *testing.T) {
+		s.ln.Close()
+		res, err := chain.Authenticate(ctx, "local@example.com", "local-pass")
+		if err != nil || res.User == nil {
+			t.Fatalf("unlinked user: got %+v, %v; want the local login", res, err)
+		}
+		if _, err := chain.Authenticate(ctx, "linked@example.com", "local-pass"); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("linked user: got %v, want ErrUnavailable", err)
+		}
+	})
+}
@@ -11,12 +11,17 @@
 package main
 
 import (
-	"net/http"
+	"log"
+	"time"
 
 	"github.com/efecan/vatansoft-case/config"
 	"github.com/efecan/vatansoft-case/controllers"
 	_ "github.com/efecan/vatansoft-case/docs"
+	"github.com/efecan/vatansoft-case/i18n"
 	"github.com/efecan/vatansoft-case/middlewares"
+	"github.com/efecan/vatansoft-case/netguard"
+	"github.com/efecan/vatansoft-case/permissions"
+	"github.com/efecan/vatansoft-case/utils"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -24,41 +29,127 @@ import (
 
 func main() {
 	config.LoadEnv()
+	config.InitEncryption()
 	config.ConnectDB()
 	config.InitRedis()
+	utils.SetupValidator()
+	i18n.Init()
 
 	r := gin.Default()
+	if err := r.SetTrustedProxies(config.TrustedProxies()); err != nil {
+		log.Fatalf("⚠️ Invalid TRUSTED_PROXIES: %v", err)
+	}
+	if err := netguard.Validate(); err != nil {
+		log.Fatalf("⚠️ Invalid INTERNAL_NETWORKS_ALLOWED: %v", err)
+	}
+	r.Use(middlewares.RequestID)
 
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "pong"})
 	})
 
-	r.POST("/register", controllers.Register)
-	r.POST("/login", controllers.Login)
-	r.POST("/auth/request-password-reset", controllers.RequestPasswordReset)
-	r.POST("/auth/reset-password", controllers.ResetPassword)
+	registerLimit := middlewares.RateLimit(middlewares.RateLimitOptions{Name: "register", Limit: 5, Window: time.Hour})
+	loginLimit := middlewares.RateLimit(middlewares.RateLimitOptions{Name: "login", Limit: 10, Window: time.Minute})
+	resetRequestLimit := middlewares.RateLimit(middlewares.RateLimitOptions{Name: "password-reset-request", Limit: 3, Window: 15 * time.Minute})
+	resetLimit := middlewares.RateLimit(middlewares.RateLimitOptions{Name: "password-reset", Limit: 10, Window: 15 * time.Minute})
+	smsCodeLimit := middlewares.RateLimit(middlewares.RateLimitOptions{Name: "sms-login-request", Limit: 5, Window: 15 * time.Minute})
+	publicLimit := middlewares.RateLimit(middlewares.RateLimitOptions{Name: "public", Limit: 60, Window: time.Minute})
+	invitationLimit := middlewares.RateLimit(middlewares.RateLimitOptions{Name: "invitation", Limit: 10, Window: 15 * time.Minute})
+	inviteResendLimit := middlewares.RateLimit(middlewares.RateLimitOptions{
+		Name:   "invitation-resend",
+		Limit:  5,
+		Window: time.Hour,
+		Key:    func(c *gin.Context) string { return "invitee:" + c.Param("id") },
+	})
+
+	contactChangeLimit := middlewares.RateLimit(middlewares.RateLimitOptions{
+		Name:   "contact-change",
+		Limit:  5,
+		Window: time.Hour,
+		Key:    middlewares.KeyByUser,
+	})
+	meLimit := middlewares.RateLimit(middlewares.RateLimitOptions{Name: "me", Limit: 10, Window: 15 * time.Minute, Key: middlewares.KeyByUser})
+	verificationResendLimit := middlewares.RateLimit(middlewares.RateLimitOptions{
+		Name:   "verification-resend",
+		Limit:  5,
+		Window: time.Hour,
+		Key:    middlewares.KeyByUser,
+	})
+
+	r.POST("/register", registerLimit, middlewares.Idempotency, controllers.Register)
+	r.POST("/login", loginLimit, controllers.Login)
+	r.POST("/auth/change-password", loginLimit, controllers.ChangeExpiredPassword)
+	r.POST("/auth/request-password-reset", resetRequestLimit, controllers.RequestPasswordReset)
+	r.POST("/auth/reset-password", resetLimit, controllers.ResetPassword)
+	r.POST("/auth/sms/request", smsCodeLimit, controllers.RequestSMSLoginCode)
+	r.POST("/auth/sms/verify", loginLimit, controllers.SMSLogin)
+	r.GET("/auth/oidc/:hospitalID/login", loginLimit, controllers.OIDCLogin)
+	r.GET("/auth/oidc/callback", loginLimit, controllers.OIDCCallback)
+	r.GET("/invitations/:token", invitationLimit, controllers.GetInvitation)
+	r.POST("/invitations/accept", invitationLimit, controllers.AcceptInvitation)
 	r.POST("/hospitals/register", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.HospitalRegister)
 	r.GET("/hospitals", middlewares.RequireAuth, controllers.GetHospitals)
-	r.POST("/users", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.CreateUser)
-	r.GET("/users", middlewares.RequireAuth, controllers.GetUsers)
-	r.GET("/listusers", middlewares.RequireAuth, controllers.ListUsers)
-	r.PUT("/users/:id", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.UpdateUser)
-	r.DELETE("/users/:id", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.DeleteUser)
-	r.POST("/departments", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.CreateDepartment)
-	r.GET("/departments", middlewares.RequireAuth, controllers.GetDepartments)
-	r.GET("/departments/:id/doctors", middlewares.RequireAuth, controllers.GetDoctorsByDepartment)
+	r.POST("/users", middlewares.Authenticate(permissions.WriteUsers), middlewares.RequireAdmin, middlewares.Idempotency, controllers.CreateUser)
+	r.GET("/users", middlewares.Authenticate(permissions.ReadUsers), controllers.GetUsers)
+	r.GET("/listusers", middlewares.Authenticate(permissions.ReadUsers), controllers.ListUsers)
+	r.GET("/search", middlewares.Authenticate(permissions.ReadUsers), controllers.Search)
+	r.GET("/users/:id", middlewares.Authenticate(permissions.ReadUsers), middlewares.RequireAdmin, controllers.GetUser)
+	r.PATCH("/users/:id", middlewares.Authenticate(permissions.WriteUsers), middlewares.RequireAdmin, controllers.UpdateUser)
+	r.PUT("/users/:id", middlewares.Authenticate(permissions.WriteUsers), middlewares.RequireAdmin, controllers.ReplaceUser)
+	r.DELETE("/users/:id", middlewares.Authenticate(permissions.WriteUsers), middlewares.RequireAdmin, controllers.DeleteUser)
+	r.POST("/users/:id/invitation/resend", middlewares.RequireAuth, middlewares.RequireAdmin, inviteResendLimit, controllers.ResendInvitation)
+	r.DELETE("/users/:id/invitation", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.RevokeInvitation)
+	r.GET("/users/:id/sessions", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.GetUserSessions)
+	r.DELETE("/users/:id/sessions", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.RevokeUserSessions)
+	r.DELETE("/users/:id/sessions/:sessionID", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.RevokeUserSession)
+	r.POST("/departments", middlewares.RequireAuth, middlewares.RequireAdmin, middlewares.Idempotency, controllers.CreateDepartment)
+	r.GET("/departments", middlewares.Authenticate(permissions.ReadDepartments), controllers.GetDepartments)
+	r.GET("/departments/export", middlewares.Authenticate(permissions.ReadDepartments), middlewares.RequireAdmin, controllers.ExportDepartments)
+	r.GET("/departments/:id/doctors", middlewares.Authenticate(permissions.ReadDepartments), controllers.GetDoctorsByDepartment)
 	r.GET("/cities", middlewares.RequireAuth, controllers.GetCities)
-	r.GET("/profession-groups", controllers.GetProfessionGroups)
+	r.GET("/profession-groups", publicLimit, controllers.GetProfessionGroups)
+	r.POST("/users/import", middlewares.Authenticate(permissions.WriteUsers), middlewares.RequireAdmin, middlewares.Idempotency, controllers.ImportUsers)
+	r.GET("/users/import/:id", middlewares.Authenticate(permissions.WriteUsers), middlewares.RequireAdmin, controllers.GetImportJob)
+	r.GET("/users/export", middlewares.Authenticate(permissions.ReadUsers), middlewares.RequireAdmin, controllers.ExportUsers)
+	r.GET("/users/deleted", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.GetDeletedUsers)
+	r.POST("/users/:id/restore", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.RestoreUser)
+	r.DELETE("/users/:id/purge", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.PurgeUser)
+	r.GET("/users/:id/export", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.ExportUserData)
+	r.POST("/users/:id/erase", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.EraseUser)
+	r.GET("/doctors/:id/export", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.ExportDoctorData)
+	r.POST("/doctors/:id/erase", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.EraseDoctor)
+	r.GET("/password-policy", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.GetPasswordPolicy)
+	r.PUT("/password-policy", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.UpdatePasswordPolicy)
+	r.GET("/retention-policies", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.GetRetentionPolicies)
+	r.GET("/audit-logs", middlewares.Authenticate(permissions.ReadAuditLog), middlewares.RequireAdmin, controllers.GetAuditLogs)
+	r.POST("/api-keys", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.CreateAPIKey)
+	r.GET("/api-keys", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.GetAPIKeys)
+	r.DELETE("/api-keys/:id", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.RevokeAPIKey)
+	r.GET("/oidc-config", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.GetOIDCConfig)
+	r.PUT("/oidc-config", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.UpdateOIDCConfig)
+	r.DELETE("/oidc-config", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.DeleteOIDCConfig)
+	r.GET("/ldap-config", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.GetLDAPConfig)
+	r.PUT("/ldap-config", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.UpdateLDAPConfig)
+	r.DELETE("/ldap-config", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.DeleteLDAPConfig)
+	r.GET("/sms-login-config", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.GetSMSLoginConfig)
+	r.PUT("/sms-login-config", middlewares.RequireAuth, middlewares.RequireAdmin, controllers.UpdateSMSLoginConfig)
+	r.GET("/audit-logs/verify", middlewares.Authenticate(permissions.ReadAuditLog), middlewares.RequireAdmin, controllers.VerifyAuditLogs)
 
-	r.GET("/me", middlewares.RequireAuth, func(c *gin.Context) {
-		userID := c.GetInt("userID")
-		userName := c.GetString("userName")
+	r.GET("/me", middlewares.AllowUnverified, middlewares.RequireAuth, controllers.GetMe)
+	r.PATCH("/me", middlewares.RequireAuth, middlewares.ForbidImpersonation, contactChangeLimit, controllers.UpdateMe)
+	r.POST("/me/password", middlewares.RequireAuth, middlewares.ForbidImpersonation, meLimit, controllers.ChangeMyPassword)
+	r.POST("/me/email/verify", middlewares.RequireAuth, middlewares.ForbidImpersonation, meLimit, controllers.VerifyEmailChange)
+	r.POST("/me/phone/verify", middlewares.RequireAuth, middlewares.ForbidImpersonation, meLimit, controllers.VerifyPhoneChange)
+	r.GET("/me/sessions", middlewares.AllowUnverified, middlewares.RequireAuth, controllers.GetMySessions)
+	r.DELETE("/me/sessions", middlewares.AllowUnverified, middlewares.RequireAuth, middlewares.ForbidImpersonation, controllers.RevokeMyOtherSessions)
+	r.DELETE("/me/sessions/:id", middlewares.AllowUnverified, middlewares.RequireAuth, controllers.RevokeMySession)
 
-		c.JSON(http.StatusOK, gin.H{
-			"userID":   userID,
-			"userName": userName,
-		})
-	})
+	r.GET("/registration", middlewares.AllowUnverified, middlewares.RequireAuth, controllers.GetRegistration)
+	r.GET("/registration/verify-email", publicLimit, controllers.VerifyRegistrationEmail)
+	r.POST("/registration/verify-phone", middlewares.AllowUnverified, middlewares.RequireAuth, meLimit, controllers.VerifyRegistrationPhone)
+	r.POST("/registration/resend", middlewares.AllowUnverified, middlewares.RequireAuth, verificationResendLimit, controllers.ResendRegistrationVerification)
+
+	r.POST("/admin/impersonate/:userID", middlewares.RequireAuth, middlewares.RequirePlatformAdmin, controllers.ImpersonateUser)
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
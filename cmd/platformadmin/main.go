@@ -0,0 +1,71 @@
+// Command platformadmin grants or revokes the platform admin flag, which lets
+// support staff impersonate users of any hospital. It is deliberately not
+// exposed through the API, so a hospital admin can never grant it.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/session"
+	"gorm.io/gorm"
+)
+
+func main() {
+	email := flag.String("email", "", "email of the user to change")
+	revoke := flag.Bool("revoke", false, "take the flag away instead of granting it")
+	flag.Parse()
+	if *email == "" {
+		log.Fatal("❌ -email is required")
+	}
+
+	config.LoadEnv()
+	config.InitEncryption()
+	config.ConnectDB()
+
+	var user models.User
+	if err := config.DB.Where("email = ?", *email).First(&user).Error; err != nil {
+		log.Fatalf("❌ Loading user %s failed: %v", *email, err)
+	}
+	if user.PlatformAdmin == !*revoke {
+		log.Printf("✅ Nothing to do, platform_admin is already %t for %s", user.PlatformAdmin, *email)
+		return
+	}
+
+	before := user
+	user.PlatformAdmin = !*revoke
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Select("platform_admin").Updates(&user).Error; err != nil {
+			return err
+		}
+		// Tokens carry the flag, so make the user log in again to pick up
+		// the change.
+		if _, err := session.RevokeUser(tx, user.ID, 0, 0); err != nil {
+			return err
+		}
+		if *revoke {
+			if _, err := session.Revoke(tx.Where("impersonator_id = ?", user.ID), 0); err != nil {
+				return err
+			}
+		}
+		action := "user.platform_admin_grant"
+		if *revoke {
+			action = "user.platform_admin_revoke"
+		}
+		return audit.RecordSystem(tx, audit.Entry{
+			Action:     action,
+			EntityType: "user",
+			EntityID:   user.ID,
+			Before:     before,
+			After:      user,
+			HospitalID: user.HospitalID,
+		})
+	})
+	if err != nil {
+		log.Fatalf("❌ Updating %s failed: %v", *email, err)
+	}
+	log.Printf("✅ platform_admin is now %t for %s", user.PlatformAdmin, *email)
+}
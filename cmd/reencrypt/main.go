@@ -0,0 +1,113 @@
+// Command reencrypt re-seals encrypted personal data with the active key after
+// ENCRYPTION_ACTIVE_KEY_ID is rotated. Keep the old key in ENCRYPTION_KEYS
+// until this has run, then it can be removed.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/encryption"
+	"github.com/efecan/vatansoft-case/models"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 500, "number of users loaded per batch")
+	dryRun := flag.Bool("dry-run", false, "only count the users that need re-encryption")
+	flag.Parse()
+
+	config.LoadEnv()
+	config.InitEncryption()
+	config.ConnectDB()
+
+	var lastID uint
+	var scanned, rotated, failed int
+	for {
+		// Read the raw column values so the key id of each ciphertext is visible.
+		var rows []struct {
+			ID    uint
+			TCKN  string
+			Phone string
+		}
+		err := config.DB.Table("users").Select("id", "tckn", "phone").
+			Where("id > ?", lastID).Order("id").Limit(*batchSize).
+			Scan(&rows).Error
+		if err != nil {
+			log.Fatalf("❌ Loading users failed: %v", err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		for _, row := range rows {
+			lastID = row.ID
+			scanned++
+			if !encryption.NeedsRotation(row.TCKN) && !encryption.NeedsRotation(row.Phone) {
+				continue
+			}
+			if *dryRun {
+				rotated++
+				continue
+			}
+
+			var user models.User
+			if err := config.DB.Unscoped().First(&user, row.ID).Error; err != nil {
+				log.Printf("⚠️ Could not load user %d: %v", row.ID, err)
+				failed++
+				continue
+			}
+			if err := config.SaveUserPII(config.DB, &user); err != nil {
+				log.Printf("⚠️ Could not re-encrypt user %d: %v", row.ID, err)
+				failed++
+				continue
+			}
+			rotated++
+		}
+	}
+
+	if *dryRun {
+		log.Printf("✅ Dry run: %d of %d users need re-encryption", rotated, scanned)
+	} else {
+		log.Printf("✅ Re-encrypted %d of %d users (%d failed)", rotated, scanned, failed)
+	}
+	reencryptAuditKeys(*dryRun)
+}
+
+// reencryptAuditKeys re-seals the keys of audit log subjects. Losing them
+// would make the personal data in the audit log unreadable.
+func reencryptAuditKeys(dryRun bool) {
+	var rows []struct {
+		ID  uint
+		Key string
+	}
+	if err := config.DB.Table("audit_subject_keys").Select("id", "key").Scan(&rows).Error; err != nil {
+		log.Fatalf("❌ Loading audit subject keys failed: %v", err)
+	}
+	var rotated, failed int
+	for _, row := range rows {
+		if !encryption.NeedsRotation(row.Key) {
+			continue
+		}
+		if dryRun {
+			rotated++
+			continue
+		}
+		var k models.AuditSubjectKey
+		err := config.DB.First(&k, row.ID).Error
+		if err == nil {
+			err = config.DB.Model(&k).Select("key").Updates(&k).Error
+		}
+		if err != nil {
+			log.Printf("⚠️ Could not re-encrypt audit subject key %d: %v", row.ID, err)
+			failed++
+			continue
+		}
+		rotated++
+	}
+	if dryRun {
+		log.Printf("✅ Dry run: %d of %d audit subject keys need re-encryption", rotated, len(rows))
+		return
+	}
+	log.Printf("✅ Re-encrypted %d of %d audit subject keys (%d failed)", rotated, len(rows), failed)
+}
@@ -0,0 +1,25 @@
+// Command retention applies the data retention policies, anonymizing deleted
+// records whose retention period has ended. Run it daily, e.g. from cron.
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/privacy"
+)
+
+func main() {
+	config.LoadEnv()
+	config.InitEncryption()
+	config.ConnectDB()
+
+	counts, err := privacy.ApplyRetention(config.DB, time.Now())
+	for entityType, n := range counts {
+		log.Printf("✅ Anonymized %d %s records", n, entityType)
+	}
+	if err != nil {
+		log.Fatalf("❌ Applying retention policies failed: %v", err)
+	}
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 
+	"github.com/efecan/vatansoft-case/encryption"
 	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
 )
@@ -17,6 +19,34 @@ func GetEnv(key string, fallback string) string {
 	return fallback
 }
 
+// TrustedProxies lists the reverse proxies, as IPs or CIDRs, whose
+// X-Forwarded-For header is believed when working out the client IP. There
+// are none by default, so clients can't pick the IP that API key allowlists
+// and rate limits see.
+func TrustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(GetEnv("TRUSTED_PROXIES", ""), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
+// InternalNetworks lists the private networks, as IPs or CIDRs, that the
+// identity providers and directories hospitals configure may be reached on.
+// There are none by default, so hospital admins can't point the server at
+// itself or at the network it runs in.
+func InternalNetworks() []string {
+	var networks []string
+	for _, n := range strings.Split(GetEnv("INTERNAL_NETWORKS_ALLOWED", ""), ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			networks = append(networks, n)
+		}
+	}
+	return networks
+}
+
 func InitRedis() {
 	REDIS = redis.NewClient(&redis.Options{
 		Addr:     GetEnv("REDIS_HOST", "localhost:6379"),
@@ -25,6 +55,19 @@ func InitRedis() {
 	})
 }
 
+// InitEncryption loads the keys used to encrypt personal data at rest. It must
+// run before ConnectDB, which encrypts legacy rows on startup.
+func InitEncryption() {
+	err := encryption.Init(
+		GetEnv("ENCRYPTION_KEYS", ""),
+		GetEnv("ENCRYPTION_ACTIVE_KEY_ID", ""),
+		GetEnv("BLIND_INDEX_KEY", ""),
+	)
+	if err != nil {
+		log.Fatalf("⚠️ Loading encryption keys failed: %v", err)
+	}
+}
+
 func LoadEnv() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
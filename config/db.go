@@ -5,7 +5,10 @@ import (
 	"log"
 	"time"
 
+	"github.com/efecan/vatansoft-case/audit"
 	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/search"
+	"github.com/efecan/vatansoft-case/utils"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -30,18 +33,8 @@ func ConnectDB() {
 		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 		if err == nil {
 			log.Println("✅ Database connected")
-			err = db.AutoMigrate(
-				&models.Address{},
-				&models.Hospital{},
-				&models.User{},
-				&models.Doctor{},
-				&models.DepartmentType{},
-				&models.Department{},
-				&models.City{},
-				&models.District{},
-			)
-			if err != nil {
-				log.Fatalf("⚠️ AutoMigrate failed: %v", err)
+			if err := Migrate(db); err != nil {
+				log.Fatalf("⚠️ Migrating the database failed: %v", err)
 			}
 			DB = db
 			return
@@ -52,3 +45,114 @@ func ConnectDB() {
 
 	log.Fatalf("⚠️ Failed to connect to database: %v", err)
 }
+
+// Migrate brings the schema of db up to date, installs its triggers and
+// indexes and fixes up rows written by older versions.
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&models.Address{},
+		&models.Hospital{},
+		&models.User{},
+		&models.Doctor{},
+		&models.DepartmentType{},
+		&models.Department{},
+		&models.City{},
+		&models.District{},
+		&models.AuditLog{},
+		&models.AuditSubjectKey{},
+		&models.ProfessionGroup{},
+		&models.Title{},
+		&models.Invitation{},
+		&models.PasswordPolicy{},
+		&models.PasswordHistory{},
+		&models.Session{},
+		&models.APIKey{},
+		&models.OIDCProvider{},
+		&models.ExternalIdentity{},
+		&models.LDAPDirectory{},
+		&models.SMSLoginSetting{},
+	)
+	if err != nil {
+		return fmt.Errorf("auto-migrating: %w", err)
+	}
+	dropLegacyIndexes(db)
+	if err := audit.InstallTriggers(db); err != nil {
+		return fmt.Errorf("installing audit log triggers: %w", err)
+	}
+	if err := search.Install(db); err != nil {
+		return fmt.Errorf("installing search indexes: %w", err)
+	}
+	normalizePhones(db)
+	encryptLegacyUsers(db)
+	return nil
+}
+
+// dropLegacyIndexes removes unique indexes that covered deleted rows too. They
+// were replaced by partial indexes over non-deleted rows, and AutoMigrate only
+// adds indexes.
+func dropLegacyIndexes(db *gorm.DB) {
+	for _, name := range []string{"idx_users_phone_index", "idx_users_tckn_index"} {
+		if !db.Migrator().HasIndex(&models.User{}, name) {
+			continue
+		}
+		if err := db.Migrator().DropIndex(&models.User{}, name); err != nil {
+			log.Printf("⚠️ Could not drop index %s: %v", name, err)
+		}
+	}
+}
+
+// normalizePhones rewrites hospital phone numbers stored before E.164
+// normalization was enforced, so lookups by phone match regardless of how they
+// were entered. User phones are encrypted and handled by encryptLegacyUsers.
+func normalizePhones(db *gorm.DB) {
+	for _, model := range []interface{}{&models.Hospital{}} {
+		var rows []struct {
+			ID    uint
+			Phone string
+		}
+		if err := db.Model(model).Select("id", "phone").Where("phone <> '' AND phone NOT LIKE '+%'").Find(&rows).Error; err != nil {
+			log.Printf("⚠️ Could not load phones to normalize: %v", err)
+			continue
+		}
+		for _, row := range rows {
+			normalized, ok := utils.NormalizePhone(row.Phone)
+			if !ok {
+				continue
+			}
+			if err := db.Model(model).Where("id = ?", row.ID).Update("phone", normalized).Error; err != nil {
+				log.Printf("⚠️ Could not normalize phone of record %d: %v", row.ID, err)
+			}
+		}
+	}
+}
+
+// encryptLegacyUsers encrypts TCKN and phone values written before encryption
+// at rest and fills in their blind indexes. Saving through the model runs the
+// encrypted serializer and the BeforeSave hook.
+func encryptLegacyUsers(db *gorm.DB) {
+	var users []models.User
+	err := db.Unscoped().
+		Where("(phone <> '' AND phone_index IS NULL) OR (tckn <> '' AND tckn_index IS NULL)").
+		Find(&users).Error
+	if err != nil {
+		log.Printf("⚠️ Could not load users to encrypt: %v", err)
+		return
+	}
+	for i := range users {
+		users[i].Phone = utils.NormalizePhoneOrKeep(users[i].Phone)
+		if err := SaveUserPII(db, &users[i]); err != nil {
+			log.Printf("⚠️ Could not encrypt personal data of user %d: %v", users[i].ID, err)
+		}
+	}
+	if len(users) > 0 {
+		log.Printf("✅ Encrypted personal data of %d users", len(users))
+	}
+}
+
+// SaveUserPII rewrites only the encrypted personal columns of a user and their
+// blind indexes, sealing them with the active key.
+func SaveUserPII(db *gorm.DB, user *models.User) error {
+	return db.Unscoped().Model(user).
+		Select("phone", "phone_index", "tckn", "tckn_index").
+		Updates(user).Error
+}
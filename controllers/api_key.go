@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apikey"
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/listquery"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type NewAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	// Scopes are the permissions the key is granted, e.g. users:read.
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// AllowedIPs are addresses or CIDR ranges the key may be used from.
+	// Leave empty to allow any address.
+	AllowedIPs []string   `json:"allowed_ips" binding:"max=20"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+type NewAPIKeyResponse struct {
+	models.APIKey
+	// Key is the secret to configure in the integration. It is only ever
+	// shown in this response.
+	Key string `json:"key"`
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key for an integration (admin only)
+// @Description The key acts for the admin's hospital with the given scopes (pii:national_id, pii:contact, users:read, users:write, users:admin, departments:read, audit:read). The secret is returned once and only its hash is stored. Send it as "Authorization: Bearer vsk_..." or in X-API-Key
+// @Tags API keys
+// @Accept json
+// @Produce json
+// @Param key body NewAPIKeyRequest true "Key settings"
+// @Success 201 {object} NewAPIKeyResponse
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /api-keys [post]
+func CreateAPIKey(c *gin.Context) {
+	var req NewAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !permissions.IsScope(scope) {
+			apperrors.Abort(c, apperrors.InvalidField("scopes", "scope", "field_scope"))
+			return
+		}
+	}
+	allowedIPs, invalid := apikey.NormalizeIPs(req.AllowedIPs)
+	if invalid != "" {
+		apperrors.Abort(c, apperrors.InvalidField("allowed_ips", "cidr", "field_cidr"))
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		apperrors.Abort(c, apperrors.InvalidField("expires_at", "future", "field_future"))
+		return
+	}
+
+	key, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "api_key_create_failed", "Failed to create API key"))
+		return
+	}
+	k := models.APIKey{
+		HospitalID:  uint(c.GetInt("hospitalID")),
+		Name:        req.Name,
+		Prefix:      prefix,
+		SecretHash:  hash,
+		Scopes:      req.Scopes,
+		AllowedIPs:  allowedIPs,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedByID: uint(c.GetInt("userID")),
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&k).Error; err != nil {
+			return apperrors.FromDB(err, "api_key_create_failed", "Failed to create API key")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "api_key.create",
+			EntityType: "api_key",
+			EntityID:   k.ID,
+			After:      k,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewAPIKeyResponse{APIKey: k, Key: key})
+}
+
+var apiKeyListSpec = listquery.Spec{
+	Model: &models.APIKey{},
+	Sortable: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	DefaultSort: "-id",
+	Fields:      listquery.FieldsOf(models.APIKey{}),
+}
+
+// GetAPIKeys godoc
+// @Summary List the API keys of the admin's hospital (admin only)
+// @Description Newest first by default, revoked and expired keys included. Secrets are never returned. Sortable by id, name and created_at
+// @Tags API keys
+// @Produce json
+// @Param sort query string false "Comma separated fields, prefix with - for descending"
+// @Param page_size query int false "Items per page (max 100)"
+// @Param page query int false "Page number, ignored when cursor is set"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated fields to return"
+// @Success 200 {object} listquery.Page{data=[]models.APIKey}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /api-keys [get]
+func GetAPIKeys(c *gin.Context) {
+	q, err := listquery.Parse(c, apiKeyListSpec)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	var keys []models.APIKey
+	result, err := q.Find(config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")), &keys)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "api_keys_fetch_failed", "Failed to fetch API keys"))
+		return
+	}
+	q.Respond(c, keys, result)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key (admin only)
+// @Description The key stops working immediately and can't be restored
+// @Tags API keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /api-keys/{id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_api_key_id", "Invalid API key ID"))
+		return
+	}
+
+	var k models.APIKey
+	err = config.DB.Where("hospital_id = ? AND revoked_at IS NULL", c.GetInt("hospitalID")).First(&k, id).Error
+	if err != nil {
+		apperrors.Abort(c, apperrors.NotFound("api_key_not_found", "API key not found"))
+		return
+	}
+
+	before := k
+	now := time.Now().UTC()
+	k.RevokedAt = &now
+	k.RevokedByID = uint(c.GetInt("userID"))
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&k).Select("revoked_at", "revoked_by_id").Updates(&k).Error; err != nil {
+			return apperrors.FromDB(err, "api_key_revoke_failed", "Failed to revoke API key")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "api_key.revoke",
+			EntityType: "api_key",
+			EntityID:   k.ID,
+			Before:     before,
+			After:      k,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "api_key_revoked")})
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/listquery"
+	"github.com/efecan/vatansoft-case/masking"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/gin-gonic/gin"
+)
+
+// auditLogListSpec lists the newest entries first.
+var auditLogListSpec = listquery.Spec{
+	Model: &models.AuditLog{},
+	Sortable: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+		"action":     "action",
+	},
+	DefaultSort: "-id",
+	Fields:      listquery.FieldsOf(models.AuditLog{}),
+}
+
+// auditMask masks the personal data in audit entries the way user responses
+// do, for callers without the matching pii scopes.
+func auditMask(c *gin.Context) audit.MaskFunc {
+	policy := masking.For(c)
+	hospitalID := uint(c.GetInt("hospitalID"))
+	return func(subjectType string, subjectID uint, field, value string) string {
+		owner := masking.Owner{HospitalID: hospitalID}
+		if subjectType == "user" {
+			owner.UserID = subjectID
+		}
+		switch field {
+		case "tckn":
+			return policy.TCKN(owner, value)
+		case "phone":
+			return policy.Phone(owner, value)
+		case "email":
+			return policy.Email(owner, value)
+		}
+		return value
+	}
+}
+
+// GetAuditLogs godoc
+// @Summary List audit log entries of the admin's hospital (admin only)
+// @Description Newest first by default. Sortable by id, created_at and action. Personal data in before, after and diff is decrypted and masked like user responses; that of erased people reads "[erased]"
+// @Tags Audit
+// @Produce json
+// @Param sort query string false "Comma separated fields, prefix with - for descending"
+// @Param page_size query int false "Items per page (max 100)"
+// @Param page query int false "Page number, ignored when cursor is set"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated fields to return"
+// @Param actor_id query int false "Filter by acting user ID"
+// @Param action query string false "Filter by action, e.g. user.update"
+// @Param entity_type query string false "Filter by entity type, e.g. user"
+// @Param entity_id query int false "Filter by entity ID"
+// @Param request_id query string false "Filter by request ID"
+// @Param impersonator_id query int false "Filter by the platform admin who impersonated the actor"
+// @Param api_key_id query int false "Filter by the API key used"
+// @Param from query string false "Only entries at or after this RFC 3339 time"
+// @Param to query string false "Only entries before this RFC 3339 time"
+// @Success 200 {object} listquery.Page{data=[]models.AuditLog}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /audit-logs [get]
+func GetAuditLogs(c *gin.Context) {
+	q, err := listquery.Parse(c, auditLogListSpec)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	query := config.DB.Where("hospital_id = ?", c.GetInt("hospitalID"))
+
+	for param, column := range map[string]string{
+		"actor_id":        "actor_id",
+		"action":          "action",
+		"entity_type":     "entity_type",
+		"entity_id":       "entity_id",
+		"request_id":      "request_id",
+		"impersonator_id": "impersonator_id",
+		"api_key_id":      "api_key_id",
+	} {
+		if value := c.Query(param); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	for param, op := range map[string]string{"from": ">=", "to": "<"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			apperrors.Abort(c, apperrors.BadRequest("invalid_time_filter", "from and to must be RFC 3339 timestamps"))
+			return
+		}
+		query = query.Where("created_at "+op+" ?", t)
+	}
+
+	var entries []models.AuditLog
+	result, err := q.Find(query, &entries)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "audit_logs_fetch_failed", "Failed to fetch audit logs"))
+		return
+	}
+	if err := audit.Reveal(config.DB, entries, auditMask(c)); err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "audit_logs_fetch_failed", "Failed to fetch audit logs"))
+		return
+	}
+
+	q.Respond(c, entries, result)
+}
+
+// VerifyAuditLogs godoc
+// @Summary Verify the hash chain of the admin's hospital audit log (admin only)
+// @Tags Audit
+// @Produce json
+// @Success 200 {object} audit.VerifyResult
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /audit-logs/verify [get]
+func VerifyAuditLogs(c *gin.Context) {
+	result, err := audit.Verify(config.DB, uint(c.GetInt("hospitalID")))
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "audit_verify_failed", "Failed to verify audit logs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -2,38 +2,52 @@ package controllers
 
 import (
 	"context"
-	"fmt"
-	"math/rand"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
 	"time"
 
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/authn"
 	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
 	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/otp"
+	"github.com/efecan/vatansoft-case/password"
+	"github.com/efecan/vatansoft-case/registration"
+	"github.com/efecan/vatansoft-case/session"
+	"github.com/efecan/vatansoft-case/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // Register godoc
 // @Summary Registers a new hospital and its admin user
-// @Description Creates a hospital and registers an admin for it. Only one admin per hospital is allowed.
+// @Description Creates a hospital and registers an admin for it. Only one admin per hospital is allowed. Both stay unverified until the hospital's and the admin's email addresses are confirmed through the links mailed to them and their phone numbers through the SMS codes sent to them (POST /registration/verify-phone). Until then the admin can log in but only reach GET /me, their sessions and the /registration endpoints
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param registration body object true "Hospital and admin registration info"
-// @Success 200 {object} map[string]string "Hospital and admin user registered successfully"
-// @Failure 400 {object} map[string]string "Invalid input or hospital already exists"
-// @Failure 500 {object} map[string]string "Internal error"
+// @Param Idempotency-Key header string false "Makes retries of this request safe"
+// @Success 200 {object} map[string]interface{} "message, status and pending_verification"
+// @Failure 400 {object} apperrors.Problem "Invalid input"
+// @Failure 409 {object} apperrors.Problem "Hospital or admin already exists"
+// @Failure 500 {object} apperrors.Problem "Internal error"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
+// @Failure 422 {object} apperrors.Problem "Idempotency-Key reused with a different payload"
 // @Router /register [post]
 func Register(c *gin.Context) {
 	var req struct {
 		Hospital struct {
 			Name      string `json:"name" binding:"required"`
-			TaxNumber string `json:"tax_number" binding:"required"`
+			TaxNumber string `json:"tax_number" binding:"required,vkn"`
 			Email     string `json:"email" binding:"required,email"`
-			Phone     string `json:"phone" binding:"required"`
+			Phone     string `json:"phone" binding:"required,tr_phone"`
 			Address   struct {
 				ProvinceID uint   `json:"province_id" binding:"required"`
 				DistrictID uint   `json:"district_id" binding:"required"`
@@ -43,9 +57,9 @@ func Register(c *gin.Context) {
 		Admin struct {
 			Name              string `json:"name" binding:"required"`
 			Surname           string `json:"surname" binding:"required"`
-			TCKN              string `json:"tc_no" binding:"required"`
+			TCKN              string `json:"tc_no" binding:"required,tckn"`
 			Email             string `json:"email" binding:"required,email"`
-			Phone             string `json:"phone" binding:"required"`
+			Phone             string `json:"phone" binding:"required,tr_phone"`
 			Password          string `json:"password" binding:"required"`
 			Role              string `json:"role" binding:"required"` // must be "admin"
 			ProfessionGroupID uint   `json:"profession_group_id" binding:"required"`
@@ -54,170 +68,307 @@ func Register(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		apperrors.Abort(c, apperrors.Validation(err))
 		return
 	}
+	req.Hospital.Phone = utils.NormalizePhoneOrKeep(req.Hospital.Phone)
+	req.Admin.Phone = utils.NormalizePhoneOrKeep(req.Admin.Phone)
 
 	if strings.ToLower(req.Admin.Role) != "admin" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only admin registration is allowed on this endpoint"})
+		apperrors.Abort(c, apperrors.BadRequest("admin_role_required", "Only admin registration is allowed on this endpoint"))
 		return
 	}
 
 	var existingHospital models.Hospital
 	if err := config.DB.Where("tax_number = ? OR email = ? OR phone = ?", req.Hospital.TaxNumber, req.Hospital.Email, req.Hospital.Phone).
 		First(&existingHospital).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Hospital already exists with provided tax/email/phone"})
+		apperrors.Abort(c, apperrors.Conflict("hospital_exists", "Hospital already exists with provided tax/email/phone"))
 		return
 	}
 
-	address := models.Address{
-		ProvinceID: req.Hospital.Address.ProvinceID,
-		DistrictID: req.Hospital.Address.DistrictID,
-		Street:     req.Hospital.Address.Street,
-	}
-	if err := config.DB.Create(&address).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create address"})
-		return
-	}
-
-	hospital := models.Hospital{
-		Name:      req.Hospital.Name,
-		TaxNumber: req.Hospital.TaxNumber,
-		Email:     req.Hospital.Email,
-		Phone:     req.Hospital.Phone,
-		AddressID: address.ID,
-	}
-	if err := config.DB.Create(&hospital).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create hospital"})
+	// The hospital doesn't exist yet, so the default policy applies.
+	if err := password.Check(config.DB, "admin.password", req.Admin.Password, password.Subject{
+		Name:    req.Admin.Name,
+		Surname: req.Admin.Surname,
+		Email:   req.Admin.Email,
+		TCKN:    req.Admin.TCKN,
+	}); err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Admin.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apperrors.Abort(c, apperrors.Internal(err, "password_hash_failed", "Failed to hash password"))
 		return
 	}
 
-	var existingAdmin models.User
-	if err := config.DB.Where("hospital_id = ? AND role = ?", hospital.ID, "admin").First(&existingAdmin).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "This hospital already has an admin user"})
+	// Both stay unverified until the registration's email addresses and
+	// phone numbers are confirmed.
+	var reg registration.Registration
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		address := models.Address{
+			ProvinceID: req.Hospital.Address.ProvinceID,
+			DistrictID: req.Hospital.Address.DistrictID,
+			Street:     req.Hospital.Address.Street,
+		}
+		if err := tx.Create(&address).Error; err != nil {
+			return apperrors.FromDB(err, "address_create_failed", "Failed to create address")
+		}
+
+		hospital := models.Hospital{
+			Name:      req.Hospital.Name,
+			TaxNumber: req.Hospital.TaxNumber,
+			Email:     req.Hospital.Email,
+			Phone:     req.Hospital.Phone,
+			AddressID: address.ID,
+			Status:    models.HospitalStatusUnverified,
+		}
+		if err := tx.Create(&hospital).Error; err != nil {
+			return apperrors.FromDB(err, "hospital_create_failed", "Failed to create hospital")
+		}
+
+		var existingAdmin models.User
+		if err := tx.Where("hospital_id = ? AND role = ?", hospital.ID, "admin").First(&existingAdmin).Error; err == nil {
+			return apperrors.Conflict("hospital_admin_exists", "This hospital already has an admin user")
+		}
+
+		now := time.Now().UTC()
+		admin := models.User{
+			Name:              req.Admin.Name,
+			Surname:           req.Admin.Surname,
+			TCKN:              req.Admin.TCKN,
+			Email:             req.Admin.Email,
+			Phone:             req.Admin.Phone,
+			Password:          string(hashedPassword),
+			PasswordChangedAt: &now,
+			Role:              "admin",
+			HospitalID:        hospital.ID,
+			ProfessionGroupID: req.Admin.ProfessionGroupID,
+			TitleID:           req.Admin.TitleID,
+			Status:            models.UserStatusUnverified,
+		}
+		if err := tx.Create(&admin).Error; err != nil {
+			return apperrors.FromDB(err, "admin_create_failed", "Failed to create admin user")
+		}
+		reg = registration.Registration{Hospital: hospital, Admin: admin}
+		if err := password.Remember(tx, admin); err != nil {
+			return apperrors.Internal(err, "admin_create_failed", "Failed to create admin user")
+		}
+
+		// Self-registration has no authenticated actor; attribute it to the new admin.
+		if err := audit.Record(tx, c, audit.Entry{
+			Action:     "hospital.register",
+			EntityType: "hospital",
+			EntityID:   hospital.ID,
+			After:      hospital,
+			ActorID:    admin.ID,
+			HospitalID: hospital.ID,
+		}); err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "user.create",
+			EntityType: "user",
+			EntityID:   admin.ID,
+			After:      admin,
+			ActorID:    admin.ID,
+			HospitalID: hospital.ID,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
-	admin := models.User{
-		Name:              req.Admin.Name,
-		Surname:           req.Admin.Surname,
-		TCKN:              req.Admin.TCKN,
-		Email:             req.Admin.Email,
-		Phone:             req.Admin.Phone,
-		Password:          string(hashedPassword),
-		Role:              "admin",
-		HospitalID:        hospital.ID,
-		ProfessionGroupID: req.Admin.ProfessionGroupID,
-		TitleID:           req.Admin.TitleID,
-	}
-	if err := config.DB.Create(&admin).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create admin user"})
-		return
+	// The admin can ask for everything again after logging in, so a failed
+	// delivery doesn't fail the registration.
+	if err := reg.SendAll(c.Request.Context()); err != nil {
+		log.Printf("⚠️ Could not send verification of hospital %d: %v", reg.Hospital.ID, err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Hospital and admin user registered successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":              i18n.T(c, "hospital_registered"),
+		"status":               reg.Hospital.Status,
+		"pending_verification": reg.Pending(),
+	})
 }
 
 // Login godoc
 // @Summary Logs in a user and returns a JWT token
-// @Description Authenticates the user by email and password
+// @Description Authenticates the user by email and password. Staff of hospitals with an LDAP directory log in with their directory password and have their role synced from their groups; accounts the directory doesn't know, such as the hospital's first admin, use their local password
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param credentials body object true "User credentials"
-// @Success 200 {object} map[string]string "token"
-// @Failure 400 {object} map[string]string "invalid request"
-// @Failure 401 {object} map[string]string "invalid credentials"
+// @Success 200 {object} map[string]interface{} "token; admins of unverified registrations also get status \"unverified\" and pending_verification, and their token only reaches the /registration endpoints"
+// @Failure 400 {object} apperrors.Problem "invalid request"
+// @Failure 401 {object} apperrors.Problem "invalid credentials"
+// @Failure 403 {object} apperrors.Problem "Password must be changed first (password_change_required), or no role maps to the directory account (directory_no_role)"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
 // @Router /login [post]
 func Login(c *gin.Context) {
 	var body struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		// Device names the session, e.g. "Ayşe's iPad"; the user agent is used otherwise.
+		Device string `json:"device" binding:"max=100"`
 	}
 
 	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		apperrors.Abort(c, apperrors.Validation(err))
 		return
 	}
 
-	var user models.User
-	if err := config.DB.Where("email = ?", body.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+	res, err := authenticators().Authenticate(c.Request.Context(), body.Email, body.Password)
+	switch {
+	case errors.Is(err, authn.ErrInvalidCredentials):
+		apperrors.Abort(c, apperrors.Unauthorized("invalid_credentials", "invalid credentials"))
 		return
-	}
-
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+	case errors.Is(err, authn.ErrNoRole):
+		apperrors.Abort(c, apperrors.Forbidden("directory_no_role", "Your directory account isn't mapped to a role here"))
+		return
+	case errors.Is(err, authn.ErrUnavailable):
+		apperrors.Abort(c, apperrors.Internal(err, "directory_unavailable", "Your hospital's directory can't be reached"))
+		return
+	case err != nil:
+		apperrors.Abort(c, apperrors.Internal(err, "login_failed", "Failed to log in"))
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":         user.ID,
-		"name":        user.Name,
-		"role":        user.Role,
-		"hospital_id": user.HospitalID,
-		"exp":         time.Now().Add(time.Hour * 72).Unix(),
-	})
+	var user models.User
+	if res.Account != nil {
+		user, err = externalUser(c, *res.Account)
+		if err != nil {
+			apperrors.Abort(c, err)
+			return
+		}
+	} else {
+		// Directory passwords follow the directory's own policy.
+		user = *res.User
+		policy, err := password.PolicyFor(config.DB, user.HospitalID)
+		if err != nil {
+			apperrors.Abort(c, apperrors.Internal(err, "password_policy_fetch_failed", "Failed to load the password policy"))
+			return
+		}
+		if password.MustChange(policy, user, time.Now()) {
+			apperrors.Abort(c, apperrors.Forbidden("password_change_required", "Password must be changed before logging in; use /auth/change-password"))
+			return
+		}
+	}
 
-	tokenString, err := token.SignedString([]byte(config.GetEnv("JWT_SECRET", "devsecret")))
+	tokenString, err := issueToken(c, user, body.Device)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		apperrors.Abort(c, err)
 		return
 	}
 
+	if user.Status == models.UserStatusUnverified {
+		reg, err := registration.Load(config.DB, user.HospitalID)
+		if err != nil {
+			apperrors.Abort(c, apperrors.Internal(err, "registration_fetch_failed", "Failed to load the registration"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"token":                tokenString,
+			"status":               user.Status,
+			"pending_verification": reg.Pending(),
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"token": tokenString})
 
 }
 
-func generateResetCode() string {
-	return fmt.Sprintf("%06d", rand.Intn(1000000))
+// authenticators is the chain Login checks credentials with: the directory of
+// the user's hospital, if it has one, then local passwords.
+func authenticators() authn.Chain {
+	return authn.Chain{
+		authn.LDAP{DB: config.DB},
+		authn.Local{DB: config.DB},
+	}
+}
+
+// issueToken starts a session for user and returns a JWT bound to it.
+func issueToken(c *gin.Context, user models.User, device string) (string, error) {
+	s, err := session.Start(config.DB, user, device, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return "", apperrors.Internal(err, "session_create_failed", "Failed to create session")
+	}
+	return signToken(user, s, nil)
+}
+
+// signToken returns the JWT of session s of user. actor is the platform admin
+// impersonating user, if any; it goes into the act claim (RFC 8693) so both
+// identities travel with the token.
+func signToken(user models.User, s models.Session, actor *models.User) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":            user.ID,
+		"sid":            s.TokenID,
+		"name":           user.Name,
+		"role":           user.Role,
+		"hospital_id":    user.HospitalID,
+		"lang":           user.Language,
+		"platform_admin": user.PlatformAdmin,
+		"exp":            s.ExpiresAt.Unix(),
+	}
+	if actor != nil {
+		claims["act"] = jwt.MapClaims{"sub": actor.ID, "name": actor.Name}
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	tokenString, err := token.SignedString([]byte(config.GetEnv("JWT_SECRET", "devsecret")))
+	if err != nil {
+		return "", apperrors.Internal(err, "token_generation_failed", "could not generate token")
+	}
+	return tokenString, nil
 }
 
 // RequestPasswordReset godoc
 // @Summary Request password reset code
-// @Description Sends a reset code for the given phone number. The code is returned in the response (simulating SMS).
+// @Description Sends a reset code for the given phone number. The code is returned in the response (simulating SMS). Pending users must accept their invitation instead.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body object true "Phone number for password reset"
 // @Success 200 {object} map[string]interface{} "Reset code generated"
-// @Failure 400 {object} map[string]string "Invalid request"
-// @Failure 404 {object} map[string]string "User not found"
-// @Failure 500 {object} map[string]string "Error creating reset code"
+// @Failure 400 {object} apperrors.Problem "Invalid request"
+// @Failure 404 {object} apperrors.Problem "User not found"
+// @Failure 500 {object} apperrors.Problem "Error creating reset code"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
 // @Router /auth/request-password-reset [post]
 func RequestPasswordReset(c *gin.Context) {
 	var req struct {
-		Phone string `json:"phone" binding:"required"`
+		Phone string `json:"phone" binding:"required,tr_phone"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Phone number is required and must be valid"})
+		apperrors.Abort(c, apperrors.Validation(err))
 		return
 	}
+	req.Phone = utils.NormalizePhoneOrKeep(req.Phone)
 
 	var user models.User
-	if err := config.DB.Where("phone = ?", req.Phone).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found for the provided phone number"})
+	if err := config.DB.Where("status = ?", models.UserStatusActive).Scopes(models.UserWithPhone(req.Phone)).First(&user).Error; err != nil {
+		apperrors.Abort(c, apperrors.NotFound("phone_not_registered", "User not found for the provided phone number"))
 		return
 	}
 
-	code := generateResetCode()
+	code, err := otp.Generate()
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "reset_code_failed", "Error creating reset code"))
+		return
+	}
 	key := "reset_code:" + req.Phone
 	expiration := 10 * time.Minute
 
-	err := config.REDIS.Set(context.Background(), key, code, expiration).Err()
+	err = config.REDIS.Set(context.Background(), key, code, expiration).Err()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating reset code"})
+		apperrors.Abort(c, apperrors.Internal(err, "reset_code_failed", "Error creating reset code"))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Reset code has been sent to your phone number. Please use this code to reset your password.",
+		"message": i18n.T(c, "reset_code_sent"),
 		"code":    code,
 	})
 }
@@ -230,53 +381,138 @@ func RequestPasswordReset(c *gin.Context) {
 // @Produce json
 // @Param request body object true "Reset password request"
 // @Success 200 {object} map[string]string "Password reset successfully"
-// @Failure 400 {object} map[string]string "Invalid request or code mismatch"
-// @Failure 404 {object} map[string]string "User not found"
-// @Failure 500 {object} map[string]string "Failed to update password"
+// @Failure 400 {object} apperrors.Problem "Invalid request, code mismatch or a password that violates the hospital's password policy"
+// @Failure 404 {object} apperrors.Problem "User not found"
+// @Failure 500 {object} apperrors.Problem "Failed to update password"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
 // @Router /auth/reset-password [post]
 func ResetPassword(c *gin.Context) {
 	var req struct {
-		Phone       string `json:"phone" binding:"required"`
+		Phone       string `json:"phone" binding:"required,tr_phone"`
 		Code        string `json:"code" binding:"required"`
 		NewPassword string `json:"new_password" binding:"required"`
 		ConfirmPass string `json:"confirm_password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		apperrors.Abort(c, apperrors.Validation(err))
 		return
 	}
+	req.Phone = utils.NormalizePhoneOrKeep(req.Phone)
 
 	if req.NewPassword != req.ConfirmPass {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Passwords do not match"})
+		apperrors.Abort(c, apperrors.BadRequest("password_mismatch", "Passwords do not match"))
 		return
 	}
 
 	cacheKey := "reset_code:" + req.Phone
 	storedCode, err := config.REDIS.Get(c, cacheKey).Result()
 	if err != nil || storedCode != req.Code {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+		apperrors.Abort(c, apperrors.BadRequest("invalid_code", "Invalid or expired code"))
 		return
 	}
 
 	var user models.User
-	if err := config.DB.Where("phone = ?", req.Phone).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err := config.DB.Where("status = ?", models.UserStatusActive).Scopes(models.UserWithPhone(req.Phone)).First(&user).Error; err != nil {
+		apperrors.Abort(c, apperrors.NotFound("user_not_found", "User not found"))
+		return
+	}
+
+	if err := changePassword(c, &user, "new_password", req.NewPassword); err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	config.REDIS.Del(c, cacheKey)
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "password_reset")})
+}
+
+// changePassword checks plain against the password policy of user's hospital
+// and saves it as their new password. field names the request field in
+// policy errors.
+func changePassword(c *gin.Context, user *models.User, field, plain string) error {
+	if err := password.Check(config.DB, field, plain, password.SubjectOf(*user)); err != nil {
+		return err
+	}
+	hashed, err := utils.HashPassword(plain)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return apperrors.Internal(err, "password_hash_failed", "Failed to hash password")
+	}
+
+	before := *user
+	user.Password = hashed
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := password.Changed(tx, user); err != nil {
+			return apperrors.Internal(err, "password_update_failed", "Failed to update password")
+		}
+		if err := tx.Model(user).
+			Select("password", "password_changed_at", "must_change_password").
+			Updates(user).Error; err != nil {
+			return apperrors.FromDB(err, "password_update_failed", "Failed to update password")
+		}
+		// Whoever knew the old password is logged out everywhere but here.
+		if _, err := session.RevokeUser(tx, user.ID, uint(c.GetInt("sessionID")), user.ID); err != nil {
+			return apperrors.Internal(err, "session_revoke_failed", "Failed to revoke session")
+		}
+		// Users changing their password aren't necessarily logged in.
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "user.password_change",
+			EntityType: "user",
+			EntityID:   user.ID,
+			Before:     before,
+			After:      user,
+			ActorID:    user.ID,
+			HospitalID: user.HospitalID,
+		})
+	})
+}
+
+type ChangePasswordRequest struct {
+	Email           string `json:"email" binding:"required,email"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+	ConfirmPassword string `json:"confirm_password" binding:"required"`
+}
+
+// ChangeExpiredPassword godoc
+// @Summary Change a password with the current one
+// @Description For users whose login is refused with password_change_required, because an admin asked for a new password or the hospital's rotation interval passed. The new password must meet the hospital's password policy
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "Credentials and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem "Invalid request or a password that violates the policy"
+// @Failure 401 {object} apperrors.Problem "invalid credentials"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
+// @Failure 500 {object} apperrors.Problem
+// @Router /auth/change-password [post]
+func ChangeExpiredPassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+	if req.NewPassword != req.ConfirmPassword {
+		apperrors.Abort(c, apperrors.BadRequest("password_mismatch", "Passwords do not match"))
 		return
 	}
 
-	if err := config.DB.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password", "details": err.Error()})
+	var user models.User
+	if err := config.DB.Where("email = ? AND status = ?", req.Email, models.UserStatusActive).First(&user).Error; err != nil {
+		apperrors.Abort(c, apperrors.Unauthorized("invalid_credentials", "invalid credentials"))
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		apperrors.Abort(c, apperrors.Unauthorized("invalid_credentials", "invalid credentials"))
 		return
 	}
 
-	config.REDIS.Del(c, cacheKey)
+	if err := changePassword(c, &user, "new_password", req.NewPassword); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "password_changed")})
 }
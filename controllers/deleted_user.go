@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/listquery"
+	"github.com/efecan/vatansoft-case/masking"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/privacy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DeletedUserResponse struct {
+	UserResponse
+	DeletedAt     time.Time  `json:"deleted_at"`
+	AnonymizedAt  *time.Time `json:"anonymized_at,omitempty"`
+	RetainedUntil time.Time  `json:"retained_until"`
+}
+
+// deletedUserListSpec sorts deleted users by deletion time by default.
+var deletedUserListSpec = listquery.Spec{
+	Model: &models.User{},
+	Sortable: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"surname":    "surname",
+		"deleted_at": "deleted_at",
+	},
+	DefaultSort: "-deleted_at",
+	Fields:      listquery.FieldsOf(DeletedUserResponse{}),
+}
+
+// GetDeletedUsers godoc
+// @Summary List deleted users of the admin's hospital (admin only)
+// @Description retained_until is when the user can be purged. TCKN, phone and email are masked unless the caller may see them. Sortable by id, name, surname and deleted_at
+// @Tags Users
+// @Produce json
+// @Param sort query string false "Comma separated fields, prefix with - for descending"
+// @Param page_size query int false "Items per page (max 100)"
+// @Param page query int false "Page number, ignored when cursor is set"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated fields to return"
+// @Success 200 {object} listquery.Page{data=[]DeletedUserResponse}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/deleted [get]
+func GetDeletedUsers(c *gin.Context) {
+	q, err := listquery.Parse(c, deletedUserListSpec)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	var users []models.User
+	query := config.DB.Unscoped().Where("hospital_id = ? AND deleted_at IS NOT NULL", c.GetInt("hospitalID"))
+	result, err := q.Find(query, &users, "ProfessionGroup", "Title")
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "users_fetch_failed", "Failed to fetch users"))
+		return
+	}
+
+	policy := masking.For(c)
+	retention, _ := privacy.PolicyFor("user")
+	response := make([]DeletedUserResponse, 0, len(users))
+	for _, u := range users {
+		response = append(response, DeletedUserResponse{
+			UserResponse:  newUserResponse(policy, u),
+			DeletedAt:     u.DeletedAt.Time,
+			AnonymizedAt:  u.AnonymizedAt,
+			RetainedUntil: retention.RetainedUntil(u.DeletedAt.Time),
+		})
+	}
+
+	q.Respond(c, response, result)
+}
+
+// loadDeletedUser loads the deleted user in the :id path parameter from the
+// caller's hospital.
+func loadDeletedUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_user_id", "Invalid user ID"))
+		return user, false
+	}
+	err = config.DB.Unscoped().
+		Where("hospital_id = ? AND deleted_at IS NOT NULL", c.GetInt("hospitalID")).
+		First(&user, id).Error
+	if err != nil {
+		apperrors.Abort(c, apperrors.NotFound("deleted_user_not_found", "Deleted user not found"))
+		return user, false
+	}
+	return user, true
+}
+
+// RestoreUser godoc
+// @Summary Restore a deleted user (admin only)
+// @Description Fails with 409 if the user was anonymized or another active user now has the same email, phone or TCKN
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/{id}/restore [post]
+func RestoreUser(c *gin.Context) {
+	user, ok := loadDeletedUser(c)
+	if !ok {
+		return
+	}
+	if user.AnonymizedAt != nil {
+		apperrors.Abort(c, apperrors.Conflict("subject_already_anonymized", "Personal data has already been erased"))
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+			return apperrors.FromDB(err, "user_restore_failed", "Failed to restore user")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "user.restore",
+			EntityType: "user",
+			EntityID:   user.ID,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "user_restored")})
+}
+
+// PurgeUser godoc
+// @Summary Permanently delete a deleted user (admin only)
+// @Description Only allowed once the user's retention period has ended
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/{id}/purge [delete]
+func PurgeUser(c *gin.Context) {
+	user, ok := loadDeletedUser(c)
+	if !ok {
+		return
+	}
+
+	retention, _ := privacy.PolicyFor("user")
+	if time.Now().Before(retention.RetainedUntil(user.DeletedAt.Time)) {
+		apperrors.Abort(c, apperrors.Conflict("user_still_retained", "User is still within its retention period"))
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, related := range []interface{}{&models.Invitation{}, &models.PasswordHistory{}, &models.Session{}, &models.ExternalIdentity{}} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(related).Error; err != nil {
+				return apperrors.FromDB(err, "user_purge_failed", "Failed to purge user")
+			}
+		}
+		if err := tx.Unscoped().Delete(&user).Error; err != nil {
+			return apperrors.FromDB(err, "user_purge_failed", "Failed to purge user")
+		}
+		if err := audit.ForgetSubject(tx, "user", user.ID); err != nil {
+			return apperrors.FromDB(err, "user_purge_failed", "Failed to purge user")
+		}
+		// No snapshot: the entry must not keep the data that was just purged.
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "user.purge",
+			EntityType: "user",
+			EntityID:   user.ID,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "user_purged")})
+}
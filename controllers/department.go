@@ -3,9 +3,15 @@ package controllers
 import (
 	"net/http"
 
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
 	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/listquery"
+	"github.com/efecan/vatansoft-case/masking"
 	"github.com/efecan/vatansoft-case/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type AddressResponse struct {
@@ -64,10 +70,12 @@ type DoctorWithRelationsResponse struct {
 // @Accept json
 // @Produce json
 // @Param request body CreateDepartmentRequest true "Department creation payload"
+// @Param Idempotency-Key header string false "Makes retries of this request safe"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 401 {object} apperrors.Problem
+// @Failure 422 {object} apperrors.Problem "Idempotency-Key reused with a different payload"
+// @Failure 500 {object} apperrors.Problem
 // @Security BearerAuth
 // @Router /departments [post]
 type CreateDepartmentRequest struct {
@@ -81,25 +89,25 @@ func CreateDepartment(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		apperrors.Abort(c, apperrors.Validation(err))
 		return
 	}
 
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		apperrors.Abort(c, apperrors.Unauthorized("unauthorized", "Unauthorized"))
 		return
 	}
 
 	var admin models.User
 	if err := config.DB.First(&admin, userID).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		apperrors.Abort(c, apperrors.Unauthorized("unauthorized", "Unauthorized"))
 		return
 	}
 
 	var departmentType models.DepartmentType
 	if err := config.DB.First(&departmentType, req.DepartmentTypeID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Department type not found"})
+		apperrors.Abort(c, apperrors.BadRequest("department_type_not_found", "Department type not found"))
 		return
 	}
 
@@ -108,31 +116,62 @@ func CreateDepartment(c *gin.Context) {
 		HospitalID:       admin.HospitalID,
 	}
 
-	if err := config.DB.Create(&department).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create department"})
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&department).Error; err != nil {
+			return apperrors.FromDB(err, "department_create_failed", "Could not create department")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "department.create",
+			EntityType: "department",
+			EntityID:   department.ID,
+			After:      department,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Department created successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "department_created")})
+}
+
+var departmentListSpec = listquery.Spec{
+	Model: &models.Department{},
+	Sortable: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	DefaultSort: "id",
+	Fields:      listquery.FieldsOf(DepartmentResponse{}),
 }
 
 // GetDepartments godoc
 // @Summary List departments
-// @Description Returns a list of all departments with their types and hospital info
+// @Description Returns the departments of the caller's hospital with their types and hospital info. Sortable by id, name and created_at
 // @Tags Department
 // @Produce json
-// @Success 200 {array} DepartmentResponse
-// @Failure 500 {object} map[string]string
+// @Param sort query string false "Comma separated fields, prefix with - for descending"
+// @Param page_size query int false "Items per page (max 100)"
+// @Param page query int false "Page number, ignored when cursor is set"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated fields to return"
+// @Success 200 {object} listquery.Page{data=[]DepartmentResponse}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
 // @Security BearerAuth
 // @Router /departments [get]
 func GetDepartments(c *gin.Context) {
-	var departments []models.Department
+	q, err := listquery.Parse(c, departmentListSpec)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
 
-	if err := config.DB.
-		Preload("Hospital.Address").
-		Preload("DepartmentType").
-		Find(&departments).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve departments"})
+	var departments []models.Department
+	result, err := q.Find(config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")), &departments, "Hospital.Address", "DepartmentType")
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "departments_fetch_failed", "Failed to retrieve departments"))
 		return
 	}
 
@@ -160,41 +199,60 @@ func GetDepartments(c *gin.Context) {
 		response = append(response, resp)
 	}
 
-	c.JSON(http.StatusOK, response)
+	q.Respond(c, response, result)
+}
+
+var doctorListSpec = listquery.Spec{
+	Model: &models.Doctor{},
+	Sortable: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"email":      "email",
+		"created_at": "created_at",
+	},
+	DefaultSort: "id",
+	Fields:      listquery.FieldsOf(DoctorWithRelationsResponse{}),
 }
 
 // GetDoctorsByDepartment godoc
 // @Summary Get doctors by department ID
-// @Description Returns all doctors in the specified department
+// @Description Returns the doctors in the specified department of the caller's hospital. Emails are masked unless the caller may see them
 // @Tags Department
 // @Produce json
 // @Param id path int true "Department ID"
-// @Success 200 {array} DoctorWithRelationsResponse
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param sort query string false "Comma separated fields, prefix with - for descending"
+// @Param page_size query int false "Items per page (max 100)"
+// @Param page query int false "Page number, ignored when cursor is set"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated fields to return"
+// @Success 200 {object} listquery.Page{data=[]DoctorWithRelationsResponse}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
 // @Security BearerAuth
 // @Router /departments/{id}/doctors [get]
 func GetDoctorsByDepartment(c *gin.Context) {
 	departmentID := c.Param("id")
 
-	var doctors []models.Doctor
-	err := config.DB.
-		Preload("Hospital.Address").
-		Preload("Department").
-		Where("department_id = ?", departmentID).
-		Find(&doctors).Error
+	q, err := listquery.Parse(c, doctorListSpec)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
 
+	var doctors []models.Doctor
+	result, err := q.Find(config.DB.Where("department_id = ? AND hospital_id = ?", departmentID, c.GetInt("hospitalID")), &doctors, "Hospital.Address", "Department")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctors"})
+		apperrors.Abort(c, apperrors.Internal(err, "doctors_fetch_failed", "Failed to fetch doctors"))
 		return
 	}
 
+	policy := masking.For(c)
 	var response []DoctorWithRelationsResponse
 	for _, d := range doctors {
 		resp := DoctorWithRelationsResponse{
 			ID:           d.ID,
 			Name:         d.Name,
-			Email:        d.Email,
+			Email:        policy.Email(masking.Owner{HospitalID: d.HospitalID}, d.Email),
 			HospitalID:   d.HospitalID,
 			DepartmentID: d.DepartmentID,
 			Hospital: struct {
@@ -240,5 +298,5 @@ func GetDoctorsByDepartment(c *gin.Context) {
 		response = append(response, resp)
 	}
 
-	c.JSON(http.StatusOK, response)
+	q.Respond(c, response, result)
 }
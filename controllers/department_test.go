@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDepartmentReadsStayInTheKeysHospital(t *testing.T) {
+	tx := testDB(t)
+	hospital := createHospital(t, tx, "Ankara")
+	other := createHospital(t, tx, "İzmir")
+	deptType := models.DepartmentType{Name: "Cardiology"}
+	if err := tx.Create(&deptType).Error; err != nil {
+		t.Fatal(err)
+	}
+	dept := models.Department{Name: "Cardiology", HospitalID: hospital.ID, DepartmentTypeID: deptType.ID}
+	if err := tx.Create(&dept).Error; err != nil {
+		t.Fatal(err)
+	}
+	doctor := models.Doctor{Name: "Ayşe", Email: "ayse@example.com", Password: "x", HospitalID: hospital.ID, DepartmentID: dept.ID}
+	if err := tx.Create(&doctor).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	id := strconv.Itoa(int(dept.ID))
+	for _, tt := range []struct {
+		hospital models.Hospital
+		want     int64
+	}{
+		{hospital, 1},
+		{other, 0},
+	} {
+		key := gin.H{"apiKeyID": 1, "hospitalID": int(tt.hospital.ID), "apiKeyScopes": []string{"departments:read"}}
+
+		c, w := requestAs("GET", "/departments", "", key)
+		GetDepartments(c)
+		if got := pageTotal(t, w.Code, w.Body.Bytes()); got != tt.want {
+			t.Fatalf("key of hospital %d listed %d departments, want %d", tt.hospital.ID, got, tt.want)
+		}
+
+		c, w = requestAs("GET", "/departments/"+id+"/doctors", "", key)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		GetDoctorsByDepartment(c)
+		if got := pageTotal(t, w.Code, w.Body.Bytes()); got != tt.want {
+			t.Fatalf("key of hospital %d listed %d doctors, want %d", tt.hospital.ID, got, tt.want)
+		}
+	}
+}
+
+func pageTotal(t *testing.T, status int, body []byte) int64 {
+	t.Helper()
+	var page struct {
+		Total int64 `json:"total"`
+	}
+	if status != http.StatusOK {
+		t.Fatalf("got %d %s", status, body)
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		t.Fatal(err)
+	}
+	return page.Total
+}
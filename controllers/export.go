@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/masking"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/reports"
+	"github.com/gin-gonic/gin"
+)
+
+// startDownload sets the headers of a file download named name.
+func startDownload(c *gin.Context, name, contentType string) {
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Status(http.StatusOK)
+}
+
+// referenceNames maps the IDs of a lookup table to names.
+func referenceNames(model interface{}) (map[uint]string, error) {
+	var rows []struct {
+		ID   uint
+		Name string
+	}
+	if err := config.DB.Model(model).Select("id", "name").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(rows))
+	for _, r := range rows {
+		names[r.ID] = r.Name
+	}
+	return names, nil
+}
+
+// ExportUsers godoc
+// @Summary Export users of the admin's hospital as CSV or XLSX (admin only)
+// @Description Takes the same filters as /listusers but returns every match, streamed. TCKN, phone and email are masked unless the caller may see them
+// @Tags Users
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv (default) or xlsx"
+// @Param name query string false "Filter by name"
+// @Param surname query string false "Filter by surname"
+// @Param tckn query string false "Filter by TCKN"
+// @Param profession_group_id query string false "Filter by profession group ID"
+// @Param title_id query string false "Filter by title ID"
+// @Success 200 {file} file
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/export [get]
+func ExportUsers(c *gin.Context) {
+	format := c.DefaultQuery("format", reports.FormatCSV)
+	if format != reports.FormatCSV && format != reports.FormatXLSX {
+		apperrors.Abort(c, apperrors.BadRequest("unsupported_export_format", "Unsupported export format"))
+		return
+	}
+
+	groups, err := referenceNames(&models.ProfessionGroup{})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "export_failed", "Export failed"))
+		return
+	}
+	titles, err := referenceNames(&models.Title{})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "export_failed", "Export failed"))
+		return
+	}
+	if err := audit.Record(config.DB, c, audit.Entry{Action: "user.list_export", EntityType: "user"}); err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "export_failed", "Export failed"))
+		return
+	}
+
+	streamUsers(c, format, groups, titles)
+}
+
+func streamUsers(c *gin.Context, format string, groups, titles map[uint]string) {
+	rows, err := filterUsers(c, config.DB.Model(&models.User{})).Order("id").Rows()
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "export_failed", "Export failed"))
+		return
+	}
+	defer rows.Close()
+
+	startDownload(c, "users-"+time.Now().Format("2006-01-02")+"."+format, reports.ContentType(format))
+	table, err := reports.NewTableWriter(c.Writer, format, []string{
+		"id", "name", "surname", "tckn", "email", "phone", "role", "language",
+		"profession_group", "title", "created_at",
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	policy := masking.For(c)
+	for rows.Next() {
+		var u models.User
+		if err := config.DB.ScanRows(rows, &u); err != nil {
+			// Headers are already sent, so the client sees a truncated file.
+			_ = c.Error(err)
+			return
+		}
+		owner := masking.Owner{UserID: u.ID, HospitalID: u.HospitalID}
+		err := table.WriteRow([]string{
+			strconv.FormatUint(uint64(u.ID), 10), u.Name, u.Surname,
+			policy.TCKN(owner, u.TCKN), policy.Email(owner, u.Email), policy.Phone(owner, u.Phone),
+			u.Role, u.Language, groups[u.ProfessionGroupID], titles[u.TitleID],
+			u.CreatedAt.Format(time.RFC3339),
+		})
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+	if err := table.Close(); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// ExportDepartments godoc
+// @Summary Export departments of the admin's hospital (admin only)
+// @Description csv and xlsx list the departments with their doctor counts. pdf renders a printable roster of doctors grouped by department, under the hospital header
+// @Tags Department
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/pdf
+// @Param format query string false "csv (default), xlsx or pdf"
+// @Success 200 {file} file
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /departments/export [get]
+func ExportDepartments(c *gin.Context) {
+	format := c.DefaultQuery("format", reports.FormatCSV)
+	if format != reports.FormatCSV && format != reports.FormatXLSX && format != "pdf" {
+		apperrors.Abort(c, apperrors.BadRequest("unsupported_export_format", "Unsupported export format"))
+		return
+	}
+	hospitalID := c.GetInt("hospitalID")
+
+	var hospital models.Hospital
+	if err := config.DB.Preload("Address").First(&hospital, hospitalID).Error; err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "export_failed", "Export failed"))
+		return
+	}
+
+	var departments []models.Department
+	err := config.DB.Preload("DepartmentType").
+		Where("hospital_id = ?", hospitalID).Order("name").
+		Find(&departments).Error
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "export_failed", "Export failed"))
+		return
+	}
+
+	var doctors []models.Doctor
+	if err := config.DB.Where("hospital_id = ?", hospitalID).Order("name").Find(&doctors).Error; err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "export_failed", "Export failed"))
+		return
+	}
+	byDepartment := map[uint][]models.Doctor{}
+	for _, d := range doctors {
+		byDepartment[d.DepartmentID] = append(byDepartment[d.DepartmentID], d)
+	}
+
+	filename := "departments-" + time.Now().Format("2006-01-02") + "." + format
+	if format == "pdf" {
+		writeRoster(c, filename, hospital, departments, byDepartment)
+		return
+	}
+
+	startDownload(c, filename, reports.ContentType(format))
+	table, err := reports.NewTableWriter(c.Writer, format, []string{"id", "name", "department_type", "doctors"})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	for _, dept := range departments {
+		err := table.WriteRow([]string{
+			strconv.FormatUint(uint64(dept.ID), 10), dept.Name, dept.DepartmentType.Name,
+			strconv.Itoa(len(byDepartment[dept.ID])),
+		})
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+	if err := table.Close(); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+func writeRoster(c *gin.Context, filename string, hospital models.Hospital, departments []models.Department, byDepartment map[uint][]models.Doctor) {
+	policy := masking.For(c)
+	var sections []reports.RosterDepartment
+	for _, dept := range departments {
+		section := reports.RosterDepartment{Name: dept.Name, Type: dept.DepartmentType.Name}
+		for _, d := range byDepartment[dept.ID] {
+			section.Members = append(section.Members, reports.RosterMember{
+				Name:  d.Name,
+				Email: policy.Email(masking.Owner{HospitalID: d.HospitalID}, d.Email),
+			})
+		}
+		sections = append(sections, section)
+	}
+
+	var addressParts []string
+	for _, part := range []string{
+		hospital.Address.Street,
+		strings.TrimSpace(hospital.Address.PostalCode + " " + hospital.Address.City),
+		hospital.Address.Country,
+	} {
+		if part != "" {
+			addressParts = append(addressParts, part)
+		}
+	}
+	header := reports.RosterHospital{
+		Name:    hospital.Name,
+		Phone:   hospital.Phone,
+		Email:   hospital.Email,
+		Address: strings.Join(addressParts, ", "),
+	}
+
+	labels := reports.RosterLabels{
+		Title:     i18n.T(c, "roster_title"),
+		Generated: i18n.T(c, "roster_generated"),
+		Name:      i18n.T(c, "roster_name"),
+		Email:     i18n.T(c, "roster_email"),
+		Empty:     i18n.T(c, "roster_empty"),
+		Page:      i18n.T(c, "roster_page"),
+	}
+
+	// Rosters are small; render to memory so a failure can still be reported.
+	var buf bytes.Buffer
+	if err := reports.WriteRosterPDF(&buf, header, sections, labels, time.Now()); err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "export_failed", "Export failed"))
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
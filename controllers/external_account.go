@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/authn"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/session"
+	"github.com/efecan/vatansoft-case/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// externalUser finds the user an account at a hospital's identity provider or
+// directory belongs to, linking it by email on the first login or
+// provisioning a new user, and syncs their name and role from the account.
+func externalUser(c *gin.Context, a authn.Account) (models.User, error) {
+	var user models.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		provisioned := false
+
+		var link models.ExternalIdentity
+		err := tx.Where("provider = ? AND issuer = ? AND subject = ?", a.Provider, a.Issuer, a.Subject).First(&link).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&user, link.UserID).Error; err != nil {
+				return apperrors.Forbidden("sso_account_not_found", "No account here matches your identity; ask your hospital admin to add you")
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			err := tx.Where("email = ?", a.Email).First(&user).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound) && a.AutoProvision:
+				user, err = provisionExternalUser(tx, a)
+				if err != nil {
+					return err
+				}
+				provisioned = true
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				return apperrors.Forbidden("sso_account_not_found", "No account here matches your identity; ask your hospital admin to add you")
+			case err != nil:
+				return apperrors.Internal(err, "sso_failed", "Signing in with the identity provider failed")
+			}
+			link = models.ExternalIdentity{
+				UserID:   user.ID,
+				Provider: a.Provider,
+				Issuer:   a.Issuer,
+				Subject:  a.Subject,
+			}
+		default:
+			return apperrors.Internal(err, "sso_failed", "Signing in with the identity provider failed")
+		}
+
+		// A hospital's IdP only vouches for that hospital's staff, and never
+		// for the platform operator's.
+		if user.HospitalID != a.HospitalID || user.PlatformAdmin {
+			return apperrors.Forbidden("sso_account_not_found", "No account here matches your identity; ask your hospital admin to add you")
+		}
+		// Invited users accept the terms with their invitation first.
+		if user.Status != models.UserStatusActive {
+			return apperrors.Forbidden("sso_invitation_pending", "Accept your invitation before signing in")
+		}
+
+		before := user
+		if a.Name != "" {
+			user.Name = a.Name
+		}
+		if a.Surname != "" {
+			user.Surname = a.Surname
+		}
+		user.Role = a.Role
+		if user.Name != before.Name || user.Surname != before.Surname || user.Role != before.Role {
+			user.Version++
+			if err := tx.Model(&user).Select("name", "surname", "role", "version").Updates(&user).Error; err != nil {
+				return apperrors.FromDB(err, "sso_failed", "Signing in with the identity provider failed")
+			}
+		}
+		// Older tokens still carry the previous role.
+		if user.Role != before.Role {
+			if _, err := session.RevokeUser(tx, user.ID, 0, user.ID); err != nil {
+				return apperrors.Internal(err, "session_revoke_failed", "Failed to revoke session")
+			}
+		}
+
+		link.LastLoginAt = now
+		if err := tx.Save(&link).Error; err != nil {
+			return apperrors.FromDB(err, "sso_failed", "Signing in with the identity provider failed")
+		}
+
+		entry := audit.Entry{
+			Action:     "user." + a.Provider + "_login",
+			EntityType: "user",
+			EntityID:   user.ID,
+			After:      user,
+			ActorID:    user.ID,
+			HospitalID: user.HospitalID,
+		}
+		if provisioned {
+			entry.Action = "user." + a.Provider + "_provision"
+		} else {
+			entry.Before = before
+		}
+		return audit.Record(tx, c, entry)
+	})
+	return user, err
+}
+
+// provisionExternalUser creates the user of an account the hospital's IdP or
+// directory vouches for. They have no password and sign in through it only.
+func provisionExternalUser(tx *gorm.DB, a authn.Account) (models.User, error) {
+	user := models.User{
+		Name:              a.Name,
+		Surname:           a.Surname,
+		Email:             a.Email,
+		Role:              a.Role,
+		HospitalID:        a.HospitalID,
+		ProfessionGroupID: a.ProfessionGroupID,
+		TitleID:           a.TitleID,
+		Status:            models.UserStatusActive,
+	}
+	// Phones are unique, so one already in use is left for the user to add.
+	if phone, ok := utils.NormalizePhone(a.Phone); ok {
+		var count int64
+		if err := tx.Model(&models.User{}).Scopes(models.UserWithPhone(phone)).Count(&count).Error; err != nil {
+			return user, apperrors.Internal(err, "sso_failed", "Signing in with the identity provider failed")
+		}
+		if count == 0 {
+			user.Phone = phone
+		}
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return user, apperrors.FromDB(err, "sso_failed", "Signing in with the identity provider failed")
+	}
+	return user, nil
+}
@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/authn"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/encryption"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB points config.DB at a transaction on the Postgres database of
+// TEST_DATABASE_URL, rolled back when the test ends. Tests needing it are
+// skipped without one.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if err := encryption.Init("", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	tx := db.Begin()
+	previous := config.DB
+	config.DB = tx
+	t.Cleanup(func() {
+		tx.Rollback()
+		config.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return tx
+}
+
+func testContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/auth/oidc/callback", nil)
+	return c
+}
+
+func createHospital(t *testing.T, tx *gorm.DB, name string) models.Hospital {
+	t.Helper()
+	h := models.Hospital{Name: name, Status: models.HospitalStatusActive}
+	if err := tx.Create(&h).Error; err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func createUser(t *testing.T, tx *gorm.DB, user models.User) models.User {
+	t.Helper()
+	if user.Status == "" {
+		user.Status = models.UserStatusActive
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func wantAppError(t *testing.T, err error, code string) {
+	t.Helper()
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) || appErr.Code != code {
+		t.Fatalf("got error %v, want %s", err, code)
+	}
+}
+
+func oidcAccount(hospitalID uint, email string) authn.Account {
+	return authn.Account{
+		Provider:   "oidc",
+		Issuer:     "https://idp.example.com",
+		Subject:    "subject-" + email,
+		Email:      email,
+		Name:       "Ayşe",
+		Surname:    "Yılmaz",
+		Role:       "staff",
+		HospitalID: hospitalID,
+	}
+}
+
+func linkCount(t *testing.T, tx *gorm.DB, a authn.Account) int64 {
+	t.Helper()
+	var count int64
+	err := tx.Model(&models.ExternalIdentity{}).
+		Where("provider = ? AND issuer = ? AND subject = ?", a.Provider, a.Issuer, a.Subject).Count(&count).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestExternalUserLinksByEmail(t *testing.T) {
+	tx := testDB(t)
+	hospital := createHospital(t, tx, "Ankara")
+	existing := createUser(t, tx, models.User{Name: "Ayse", Surname: "Y", Email: "ayse@example.com", Role: "doctor", HospitalID: hospital.ID})
+	s := models.Session{TokenID: "token-1", UserID: existing.ID, HospitalID: hospital.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := tx.Create(&s).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	a := oidcAccount(hospital.ID, existing.Email)
+	user, err := externalUser(testContext(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != existing.ID || user.Name != "Ayşe" || user.Surname != "Yılmaz" || user.Role != "staff" {
+		t.Fatalf("got %+v, want user %d synced from the account", user, existing.ID)
+	}
+	if linkCount(t, tx, a) != 1 {
+		t.Fatal("the account wasn't linked")
+	}
+	// The session was issued with the previous role.
+	if err := tx.First(&s, s.ID).Error; err != nil || s.RevokedAt == nil {
+		t.Fatalf("session not revoked after the role changed: %v", err)
+	}
+
+	// Later logins follow the link, even once the email changed at the IdP.
+	a.Email = "ayse.yilmaz@example.com"
+	user, err = externalUser(testContext(), a)
+	if err != nil || user.ID != existing.ID {
+		t.Fatalf("got user %d, %v; want %d", user.ID, err, existing.ID)
+	}
+}
+
+func TestExternalUserProvisions(t *testing.T) {
+	tx := testDB(t)
+	hospital := createHospital(t, tx, "Ankara")
+	group := models.ProfessionGroup{Name: "Nursing"}
+	if err := tx.Create(&group).Error; err != nil {
+		t.Fatal(err)
+	}
+	title := models.Title{Name: "Nurse", ProfessionGroupID: group.ID}
+	if err := tx.Create(&title).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	a := oidcAccount(hospital.ID, "new@example.com")
+	if _, err := externalUser(testContext(), a); err != nil {
+		wantAppError(t, err, "sso_account_not_found")
+	} else {
+		t.Fatal("unknown account signed in without auto_provision")
+	}
+	if linkCount(t, tx, a) != 0 {
+		t.Fatal("a refused account was linked")
+	}
+
+	a.AutoProvision = true
+	a.ProfessionGroupID = group.ID
+	a.TitleID = title.ID
+	a.Phone = "0555 111 22 33"
+	user, err := externalUser(testContext(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 || user.Email != a.Email || user.HospitalID != hospital.ID || user.Role != "staff" ||
+		user.Status != models.UserStatusActive || user.ProfessionGroupID != group.ID || user.TitleID != title.ID ||
+		user.Phone != "+905551112233" || user.Password != "" {
+		t.Fatalf("provisioned %+v", user)
+	}
+	if linkCount(t, tx, a) != 1 {
+		t.Fatal("the provisioned account wasn't linked")
+	}
+	var entries int64
+	if err := tx.Model(&models.AuditLog{}).Where("action = ? AND entity_id = ?", "user.oidc_provision", user.ID).Count(&entries).Error; err != nil || entries != 1 {
+		t.Fatalf("got %d provisioning audit entries, %v", entries, err)
+	}
+}
+
+func TestExternalUserRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, tx *gorm.DB, hospital, other models.Hospital) authn.Account
+		code  string
+	}{
+		{
+			name: "user of another hospital by email",
+			setup: func(t *testing.T, tx *gorm.DB, hospital, other models.Hospital) authn.Account {
+				user := createUser(t, tx, models.User{Email: "other@example.com", Role: "admin", HospitalID: other.ID})
+				return oidcAccount(hospital.ID, user.Email)
+			},
+			code: "sso_account_not_found",
+		},
+		{
+			name: "user of another hospital by link",
+			setup: func(t *testing.T, tx *gorm.DB, hospital, other models.Hospital) authn.Account {
+				user := createUser(t, tx, models.User{Email: "linked@example.com", Role: "admin", HospitalID: other.ID})
+				a := oidcAccount(hospital.ID, "someone@example.com")
+				link := models.ExternalIdentity{UserID: user.ID, Provider: a.Provider, Issuer: a.Issuer, Subject: a.Subject}
+				if err := tx.Create(&link).Error; err != nil {
+					t.Fatal(err)
+				}
+				return a
+			},
+			code: "sso_account_not_found",
+		},
+		{
+			name: "platform admin",
+			setup: func(t *testing.T, tx *gorm.DB, hospital, other models.Hospital) authn.Account {
+				user := createUser(t, tx, models.User{Email: "support@example.com", Role: "admin", HospitalID: hospital.ID, PlatformAdmin: true})
+				return oidcAccount(hospital.ID, user.Email)
+			},
+			code: "sso_account_not_found",
+		},
+		{
+			name: "platform admin with auto_provision",
+			setup: func(t *testing.T, tx *gorm.DB, hospital, other models.Hospital) authn.Account {
+				user := createUser(t, tx, models.User{Email: "support2@example.com", Role: "admin", HospitalID: hospital.ID, PlatformAdmin: true})
+				a := oidcAccount(hospital.ID, user.Email)
+				a.AutoProvision = true
+				return a
+			},
+			code: "sso_account_not_found",
+		},
+		{
+			name: "invitation pending",
+			setup: func(t *testing.T, tx *gorm.DB, hospital, other models.Hospital) authn.Account {
+				user := createUser(t, tx, models.User{Email: "invited@example.com", Role: "staff", HospitalID: hospital.ID, Status: models.UserStatusPending})
+				return oidcAccount(hospital.ID, user.Email)
+			},
+			code: "sso_invitation_pending",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := testDB(t)
+			hospital := createHospital(t, tx, "Ankara")
+			other := createHospital(t, tx, "İzmir")
+			a := tt.setup(t, tx, hospital, other)
+			var before int64
+			tx.Model(&models.User{}).Count(&before)
+
+			_, err := externalUser(testContext(), a)
+			wantAppError(t, err, tt.code)
+
+			var after int64
+			tx.Model(&models.User{}).Count(&after)
+			if after != before {
+				t.Fatal("a user was provisioned")
+			}
+			var unlinked int64
+			tx.Model(&models.ExternalIdentity{}).Where("subject = ? AND last_login_at > ?", a.Subject, time.Time{}).Count(&unlinked)
+			if unlinked != 0 {
+				t.Fatal("the refused login was recorded")
+			}
+		})
+	}
+}
@@ -2,16 +2,25 @@ package controllers
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
 	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/listquery"
+	"github.com/efecan/vatansoft-case/masking"
 	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/password"
+	"github.com/efecan/vatansoft-case/utils"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type HospitalRegisterRequest struct {
 	HospitalName string `json:"hospital_name"`
-	Phone        string `json:"phone"`
+	Phone        string `json:"phone" binding:"omitempty,tr_phone"`
 	Address      struct {
 		Street     string `json:"street"`
 		City       string `json:"city"`
@@ -34,79 +43,131 @@ type HospitalRegisterRequest struct {
 // @Param request body controllers.HospitalRegisterRequest true "Hospital and Admin registration info"
 // @Security BearerAuth
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
 // @Router /hospitals/register [post]
 func HospitalRegister(c *gin.Context) {
 	var req HospitalRegisterRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		apperrors.Abort(c, apperrors.Validation(err))
 		return
 	}
 
-	db := config.DB
-
-	address := models.Address{
-		Street:     req.Address.Street,
-		City:       req.Address.City,
-		PostalCode: req.Address.PostalCode,
-		Country:    req.Address.Country,
-	}
-	if err := db.Create(&address).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save address"})
-		return
-	}
-
-	hospital := models.Hospital{
-		Name:      req.HospitalName,
-		Phone:     req.Phone,
-		AddressID: address.ID,
-	}
-	if err := db.Create(&hospital).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save hospital"})
+	if err := password.Check(config.DB, "admin_user.password", req.AdminUser.Password, password.Subject{
+		Name:  req.AdminUser.Name,
+		Email: req.AdminUser.Email,
+	}); err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.AdminUser.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		apperrors.Abort(c, apperrors.Internal(err, "password_hash_failed", "failed to hash password"))
 		return
 	}
 
-	admin := models.User{
-		Name:       req.AdminUser.Name,
-		Email:      req.AdminUser.Email,
-		Password:   string(hash),
-		HospitalID: hospital.ID,
-	}
-	if err := db.Create(&admin).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save admin user"})
+	err = config.DB.Transaction(func(db *gorm.DB) error {
+		address := models.Address{
+			Street:     req.Address.Street,
+			City:       req.Address.City,
+			PostalCode: req.Address.PostalCode,
+			Country:    req.Address.Country,
+		}
+		if err := db.Create(&address).Error; err != nil {
+			return apperrors.FromDB(err, "address_create_failed", "failed to save address")
+		}
+
+		hospital := models.Hospital{
+			Name:      req.HospitalName,
+			Phone:     utils.NormalizePhoneOrKeep(req.Phone),
+			AddressID: address.ID,
+		}
+		if err := db.Create(&hospital).Error; err != nil {
+			return apperrors.FromDB(err, "hospital_create_failed", "failed to save hospital")
+		}
+
+		now := time.Now().UTC()
+		admin := models.User{
+			Name:              req.AdminUser.Name,
+			Email:             req.AdminUser.Email,
+			Password:          string(hash),
+			PasswordChangedAt: &now,
+			HospitalID:        hospital.ID,
+		}
+		if err := db.Create(&admin).Error; err != nil {
+			return apperrors.FromDB(err, "admin_create_failed", "failed to save admin user")
+		}
+		if err := password.Remember(db, admin); err != nil {
+			return apperrors.Internal(err, "admin_create_failed", "failed to save admin user")
+		}
+
+		if err := audit.Record(db, c, audit.Entry{
+			Action:     "hospital.register",
+			EntityType: "hospital",
+			EntityID:   hospital.ID,
+			After:      hospital,
+		}); err != nil {
+			return err
+		}
+		return audit.Record(db, c, audit.Entry{
+			Action:     "user.create",
+			EntityType: "user",
+			EntityID:   admin.ID,
+			After:      admin,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "hospital & admin created"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "hospital_admin_created")})
 
 }
 
+var hospitalListSpec = listquery.Spec{
+	Model: &models.Hospital{},
+	Sortable: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	DefaultSort: "id",
+	Fields:      []string{"id", "name", "phone", "address", "admins"},
+}
+
 // GetHospitals godoc
 // @Summary List all hospitals with their address and admin users
-// @Description Returns a list of all registered hospitals including address and admin info
+// @Description Returns a list of all registered hospitals including address and admin info. Admin emails are masked unless the caller may see them. Sortable by id, name and created_at
 // @Tags hospitals
 // @Produce json
+// @Param sort query string false "Comma separated fields, prefix with - for descending"
+// @Param page_size query int false "Items per page (max 100)"
+// @Param page query int false "Page number, ignored when cursor is set"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated fields to return"
 // @Security BearerAuth
-// @Success 200 {array} map[string]interface{}
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} listquery.Page{data=[]map[string]interface{}}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
 // @Router /hospitals [get]
 func GetHospitals(c *gin.Context) {
-	var hospitals []models.Hospital
+	q, err := listquery.Parse(c, hospitalListSpec)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
 
-	err := config.DB.Preload("Address").Preload("Users").Find(&hospitals).Error
+	var hospitals []models.Hospital
+	result, err := q.Find(config.DB, &hospitals, "Address", "Users")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch hospitals"})
+		apperrors.Abort(c, apperrors.Internal(err, "hospitals_fetch_failed", "Failed to fetch hospitals"))
 		return
 	}
 
+	policy := masking.For(c)
 	var response []gin.H
 	for _, h := range hospitals {
 		var admins []gin.H
@@ -114,7 +175,7 @@ func GetHospitals(c *gin.Context) {
 			admins = append(admins, gin.H{
 				"id":    u.ID,
 				"name":  u.Name,
-				"email": u.Email,
+				"email": policy.Email(masking.Owner{UserID: u.ID, HospitalID: u.HospitalID}, u.Email),
 			})
 		}
 
@@ -127,5 +188,5 @@ func GetHospitals(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, response)
+	q.Respond(c, response, result)
 }
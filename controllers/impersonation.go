@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/session"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ImpersonateRequest struct {
+	// Reason is kept in the audit log, e.g. the support ticket being worked on.
+	Reason string `json:"reason" binding:"required,min=5,max=500"`
+}
+
+type ImpersonateResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    uint      `json:"user_id"`
+	SessionID uint      `json:"session_id"`
+}
+
+// ImpersonateUser godoc
+// @Summary Act as another user (platform admin only)
+// @Description Issues a short-lived token for the user that also names the platform admin in its act claim. Requests made with it behave exactly as the user's own, except that password and contact changes are refused; every request is recorded in the audit log of the user's hospital with impersonator_id set, and responses carry an X-Impersonated-By header. End it early by revoking the session through DELETE /me/sessions/{id}. Platform admins and inactive users can't be impersonated
+// @Tags Impersonation
+// @Accept json
+// @Produce json
+// @Param userID path int true "User to impersonate"
+// @Param request body ImpersonateRequest true "Reason for the audit log"
+// @Success 200 {object} ImpersonateResponse
+// @Failure 400 {object} apperrors.Problem
+// @Failure 403 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /admin/impersonate/{userID} [post]
+func ImpersonateUser(c *gin.Context) {
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+	id, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	var admin models.User
+	if err := config.DB.Where("platform_admin = ? AND status = ?", true, models.UserStatusActive).First(&admin, c.GetInt("userID")).Error; err != nil {
+		apperrors.Abort(c, apperrors.Forbidden("platform_admin_required", "Platform admin access required"))
+		return
+	}
+
+	var target models.User
+	if err := config.DB.Where("status = ?", models.UserStatusActive).First(&target, id).Error; err != nil {
+		apperrors.Abort(c, apperrors.NotFound("user_not_found", "User not found"))
+		return
+	}
+	if target.ID == admin.ID || target.PlatformAdmin {
+		apperrors.Abort(c, apperrors.Forbidden("impersonation_not_allowed", "This user can't be impersonated"))
+		return
+	}
+
+	var s models.Session
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		s, err = session.StartImpersonation(tx, target, admin, c.Request.UserAgent(), c.ClientIP())
+		if err != nil {
+			return apperrors.Internal(err, "session_create_failed", "Failed to create session")
+		}
+		// Recorded in the target's hospital so its admins see it too.
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "impersonation.start",
+			EntityType: "user",
+			EntityID:   target.ID,
+			After: gin.H{
+				"reason":     req.Reason,
+				"session_id": s.ID,
+				"expires_at": s.ExpiresAt,
+			},
+			HospitalID: target.HospitalID,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	token, err := signToken(target, s, &admin)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ImpersonateResponse{
+		Token:     token,
+		ExpiresAt: s.ExpiresAt,
+		UserID:    target.ID,
+		SessionID: s.ID,
+	})
+}
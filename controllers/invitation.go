@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/invite"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/notify"
+	"github.com/efecan/vatansoft-case/password"
+	"github.com/efecan/vatansoft-case/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type InvitationResponse struct {
+	Name         string    `json:"name"`
+	Surname      string    `json:"surname"`
+	Hospital     string    `json:"hospital"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	TermsVersion string    `json:"terms_version"`
+}
+
+type AcceptInvitationRequest struct {
+	Token           string `json:"token" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	ConfirmPassword string `json:"confirm_password" binding:"required"`
+	AcceptTerms     bool   `json:"accept_terms"`
+}
+
+type ResendInvitationRequest struct {
+	Channel string `json:"channel" binding:"omitempty,oneof=email sms"`
+}
+
+// invitationError maps an invite lookup error onto an API error.
+func invitationError(err error) *apperrors.Error {
+	switch {
+	case errors.Is(err, invite.ErrNotFound):
+		return apperrors.NotFound("invitation_not_found", "Invitation not found")
+	case errors.Is(err, invite.ErrExpired):
+		return apperrors.BadRequest("invitation_expired", "Invitation has expired; ask your administrator to resend it")
+	case errors.Is(err, invite.ErrRevoked):
+		return apperrors.BadRequest("invitation_revoked", "Invitation was revoked")
+	case errors.Is(err, invite.ErrAccepted):
+		return apperrors.Conflict("invitation_accepted", "Invitation was already accepted")
+	}
+	return apperrors.Internal(err, "invitation_fetch_failed", "Failed to fetch invitation")
+}
+
+// GetInvitation godoc
+// @Summary Show an invitation
+// @Description Lets the invitee check an invitation token before accepting it
+// @Tags Invitations
+// @Produce json
+// @Param token path string true "Invitation token"
+// @Success 200 {object} InvitationResponse
+// @Failure 400 {object} apperrors.Problem "Invitation expired or revoked"
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem "Invitation already accepted"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
+// @Router /invitations/{token} [get]
+func GetInvitation(c *gin.Context) {
+	inv, user, err := invite.Lookup(config.DB, c.Param("token"))
+	if err != nil {
+		apperrors.Abort(c, invitationError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, InvitationResponse{
+		Name:         user.Name,
+		Surname:      user.Surname,
+		Hospital:     user.Hospital.Name,
+		ExpiresAt:    inv.ExpiresAt,
+		TermsVersion: invite.TermsVersion(),
+	})
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Sets the invitee's password, records their acceptance of the terms and activates the account. The password must meet the hospital's password policy
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Param request body AcceptInvitationRequest true "Token, new password and terms acceptance"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem "Invitation already accepted"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
+// @Failure 500 {object} apperrors.Problem
+// @Router /invitations/accept [post]
+func AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+	if req.Password != req.ConfirmPassword {
+		apperrors.Abort(c, apperrors.BadRequest("password_mismatch", "Passwords do not match"))
+		return
+	}
+	if !req.AcceptTerms {
+		apperrors.Abort(c, apperrors.BadRequest("terms_not_accepted", "The terms must be accepted"))
+		return
+	}
+
+	inv, user, err := invite.Lookup(config.DB, req.Token)
+	if err != nil {
+		apperrors.Abort(c, invitationError(err))
+		return
+	}
+	if err := password.Check(config.DB, "password", req.Password, password.SubjectOf(user)); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	hashed, err := utils.HashPassword(req.Password)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "password_hash_failed", "Failed to hash password"))
+		return
+	}
+
+	now := time.Now().UTC()
+	before := user
+	user.Password = hashed
+	user.Status = models.UserStatusActive
+	user.TermsAcceptedAt = &now
+	user.TermsVersion = invite.TermsVersion()
+	user.Version++
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Claiming the invitation first makes concurrent accepts of the same
+		// token fail instead of both succeeding.
+		result := tx.Model(&inv).Where("accepted_at IS NULL AND revoked_at IS NULL").Update("accepted_at", now)
+		if result.Error != nil {
+			return apperrors.Internal(result.Error, "invitation_accept_failed", "Failed to accept invitation")
+		}
+		if result.RowsAffected == 0 {
+			return apperrors.Conflict("invitation_accepted", "Invitation was already accepted")
+		}
+		if err := password.Changed(tx, &user); err != nil {
+			return apperrors.Internal(err, "invitation_accept_failed", "Failed to accept invitation")
+		}
+		if err := tx.Model(&user).
+			Select("password", "password_changed_at", "must_change_password", "status",
+				"terms_accepted_at", "terms_version", "version").
+			Updates(&user).Error; err != nil {
+			return apperrors.Internal(err, "invitation_accept_failed", "Failed to accept invitation")
+		}
+		// The invitee isn't authenticated yet; attribute the change to them.
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "invitation.accept",
+			EntityType: "user",
+			EntityID:   user.ID,
+			Before:     before,
+			After:      user,
+			ActorID:    user.ID,
+			HospitalID: user.HospitalID,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "invitation_accepted_ok")})
+}
+
+// loadPendingInvitation loads the pending user named in the path, scoped to
+// the admin's hospital, together with their invitation if they have one.
+func loadPendingInvitation(c *gin.Context) (models.User, *models.Invitation, bool) {
+	var user models.User
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_user_id", "Invalid user ID"))
+		return user, nil, false
+	}
+	err = config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")).Preload("Hospital").First(&user, id).Error
+	if err != nil {
+		apperrors.Abort(c, apperrors.NotFound("user_not_found", "User not found"))
+		return user, nil, false
+	}
+	if user.Status != models.UserStatusPending {
+		apperrors.Abort(c, apperrors.Conflict("user_not_pending", "User has already accepted their invitation"))
+		return user, nil, false
+	}
+
+	var inv models.Invitation
+	err = config.DB.Where("user_id = ?", user.ID).First(&inv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, nil, true
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "invitation_fetch_failed", "Failed to fetch invitation"))
+		return user, nil, false
+	}
+	return user, &inv, true
+}
+
+// ResendInvitation godoc
+// @Summary Resend a user's invitation (admin only)
+// @Description Issues a new token with a fresh expiry and delivers it, invalidating the previous token. Also reopens a revoked invitation. channel switches between email and SMS
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body ResendInvitationRequest false "Delivery channel"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem "User is not pending"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/{id}/invitation/resend [post]
+func ResendInvitation(c *gin.Context) {
+	var req ResendInvitationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apperrors.Abort(c, apperrors.Validation(err))
+			return
+		}
+	}
+
+	user, inv, ok := loadPendingInvitation(c)
+	if !ok {
+		return
+	}
+
+	var token string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if inv == nil {
+			channel := req.Channel
+			if channel == "" {
+				channel = notify.ChannelEmail
+			}
+			var created models.Invitation
+			created, token, err = invite.Create(tx, user, channel, uint(c.GetInt("userID")))
+			inv = &created
+		} else {
+			token, err = invite.Renew(tx, inv, req.Channel)
+		}
+		if err != nil {
+			return apperrors.Internal(err, "invitation_create_failed", "Failed to create invitation")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "invitation.resend",
+			EntityType: "invitation",
+			EntityID:   inv.ID,
+			After:      inv,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	sent := true
+	if err := invite.Send(c, user, *inv, token); err != nil {
+		log.Printf("⚠️ Could not deliver invitation %d: %v", inv.ID, err)
+		sent = false
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         i18n.T(c, "invitation_resent"),
+		"invitation_sent": sent,
+	})
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke a user's invitation (admin only)
+// @Description The token stops working; the user stays pending and can be invited again with resend
+// @Tags Invitations
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem "User is not pending"
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/{id}/invitation [delete]
+func RevokeInvitation(c *gin.Context) {
+	_, inv, ok := loadPendingInvitation(c)
+	if !ok {
+		return
+	}
+	if inv == nil || inv.RevokedAt != nil {
+		apperrors.Abort(c, apperrors.NotFound("invitation_not_found", "Invitation not found"))
+		return
+	}
+
+	before := *inv
+	now := time.Now().UTC()
+	inv.RevokedAt = &now
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(inv).Update("revoked_at", now).Error; err != nil {
+			return apperrors.Internal(err, "invitation_revoke_failed", "Failed to revoke invitation")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "invitation.revoke",
+			EntityType: "invitation",
+			EntityID:   inv.ID,
+			Before:     before,
+			After:      inv,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "invitation_revoked_ok")})
+}
@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/authn"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/netguard"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LDAPConfigRequest struct {
+	Enabled       bool   `json:"enabled"`
+	URL           string `json:"url" binding:"required,url"`
+	StartTLS      bool   `json:"start_tls"`
+	CACertificate string `json:"ca_certificate" binding:"max=65536"`
+	BindDN        string `json:"bind_dn" binding:"max=512"`
+	// BindPassword is write-only. Leave it out to keep the stored password.
+	BindPassword *string `json:"bind_password" binding:"omitnil,max=512"`
+	BaseDN       string  `json:"base_dn" binding:"required,max=512"`
+	// UserFilter defaults to matching mail or userPrincipalName against {email}.
+	UserFilter   string                      `json:"user_filter" binding:"max=1024"`
+	Attributes   models.LDAPAttributeMapping `json:"attributes"`
+	EmailDomains []string                    `json:"email_domains" binding:"max=20,dive,fqdn"`
+	RoleMapping  map[string]string           `json:"role_mapping"`
+	DefaultRole  string                      `json:"default_role" binding:"max=50"`
+
+	AutoProvision            bool `json:"auto_provision"`
+	DefaultProfessionGroupID uint `json:"default_profession_group_id" binding:"required_if=AutoProvision true"`
+	DefaultTitleID           uint `json:"default_title_id" binding:"required_if=AutoProvision true"`
+}
+
+// GetLDAPConfig godoc
+// @Summary Get the LDAP directory settings of the admin's hospital (admin only)
+// @Description The bind password is never returned
+// @Tags Directory
+// @Produce json
+// @Success 200 {object} models.LDAPDirectory
+// @Failure 404 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /ldap-config [get]
+func GetLDAPConfig(c *gin.Context) {
+	var d models.LDAPDirectory
+	if err := config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")).First(&d).Error; err != nil {
+		apperrors.Abort(c, apperrors.NotFound("ldap_not_configured", "No LDAP directory is set up for this hospital"))
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// UpdateLDAPConfig godoc
+// @Summary Log staff in against the hospital's LDAP directory, e.g. Active Directory (admin only)
+// @Description When enabled, /login looks staff up in the directory with the service account (bind_dn) and binds as them with the password they entered. Users the directory doesn't know keep logging in with their local password; users once linked to it can't. Roles are synced on every login from the user's groups (memberOf by default) through role_mapping, keyed by group DN or CN, falling back to default_role; users with neither can't log in. With auto_provision, unknown users whose email domain is in email_domains are created with the default profession group and title. The settings are checked by connecting to the directory when enabled; directories on private or local networks are refused unless INTERNAL_NETWORKS_ALLOWED lists them
+// @Tags Directory
+// @Accept json
+// @Produce json
+// @Param config body LDAPConfigRequest true "Directory settings"
+// @Success 200 {object} models.LDAPDirectory
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /ldap-config [put]
+func UpdateLDAPConfig(c *gin.Context) {
+	var req LDAPConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+	if !strings.HasPrefix(req.URL, "ldap://") && !strings.HasPrefix(req.URL, "ldaps://") {
+		apperrors.Abort(c, apperrors.InvalidField("url", "ldap_url", "field_ldap_url"))
+		return
+	}
+	if req.CACertificate != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(req.CACertificate)) {
+		apperrors.Abort(c, apperrors.InvalidField("ca_certificate", "pem", "field_pem"))
+		return
+	}
+
+	hospitalID := uint(c.GetInt("hospitalID"))
+	var d models.LDAPDirectory
+	err := config.DB.Where("hospital_id = ?", hospitalID).First(&d).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		apperrors.Abort(c, apperrors.Internal(err, "ldap_config_fetch_failed", "Failed to load directory settings"))
+		return
+	}
+	before := d
+
+	domains := make([]string, 0, len(req.EmailDomains))
+	for _, domain := range req.EmailDomains {
+		domains = append(domains, strings.ToLower(domain))
+	}
+	roleMapping := make(map[string]string, len(req.RoleMapping))
+	for group, role := range req.RoleMapping {
+		roleMapping[group] = strings.ToLower(strings.TrimSpace(role))
+	}
+	d.HospitalID = hospitalID
+	d.Enabled = req.Enabled
+	d.URL = req.URL
+	d.StartTLS = req.StartTLS
+	d.CACertificate = req.CACertificate
+	d.BindDN = req.BindDN
+	if req.BindPassword != nil {
+		d.BindPassword = *req.BindPassword
+	}
+	d.BaseDN = req.BaseDN
+	d.UserFilter = req.UserFilter
+	d.Attributes = req.Attributes
+	d.EmailDomains = domains
+	d.RoleMapping = roleMapping
+	d.DefaultRole = strings.ToLower(strings.TrimSpace(req.DefaultRole))
+	d.AutoProvision = req.AutoProvision
+	d.DefaultProfessionGroupID = req.DefaultProfessionGroupID
+	d.DefaultTitleID = req.DefaultTitleID
+
+	if d.Enabled {
+		conn, err := authn.Dial(d, 10*time.Second)
+		if errors.Is(err, netguard.ErrForbidden) {
+			apperrors.Abort(c, apperrors.InvalidField("url", "internal_address", "field_internal_address"))
+			return
+		} else if err != nil {
+			apperrors.Abort(c, apperrors.InvalidField("url", "ldap_connect", "field_ldap_connect"))
+			return
+		}
+		conn.Close()
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if d.AutoProvision {
+			if err := checkProvisioningDefaults(tx, d.DefaultProfessionGroupID, d.DefaultTitleID); err != nil {
+				return err
+			}
+		}
+		// A domain routes unknown users to one hospital only.
+		for _, domain := range d.EmailDomains {
+			var count int64
+			err := tx.Model(&models.LDAPDirectory{}).
+				Where("hospital_id <> ? AND email_domains @> ?", hospitalID, fmt.Sprintf("[%q]", domain)).
+				Count(&count).Error
+			if err != nil {
+				return apperrors.Internal(err, "ldap_config_update_failed", "Failed to save directory settings")
+			}
+			if count > 0 {
+				return apperrors.InvalidField("email_domains", "unique", "field_unique")
+			}
+		}
+		if err := tx.Save(&d).Error; err != nil {
+			return apperrors.FromDB(err, "ldap_config_update_failed", "Failed to save directory settings")
+		}
+		entry := audit.Entry{
+			Action:     "ldap_config.update",
+			EntityType: "ldap_directory",
+			EntityID:   d.ID,
+			After:      d,
+		}
+		if before.ID != 0 {
+			entry.Before = before
+		}
+		return audit.Record(tx, c, entry)
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, d)
+}
+
+// DeleteLDAPConfig godoc
+// @Summary Stop logging staff in against the hospital's LDAP directory (admin only)
+// @Description Users keep their links to the directory, so setting it up again restores them. Users without a local password have to reset it to log in
+// @Tags Directory
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /ldap-config [delete]
+func DeleteLDAPConfig(c *gin.Context) {
+	var d models.LDAPDirectory
+	if err := config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")).First(&d).Error; err != nil {
+		apperrors.Abort(c, apperrors.NotFound("ldap_not_configured", "No LDAP directory is set up for this hospital"))
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&d).Error; err != nil {
+			return apperrors.FromDB(err, "ldap_config_delete_failed", "Failed to remove directory settings")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "ldap_config.delete",
+			EntityType: "ldap_directory",
+			EntityID:   d.ID,
+			Before:     d,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "ldap_config_deleted")})
+}
@@ -2,10 +2,11 @@ package controllers
 
 import (
 	"encoding/json"
-	"net/http"
 	"time"
 
+	"github.com/efecan/vatansoft-case/apperrors"
 	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/listquery"
 	"github.com/efecan/vatansoft-case/models"
 	"github.com/gin-gonic/gin"
 )
@@ -21,30 +22,58 @@ type CityResponse struct {
 	Districts []DistrictResponse `json:"districts"`
 }
 
+var cityListSpec = listquery.Spec{
+	Model: &models.City{},
+	Sortable: map[string]string{
+		"id":   "id",
+		"name": "name",
+	},
+	DefaultSort: "id",
+	Fields:      listquery.FieldsOf(CityResponse{}),
+}
+
+// cachedCityPage is a page of cities as kept in Redis.
+type cachedCityPage struct {
+	Data   []CityResponse   `json:"data"`
+	Result listquery.Result `json:"result"`
+}
+
 // GetCities godoc
 // @Summary List cities with districts
-// @Description Returns a list of all cities and their districts, with Redis caching
+// @Description Returns the cities and their districts, with Redis caching. Sortable by id and name
 // @Tags Location
 // @Produce json
-// @Success 200 {array} CityResponse
-// @Failure 500 {object} map[string]string
+// @Param sort query string false "Comma separated fields, prefix with - for descending"
+// @Param page_size query int false "Items per page (max 100)"
+// @Param page query int false "Page number, ignored when cursor is set"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated fields to return"
+// @Success 200 {object} listquery.Page{data=[]CityResponse}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
 // @Security BearerAuth
 // @Router /cities [get]
 func GetCities(c *gin.Context) {
-	cacheKey := "cities_with_districts"
+	q, err := listquery.Parse(c, cityListSpec)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	cacheKey := "cities_with_districts:" + q.Key()
 
 	cached, err := config.REDIS.Get(c, cacheKey).Result()
 	if err == nil {
-		var response []CityResponse
-		if err := json.Unmarshal([]byte(cached), &response); err == nil {
-			c.JSON(http.StatusOK, response)
+		var page cachedCityPage
+		if err := json.Unmarshal([]byte(cached), &page); err == nil {
+			q.Respond(c, page.Data, page.Result)
 			return
 		}
 	}
 
 	var cities []models.City
-	if err := config.DB.Preload("Districts").Find(&cities).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cities"})
+	result, err := q.Find(config.DB, &cities, "Districts")
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "cities_fetch_failed", "Failed to retrieve cities"))
 		return
 	}
 
@@ -65,8 +94,8 @@ func GetCities(c *gin.Context) {
 		})
 	}
 
-	data, _ := json.Marshal(response)
+	data, _ := json.Marshal(cachedCityPage{Data: response, Result: result})
 	config.REDIS.Set(c, cacheKey, data, 24*time.Hour)
 
-	c.JSON(http.StatusOK, response)
+	q.Respond(c, response, result)
 }
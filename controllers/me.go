@@ -0,0 +1,432 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/masking"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/notify"
+	"github.com/efecan/vatansoft-case/otp"
+	"github.com/efecan/vatansoft-case/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Contact fields that are only changed once the new value is verified.
+const (
+	contactEmail = "email"
+	contactPhone = "phone"
+)
+
+type MeHospital struct {
+	ID      uint           `json:"id"`
+	Name    string         `json:"name"`
+	Email   string         `json:"email"`
+	Phone   string         `json:"phone"`
+	Address models.Address `json:"address"`
+}
+
+type MeResponse struct {
+	UserResponse
+	Hospital          MeHospital `json:"hospital"`
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
+	TermsAcceptedAt   *time.Time `json:"terms_accepted_at,omitempty"`
+	TermsVersion      string     `json:"terms_version,omitempty"`
+	// ImpersonatedBy is set when a platform admin is acting as the user, so
+	// clients can show it.
+	ImpersonatedBy *MeImpersonator `json:"impersonated_by,omitempty"`
+}
+
+type MeImpersonator struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type MePatch struct {
+	// Email and Phone aren't changed right away: a code is sent to the new
+	// address and the change applies once it is confirmed.
+	Email    *string `json:"email" binding:"omitnil,email"`
+	Phone    *string `json:"phone" binding:"omitnil,tr_phone"`
+	Language *string `json:"language" binding:"omitnil,oneof=tr en"`
+}
+
+type MePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+	ConfirmPassword string `json:"confirm_password" binding:"required"`
+}
+
+type ContactVerifyRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// contactCodeTTL is how long a contact change code stays valid.
+func contactCodeTTL() time.Duration {
+	ttl, err := time.ParseDuration(config.GetEnv("CONTACT_CHANGE_CODE_TTL", "15m"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
+	}
+	return ttl
+}
+
+func contactChangeKey(userID uint, field string) string {
+	return "contact_change:" + strconv.FormatUint(uint64(userID), 10) + ":" + field
+}
+
+// currentUser loads the authenticated user. Users deleted or erased since
+// their token was issued are reported as not found.
+func currentUser(c *gin.Context, preloads ...string) (models.User, error) {
+	query := config.DB
+	for _, p := range preloads {
+		query = query.Preload(p)
+	}
+	var user models.User
+	// Admins of unverified registrations only get here through the routes
+	// RequireAuth lets them reach.
+	statuses := []string{models.UserStatusActive, models.UserStatusUnverified}
+	err := query.Where("status IN ?", statuses).First(&user, c.GetInt("userID")).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, apperrors.NotFound("user_not_found", "User not found")
+		}
+		return user, apperrors.Internal(err, "users_fetch_failed", "Failed to fetch users")
+	}
+	return user, nil
+}
+
+// GetMe godoc
+// @Summary Get the profile of the logged in user
+// @Description Returns the caller's own profile, unmasked, with their hospital, profession group and title
+// @Tags Me
+// @Produce json
+// @Success 200 {object} MeResponse
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /me [get]
+func GetMe(c *gin.Context) {
+	user, err := currentUser(c, "Hospital.Address", "ProfessionGroup", "Title")
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	var impersonator *MeImpersonator
+	if id := c.GetInt("impersonatorID"); id != 0 {
+		impersonator = &MeImpersonator{ID: uint(id), Name: c.GetString("impersonatorName")}
+	}
+
+	c.JSON(http.StatusOK, MeResponse{
+		UserResponse: newUserResponse(masking.For(c), user),
+		Hospital: MeHospital{
+			ID:      user.Hospital.ID,
+			Name:    user.Hospital.Name,
+			Email:   user.Hospital.Email,
+			Phone:   user.Hospital.Phone,
+			Address: user.Hospital.Address,
+		},
+		PasswordChangedAt: user.PasswordChangedAt,
+		TermsAcceptedAt:   user.TermsAcceptedAt,
+		TermsVersion:      user.TermsVersion,
+		ImpersonatedBy:    impersonator,
+	})
+}
+
+// UpdateMe godoc
+// @Summary Update the contact details of the logged in user
+// @Description language changes right away. A new email or phone is only applied once confirmed: a six digit code is sent to the new address and must be posted to /me/email/verify or /me/phone/verify. The response lists the fields waiting for verification
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Param profile body MePatch true "Fields to change"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem "Email or phone already in use"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /me [patch]
+func UpdateMe(c *gin.Context) {
+	var patch MePatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	// Check everything before sending any code, so a conflicting phone doesn't
+	// leave an email change half started.
+	var email, phone string
+	if patch.Email != nil && *patch.Email != user.Email {
+		email = *patch.Email
+		var count int64
+		if err := config.DB.Model(&models.User{}).Where("email = ? AND id <> ?", email, user.ID).Count(&count).Error; err != nil {
+			apperrors.Abort(c, apperrors.Internal(err, "users_fetch_failed", "Failed to fetch users"))
+			return
+		}
+		if count > 0 {
+			apperrors.Abort(c, apperrors.Conflict("email_taken", "This email address is already in use"))
+			return
+		}
+	}
+	if patch.Phone != nil {
+		if normalized := utils.NormalizePhoneOrKeep(*patch.Phone); normalized != user.Phone {
+			phone = normalized
+			var count int64
+			if err := config.DB.Model(&models.User{}).Scopes(models.UserWithPhone(phone)).Where("id <> ?", user.ID).Count(&count).Error; err != nil {
+				apperrors.Abort(c, apperrors.Internal(err, "users_fetch_failed", "Failed to fetch users"))
+				return
+			}
+			if count > 0 {
+				apperrors.Abort(c, apperrors.Conflict("phone_taken", "This phone number is already in use"))
+				return
+			}
+		}
+	}
+
+	if patch.Language != nil && *patch.Language != user.Language {
+		before := user
+		user.Language = *patch.Language
+		user.Version++
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Select("language", "version").Updates(&user).Error; err != nil {
+				return apperrors.FromDB(err, "user_update_failed", "Failed to update user")
+			}
+			return audit.Record(tx, c, audit.Entry{
+				Action:     "user.self_update",
+				EntityType: "user",
+				EntityID:   user.ID,
+				Before:     before,
+				After:      user,
+			})
+		})
+		if err != nil {
+			apperrors.Abort(c, err)
+			return
+		}
+	}
+
+	pending := []string{}
+	if email != "" {
+		if err := sendContactCode(c, user, contactEmail, notify.ChannelEmail, email); err != nil {
+			apperrors.Abort(c, err)
+			return
+		}
+		pending = append(pending, contactEmail)
+	}
+	if phone != "" {
+		if err := sendContactCode(c, user, contactPhone, notify.ChannelSMS, phone); err != nil {
+			apperrors.Abort(c, err)
+			return
+		}
+		pending = append(pending, contactPhone)
+	}
+
+	message := "profile_updated"
+	if len(pending) > 0 {
+		message = "contact_verification_sent"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":              i18n.T(c, message),
+		"pending_verification": pending,
+	})
+}
+
+// sendContactCode starts the verification of a new email or phone, replacing
+// any change of the same field still waiting for its code.
+func sendContactCode(c *gin.Context, user models.User, field, channel, to string) error {
+	ttl := contactCodeTTL()
+	code, err := otp.Issue(c.Request.Context(), contactChangeKey(user.ID, field), to, ttl)
+	if err != nil {
+		return apperrors.Internal(err, "contact_code_failed", "Failed to create verification code")
+	}
+	lang := user.Language
+	if !i18n.IsSupported(lang) {
+		lang = i18n.Default()
+	}
+	params := map[string]string{
+		"name":    user.Name,
+		"code":    code,
+		"minutes": strconv.Itoa(int(ttl.Minutes())),
+	}
+	err = notify.Send(c.Request.Context(), notify.Message{
+		Channel: channel,
+		To:      to,
+		Subject: i18n.Message(lang, "contact_code_subject", params),
+		Body:    i18n.Message(lang, "contact_code_body", params),
+	})
+	if err != nil {
+		return apperrors.Internal(err, "contact_code_failed", "Failed to send verification code")
+	}
+	return nil
+}
+
+// VerifyEmailChange godoc
+// @Summary Confirm a new email address
+// @Description Applies the email change started with PATCH /me. A code is void after five wrong attempts; request a new one with PATCH /me. The previous address is told about the change
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Param request body ContactVerifyRequest true "Code sent to the new address"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem "Invalid or expired code"
+// @Failure 409 {object} apperrors.Problem "Email taken in the meantime"
+// @Failure 429 {object} apperrors.Problem "Too many wrong codes"
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /me/email/verify [post]
+func VerifyEmailChange(c *gin.Context) {
+	verifyContactChange(c, contactEmail)
+}
+
+// VerifyPhoneChange godoc
+// @Summary Confirm a new phone number
+// @Description Applies the phone change started with PATCH /me. A code is void after five wrong attempts; request a new one with PATCH /me
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Param request body ContactVerifyRequest true "Code sent to the new phone"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem "Invalid or expired code"
+// @Failure 409 {object} apperrors.Problem "Phone taken in the meantime"
+// @Failure 429 {object} apperrors.Problem "Too many wrong codes"
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /me/phone/verify [post]
+func VerifyPhoneChange(c *gin.Context) {
+	verifyContactChange(c, contactPhone)
+}
+
+func verifyContactChange(c *gin.Context, field string) {
+	var req ContactVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	value, err := otp.Verify(c.Request.Context(), contactChangeKey(user.ID, field), req.Code)
+	switch {
+	case errors.Is(err, otp.ErrInvalid), errors.Is(err, otp.ErrExpired):
+		apperrors.Abort(c, apperrors.BadRequest("invalid_code", "Invalid or expired code"))
+		return
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		apperrors.Abort(c, apperrors.TooManyRequests("too_many_attempts", "Too many wrong codes; request a new one"))
+		return
+	case err != nil:
+		apperrors.Abort(c, apperrors.Internal(err, "contact_verify_failed", "Failed to verify code"))
+		return
+	}
+
+	before := user
+	columns := []string{"version"}
+	if field == contactEmail {
+		user.Email = value
+		columns = append(columns, "email")
+	} else {
+		user.Phone = value
+		columns = append(columns, "phone", "phone_index")
+	}
+	user.Version++
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Select(columns).Updates(&user).Error; err != nil {
+			return apperrors.FromDB(err, "user_update_failed", "Failed to update user")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "user." + field + "_change",
+			EntityType: "user",
+			EntityID:   user.ID,
+			Before:     before,
+			After:      user,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	// Tell the old address, so a hijacked session can't quietly take over
+	// the account's email.
+	if field == contactEmail && before.Email != "" {
+		lang := user.Language
+		if !i18n.IsSupported(lang) {
+			lang = i18n.Default()
+		}
+		params := map[string]string{"name": user.Name, "email": user.Email}
+		err := notify.Send(c.Request.Context(), notify.Message{
+			Channel: notify.ChannelEmail,
+			To:      before.Email,
+			Subject: i18n.Message(lang, "email_changed_subject", params),
+			Body:    i18n.Message(lang, "email_changed_body", params),
+		})
+		if err != nil {
+			log.Printf("⚠️ Could not notify user %d of their email change: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, field+"_changed")})
+}
+
+// ChangeMyPassword godoc
+// @Summary Change the password of the logged in user
+// @Description Requires the current password. The new password must meet the hospital's password policy
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Param request body MePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem "Invalid request or a password that violates the policy"
+// @Failure 401 {object} apperrors.Problem "Wrong current password"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /me/password [post]
+func ChangeMyPassword(c *gin.Context) {
+	var req MePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+	if req.NewPassword != req.ConfirmPassword {
+		apperrors.Abort(c, apperrors.BadRequest("password_mismatch", "Passwords do not match"))
+		return
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		apperrors.Abort(c, apperrors.Unauthorized("wrong_current_password", "Current password is incorrect"))
+		return
+	}
+
+	if err := changePassword(c, &user, "new_password", req.NewPassword); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "password_changed")})
+}
@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/authn"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/netguard"
+	"github.com/efecan/vatansoft-case/sso"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OIDCConfigRequest struct {
+	Enabled  bool   `json:"enabled"`
+	Issuer   string `json:"issuer" binding:"required,url"`
+	ClientID string `json:"client_id" binding:"required,max=255"`
+	// ClientSecret is write-only. Leave it out to keep the stored secret;
+	// public clients relying on PKCE alone may never set one.
+	ClientSecret *string `json:"client_secret" binding:"omitnil,max=512"`
+	// Scopes are requested in addition to openid, email and profile.
+	Scopes      []string                `json:"scopes" binding:"max=20"`
+	Claims      models.OIDCClaimMapping `json:"claims"`
+	RoleMapping map[string]string       `json:"role_mapping"`
+	DefaultRole string                  `json:"default_role" binding:"max=50"`
+
+	AutoProvision            bool `json:"auto_provision"`
+	DefaultProfessionGroupID uint `json:"default_profession_group_id" binding:"required_if=AutoProvision true"`
+	DefaultTitleID           uint `json:"default_title_id" binding:"required_if=AutoProvision true"`
+}
+
+// GetOIDCConfig godoc
+// @Summary Get the OpenID Connect settings of the admin's hospital (admin only)
+// @Description The client secret is never returned
+// @Tags Single sign-on
+// @Produce json
+// @Success 200 {object} models.OIDCProvider
+// @Failure 404 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /oidc-config [get]
+func GetOIDCConfig(c *gin.Context) {
+	var p models.OIDCProvider
+	if err := config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")).First(&p).Error; err != nil {
+		apperrors.Abort(c, apperrors.NotFound("sso_not_configured", "Single sign-on isn't set up for this hospital"))
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// UpdateOIDCConfig godoc
+// @Summary Set up single sign-on with the hospital's identity provider (admin only)
+// @Description Register the redirect URL OIDC_REDIRECT_URL (by default http://localhost:8080/auth/oidc/callback) with the IdP. The issuer must be an https URL, and its discovery document is fetched to check the settings; it can't be on a private or local network unless INTERNAL_NETWORKS_ALLOWED lists it. Staff are matched by the IdP's subject, or on their first login by email; with auto_provision, unknown staff are created with the default profession group and title. The first value of the roles claim (groups by default) found in role_mapping sets the user's role, falling back to default_role; users with neither can't sign in
+// @Tags Single sign-on
+// @Accept json
+// @Produce json
+// @Param config body OIDCConfigRequest true "Identity provider settings"
+// @Success 200 {object} models.OIDCProvider
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /oidc-config [put]
+func UpdateOIDCConfig(c *gin.Context) {
+	var req OIDCConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+	if u, err := url.Parse(req.Issuer); err != nil || u.Scheme != "https" {
+		apperrors.Abort(c, apperrors.InvalidField("issuer", "https_url", "field_https_url"))
+		return
+	}
+	if err := sso.Check(req.Issuer); errors.Is(err, netguard.ErrForbidden) {
+		apperrors.Abort(c, apperrors.InvalidField("issuer", "internal_address", "field_internal_address"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.InvalidField("issuer", "oidc_discovery", "field_oidc_discovery"))
+		return
+	}
+
+	hospitalID := uint(c.GetInt("hospitalID"))
+	var p models.OIDCProvider
+	err := config.DB.Where("hospital_id = ?", hospitalID).First(&p).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		apperrors.Abort(c, apperrors.Internal(err, "sso_config_fetch_failed", "Failed to load single sign-on settings"))
+		return
+	}
+	before := p
+	if p.Issuer != "" && p.Issuer != req.Issuer {
+		sso.Forget(p.Issuer)
+	}
+
+	roleMapping := make(map[string]string, len(req.RoleMapping))
+	for group, role := range req.RoleMapping {
+		roleMapping[group] = strings.ToLower(strings.TrimSpace(role))
+	}
+	p.HospitalID = hospitalID
+	p.Enabled = req.Enabled
+	p.Issuer = req.Issuer
+	p.ClientID = req.ClientID
+	if req.ClientSecret != nil {
+		p.ClientSecret = *req.ClientSecret
+	}
+	p.Scopes = req.Scopes
+	p.Claims = req.Claims
+	p.RoleMapping = roleMapping
+	p.DefaultRole = strings.ToLower(strings.TrimSpace(req.DefaultRole))
+	p.AutoProvision = req.AutoProvision
+	p.DefaultProfessionGroupID = req.DefaultProfessionGroupID
+	p.DefaultTitleID = req.DefaultTitleID
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if p.AutoProvision {
+			if err := checkProvisioningDefaults(tx, p.DefaultProfessionGroupID, p.DefaultTitleID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&p).Error; err != nil {
+			return apperrors.FromDB(err, "sso_config_update_failed", "Failed to save single sign-on settings")
+		}
+		entry := audit.Entry{
+			Action:     "oidc_config.update",
+			EntityType: "oidc_provider",
+			EntityID:   p.ID,
+			After:      p,
+		}
+		if before.ID != 0 {
+			entry.Before = before
+		}
+		return audit.Record(tx, c, entry)
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// checkProvisioningDefaults makes sure the profession group and title given
+// to users provisioned from an IdP or directory exist and fit together.
+func checkProvisioningDefaults(tx *gorm.DB, professionGroupID, titleID uint) error {
+	var count int64
+	if err := tx.Model(&models.ProfessionGroup{}).Where("id = ?", professionGroupID).Count(&count).Error; err != nil {
+		return apperrors.Internal(err, "internal_error", "Internal server error")
+	}
+	if count == 0 {
+		return apperrors.InvalidField("default_profession_group_id", "reference", "field_reference")
+	}
+
+	var title models.Title
+	if err := tx.First(&title, titleID).Error; err != nil {
+		return apperrors.InvalidField("default_title_id", "reference", "field_reference")
+	}
+	if title.ProfessionGroupID != professionGroupID {
+		return apperrors.InvalidField("default_title_id", "profession_group", "field_title_group")
+	}
+	return nil
+}
+
+// DeleteOIDCConfig godoc
+// @Summary Turn single sign-on off and remove its settings (admin only)
+// @Description Users keep their links to the IdP, so setting the same issuer up again restores them. Users without a password have to reset it to log in
+// @Tags Single sign-on
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /oidc-config [delete]
+func DeleteOIDCConfig(c *gin.Context) {
+	var p models.OIDCProvider
+	if err := config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")).First(&p).Error; err != nil {
+		apperrors.Abort(c, apperrors.NotFound("sso_not_configured", "Single sign-on isn't set up for this hospital"))
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&p).Error; err != nil {
+			return apperrors.FromDB(err, "sso_config_delete_failed", "Failed to remove single sign-on settings")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "oidc_config.delete",
+			EntityType: "oidc_provider",
+			EntityID:   p.ID,
+			Before:     p,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	sso.Forget(p.Issuer)
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "sso_config_deleted")})
+}
+
+// enabledOIDCProvider loads the enabled provider of hospitalID.
+func enabledOIDCProvider(hospitalID uint) (models.OIDCProvider, error) {
+	var p models.OIDCProvider
+	err := config.DB.Where("hospital_id = ? AND enabled = ?", hospitalID, true).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return p, apperrors.NotFound("sso_not_configured", "Single sign-on isn't set up for this hospital")
+	}
+	if err != nil {
+		return p, apperrors.Internal(err, "sso_config_fetch_failed", "Failed to load single sign-on settings")
+	}
+	return p, nil
+}
+
+// OIDCLogin godoc
+// @Summary Sign in with the hospital's identity provider
+// @Description Redirects the browser to the IdP to start an authorization code login with PKCE. The IdP sends the user back to /auth/oidc/callback
+// @Tags Single sign-on
+// @Param hospitalID path int true "Hospital ID"
+// @Success 302 "Redirect to the identity provider"
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 429 {object} apperrors.Problem "Too many requests"
+// @Failure 500 {object} apperrors.Problem "The identity provider can't be reached"
+// @Router /auth/oidc/{hospitalID}/login [get]
+func OIDCLogin(c *gin.Context) {
+	hospitalID, err := strconv.Atoi(c.Param("hospitalID"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_hospital_id", "Invalid hospital ID"))
+		return
+	}
+	p, err := enabledOIDCProvider(uint(hospitalID))
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	authURL, err := sso.Begin(c.Request.Context(), p)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "sso_unavailable", "The identity provider can't be reached"))
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback godoc
+// @Summary Finish signing in with the hospital's identity provider
+// @Description The IdP redirects here. On success the response carries our JWT like /login does, or, if OIDC_SUCCESS_URL is set, redirects there with the token in the URL fragment (#token=...)
+// @Tags Single sign-on
+// @Produce json
+// @Param state query string true "Login state"
+// @Param code query string true "Authorization code"
+// @Success 200 {object} map[string]string "token"
+// @Success 302 "Redirect to OIDC_SUCCESS_URL"
+// @Failure 400 {object} apperrors.Problem "Unknown or expired login"
+// @Failure 401 {object} apperrors.Problem "The IdP login failed"
+// @Failure 403 {object} apperrors.Problem "No account or role for the user"
+// @Router /auth/oidc/callback [get]
+func OIDCCallback(c *gin.Context) {
+	if c.Query("error") != "" {
+		apperrors.Abort(c, apperrors.Unauthorized("sso_failed", "Signing in with the identity provider failed"))
+		return
+	}
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		apperrors.Abort(c, apperrors.BadRequest("sso_state_invalid", "The sign-in is unknown or expired; please start over"))
+		return
+	}
+
+	hospitalID, err := sso.HospitalOf(c.Request.Context(), state)
+	if errors.Is(err, sso.ErrUnknownState) {
+		apperrors.Abort(c, apperrors.BadRequest("sso_state_invalid", "The sign-in is unknown or expired; please start over"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "sso_failed", "Signing in with the identity provider failed"))
+		return
+	}
+	p, err := enabledOIDCProvider(hospitalID)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	identity, err := sso.Finish(c.Request.Context(), p, state, code)
+	switch {
+	case errors.Is(err, sso.ErrUnknownState):
+		apperrors.Abort(c, apperrors.BadRequest("sso_state_invalid", "The sign-in is unknown or expired; please start over"))
+		return
+	case errors.Is(err, sso.ErrNoEmail):
+		apperrors.Abort(c, apperrors.Forbidden("sso_email_missing", "The identity provider didn't share a verified email address"))
+		return
+	case err != nil:
+		log.Printf("⚠️ OIDC login for hospital %d failed: %v", hospitalID, err)
+		apperrors.Abort(c, apperrors.Unauthorized("sso_failed", "Signing in with the identity provider failed"))
+		return
+	}
+
+	role, err := sso.Role(p, identity)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Forbidden("sso_no_role", "Your identity provider account isn't mapped to a role here"))
+		return
+	}
+
+	user, err := externalUser(c, authn.Account{
+		Provider:          sso.ProviderOIDC,
+		Issuer:            identity.Issuer,
+		Subject:           identity.Subject,
+		Email:             identity.Email,
+		Name:              identity.Name,
+		Surname:           identity.Surname,
+		Phone:             identity.Phone,
+		Role:              role,
+		HospitalID:        p.HospitalID,
+		AutoProvision:     p.AutoProvision,
+		ProfessionGroupID: p.DefaultProfessionGroupID,
+		TitleID:           p.DefaultTitleID,
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	token, err := issueToken(c, user, "")
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	if successURL := config.GetEnv("OIDC_SUCCESS_URL", ""); successURL != "" {
+		// The fragment never reaches servers or their logs.
+		c.Redirect(http.StatusFound, successURL+"#token="+url.QueryEscape(token))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"token": token})
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/password"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PasswordPolicyRequest struct {
+	MinLength          int  `json:"min_length" binding:"required,min=8,max=72"`
+	RequireUpper       bool `json:"require_upper"`
+	RequireLower       bool `json:"require_lower"`
+	RequireDigit       bool `json:"require_digit"`
+	RequireSymbol      bool `json:"require_symbol"`
+	ForbidPersonalInfo bool `json:"forbid_personal_info"`
+	HistorySize        int  `json:"history_size" binding:"min=0,max=24"`
+	MaxAgeDays         int  `json:"max_age_days" binding:"min=0,max=3650"`
+	CheckBreached      bool `json:"check_breached"`
+}
+
+// GetPasswordPolicy godoc
+// @Summary Get the password policy of the admin's hospital (admin only)
+// @Description Hospitals that never saved a policy get the defaults
+// @Tags Password policy
+// @Produce json
+// @Success 200 {object} models.PasswordPolicy
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /password-policy [get]
+func GetPasswordPolicy(c *gin.Context) {
+	policy, err := password.PolicyFor(config.DB, uint(c.GetInt("hospitalID")))
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "password_policy_fetch_failed", "Failed to load the password policy"))
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePasswordPolicy godoc
+// @Summary Replace the password policy of the admin's hospital (admin only)
+// @Description Applies to passwords set from now on. Existing passwords older than max_age_days must be changed at the next login; 0 turns rotation off
+// @Tags Password policy
+// @Accept json
+// @Produce json
+// @Param policy body PasswordPolicyRequest true "Password policy"
+// @Success 200 {object} models.PasswordPolicy
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /password-policy [put]
+func UpdatePasswordPolicy(c *gin.Context) {
+	var req PasswordPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+
+	policy, err := password.PolicyFor(config.DB, uint(c.GetInt("hospitalID")))
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "password_policy_fetch_failed", "Failed to load the password policy"))
+		return
+	}
+	before := policy
+	policy.MinLength = req.MinLength
+	policy.RequireUpper = req.RequireUpper
+	policy.RequireLower = req.RequireLower
+	policy.RequireDigit = req.RequireDigit
+	policy.RequireSymbol = req.RequireSymbol
+	policy.ForbidPersonalInfo = req.ForbidPersonalInfo
+	policy.HistorySize = req.HistorySize
+	policy.MaxAgeDays = req.MaxAgeDays
+	policy.CheckBreached = req.CheckBreached
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&policy).Error; err != nil {
+			return apperrors.FromDB(err, "password_policy_update_failed", "Failed to update the password policy")
+		}
+		entry := audit.Entry{
+			Action:     "password_policy.update",
+			EntityType: "password_policy",
+			EntityID:   policy.ID,
+			After:      policy,
+		}
+		// A hospital on the defaults has no stored policy to diff against.
+		if before.ID != 0 {
+			entry.Before = before
+		}
+		return audit.Record(tx, c, entry)
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
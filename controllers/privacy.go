@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/privacy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// loadSubjectUser loads the user in the :id path parameter, including deleted
+// ones, and makes sure it belongs to the caller's hospital.
+func loadSubjectUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_user_id", "Invalid user ID"))
+		return user, false
+	}
+	err = config.DB.Unscoped().Preload("Hospital").Preload("ProfessionGroup").Preload("Title").
+		Where("hospital_id = ?", c.GetInt("hospitalID")).
+		First(&user, id).Error
+	if err != nil {
+		apperrors.Abort(c, apperrors.NotFound("user_not_found", "User not found"))
+		return user, false
+	}
+	return user, true
+}
+
+// loadSubjectDoctor loads the doctor in the :id path parameter, including
+// deleted ones, and makes sure it belongs to the caller's hospital.
+func loadSubjectDoctor(c *gin.Context) (models.Doctor, bool) {
+	var doctor models.Doctor
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_doctor_id", "Invalid doctor ID"))
+		return doctor, false
+	}
+	err = config.DB.Unscoped().Preload("Hospital").Preload("Department").
+		Where("hospital_id = ?", c.GetInt("hospitalID")).
+		First(&doctor, id).Error
+	if err != nil {
+		apperrors.Abort(c, apperrors.NotFound("doctor_not_found", "Doctor not found"))
+		return doctor, false
+	}
+	return doctor, true
+}
+
+// writeExport records the export in the audit log and sends the bundle as
+// JSON or, with format=zip, as a ZIP download.
+func writeExport(c *gin.Context, bundle privacy.Bundle) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_export_format", "format must be json or zip"))
+		return
+	}
+
+	err := audit.Record(config.DB, c, audit.Entry{
+		Action:     bundle.SubjectType + ".export",
+		EntityType: bundle.SubjectType,
+		EntityID:   bundle.SubjectID,
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "subject_export_failed", "Failed to export personal data"))
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%d-export", bundle.SubjectType, bundle.SubjectID)
+	if format == "json" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.JSON(http.StatusOK, bundle)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	c.Status(http.StatusOK)
+	if err := privacy.WriteZIP(c.Writer, bundle); err != nil {
+		// Headers are already sent, so the client sees a truncated archive.
+		_ = c.Error(err)
+	}
+}
+
+// ExportUserData godoc
+// @Summary Export all personal data held about a user (admin only)
+// @Description KVKK access request. Includes deleted users and the audit entries about or made by the user
+// @Tags Privacy
+// @Produce json
+// @Produce application/zip
+// @Param id path int true "User ID"
+// @Param format query string false "json (default) or zip"
+// @Success 200 {object} privacy.Bundle
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/{id}/export [get]
+func ExportUserData(c *gin.Context) {
+	user, ok := loadSubjectUser(c)
+	if !ok {
+		return
+	}
+
+	bundle, err := privacy.ExportUser(config.DB, user)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "subject_export_failed", "Failed to export personal data"))
+		return
+	}
+	writeExport(c, bundle)
+}
+
+// EraseUser godoc
+// @Summary Erase a user's personal data (admin only)
+// @Description KVKK erasure request. Anonymizes the user's personal fields and deletes the user, keeping the record so audit history stays intact
+// @Tags Privacy
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/{id}/erase [post]
+func EraseUser(c *gin.Context) {
+	user, ok := loadSubjectUser(c)
+	if !ok {
+		return
+	}
+	if user.AnonymizedAt != nil {
+		apperrors.Abort(c, apperrors.Conflict("subject_already_anonymized", "Personal data has already been erased"))
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := privacy.AnonymizeUser(tx, &user); err != nil {
+			return apperrors.Internal(err, "subject_erase_failed", "Failed to erase personal data")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "user.erase",
+			EntityType: "user",
+			EntityID:   user.ID,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "subject_erased")})
+}
+
+// ExportDoctorData godoc
+// @Summary Export all personal data held about a doctor (admin only)
+// @Description KVKK access request. Includes deleted doctors and the audit entries about the doctor
+// @Tags Privacy
+// @Produce json
+// @Produce application/zip
+// @Param id path int true "Doctor ID"
+// @Param format query string false "json (default) or zip"
+// @Success 200 {object} privacy.Bundle
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /doctors/{id}/export [get]
+func ExportDoctorData(c *gin.Context) {
+	doctor, ok := loadSubjectDoctor(c)
+	if !ok {
+		return
+	}
+
+	bundle, err := privacy.ExportDoctor(config.DB, doctor)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "subject_export_failed", "Failed to export personal data"))
+		return
+	}
+	writeExport(c, bundle)
+}
+
+// EraseDoctor godoc
+// @Summary Erase a doctor's personal data (admin only)
+// @Description KVKK erasure request. Anonymizes the doctor's personal fields and deletes the doctor, keeping the record for referential integrity
+// @Tags Privacy
+// @Produce json
+// @Param id path int true "Doctor ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /doctors/{id}/erase [post]
+func EraseDoctor(c *gin.Context) {
+	doctor, ok := loadSubjectDoctor(c)
+	if !ok {
+		return
+	}
+	if doctor.AnonymizedAt != nil {
+		apperrors.Abort(c, apperrors.Conflict("subject_already_anonymized", "Personal data has already been erased"))
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := privacy.AnonymizeDoctor(tx, &doctor); err != nil {
+			return apperrors.Internal(err, "subject_erase_failed", "Failed to erase personal data")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "doctor.erase",
+			EntityType: "doctor",
+			EntityID:   doctor.ID,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "subject_erased")})
+}
+
+// GetRetentionPolicies godoc
+// @Summary List data retention policies per entity type (admin only)
+// @Tags Privacy
+// @Produce json
+// @Success 200 {array} privacy.RetentionPolicy
+// @Security BearerAuth
+// @Router /retention-policies [get]
+func GetRetentionPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, privacy.Policies())
+}
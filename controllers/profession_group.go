@@ -3,10 +3,11 @@ package controllers
 import (
 	"context"
 	"encoding/json"
-	"net/http"
 	"time"
 
+	"github.com/efecan/vatansoft-case/apperrors"
 	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/listquery"
 	"github.com/efecan/vatansoft-case/models"
 	"github.com/gin-gonic/gin"
 )
@@ -22,30 +23,59 @@ type ProfessionGroupResponse struct {
 	Titles []TitleResponse `json:"titles"`
 }
 
+var professionGroupListSpec = listquery.Spec{
+	Model: &models.ProfessionGroup{},
+	Sortable: map[string]string{
+		"id":   "id",
+		"name": "name",
+	},
+	DefaultSort: "id",
+	Fields:      listquery.FieldsOf(ProfessionGroupResponse{}),
+}
+
+// cachedProfessionGroupPage is a page of profession groups as kept in Redis.
+type cachedProfessionGroupPage struct {
+	Data   []ProfessionGroupResponse `json:"data"`
+	Result listquery.Result          `json:"result"`
+}
+
 // GetProfessionGroups godoc
 // @Summary Get all profession groups with titles
-// @Description Returns all profession groups and their associated titles, using Redis cache
+// @Description Returns the profession groups and their associated titles, using Redis cache. Sortable by id and name
 // @Tags Profession Groups
 // @Produce json
-// @Success 200 {array} ProfessionGroupResponse
-// @Failure 500 {object} map[string]string
+// @Param sort query string false "Comma separated fields, prefix with - for descending"
+// @Param page_size query int false "Items per page (max 100)"
+// @Param page query int false "Page number, ignored when cursor is set"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated fields to return"
+// @Success 200 {object} listquery.Page{data=[]ProfessionGroupResponse}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
 // @Security BearerAuth
+// @Failure 429 {object} apperrors.Problem "Too many requests"
 // @Router /profession-groups [get]
 func GetProfessionGroups(c *gin.Context) {
-	const cacheKey = "profession_groups"
+	q, err := listquery.Parse(c, professionGroupListSpec)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	cacheKey := "profession_groups:" + q.Key()
 
 	cached, err := config.REDIS.Get(context.Background(), cacheKey).Result()
 	if err == nil {
-		var response []ProfessionGroupResponse
-		if json.Unmarshal([]byte(cached), &response) == nil {
-			c.JSON(http.StatusOK, response)
+		var page cachedProfessionGroupPage
+		if json.Unmarshal([]byte(cached), &page) == nil {
+			q.Respond(c, page.Data, page.Result)
 			return
 		}
 	}
 
 	var groups []models.ProfessionGroup
-	if err := config.DB.Preload("Titles").Find(&groups).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profession groups"})
+	result, err := q.Find(config.DB, &groups, "Titles")
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "profession_groups_fetch_failed", "Failed to retrieve profession groups"))
 		return
 	}
 
@@ -62,8 +92,8 @@ func GetProfessionGroups(c *gin.Context) {
 		})
 	}
 
-	data, _ := json.Marshal(response)
+	data, _ := json.Marshal(cachedProfessionGroupPage{Data: response, Result: result})
 	config.REDIS.Set(context.Background(), cacheKey, data, 24*time.Hour)
 
-	c.JSON(http.StatusOK, response)
+	q.Respond(c, response, result)
 }
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/notify"
+	"github.com/efecan/vatansoft-case/otp"
+	"github.com/efecan/vatansoft-case/registration"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RegistrationPhoneRequest struct {
+	Contact string `json:"contact" binding:"required,oneof=hospital_phone admin_phone"`
+	Code    string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type RegistrationResendRequest struct {
+	Contact string `json:"contact" binding:"required,oneof=hospital_email hospital_phone admin_email admin_phone"`
+}
+
+// callerRegistration loads the unverified registration of the admin calling.
+func callerRegistration(c *gin.Context) (registration.Registration, error) {
+	reg, err := registration.Load(config.DB, uint(c.GetInt("hospitalID")))
+	if errors.Is(err, registration.ErrNotFound) || (err == nil && reg.Admin.ID != uint(c.GetInt("userID"))) {
+		return reg, apperrors.NotFound("registration_not_found", "No unverified registration was found")
+	}
+	if err != nil {
+		return reg, apperrors.Internal(err, "registration_fetch_failed", "Failed to load the registration")
+	}
+	return reg, nil
+}
+
+// saveRegistration stores what reg confirmed and audits it. wasPending lists
+// the contacts pending before, so the entry names the contacts confirmed
+// rather than their addresses.
+func saveRegistration(c *gin.Context, reg *registration.Registration, wasPending []string) (bool, error) {
+	var confirmed []string
+	for _, contact := range wasPending {
+		if !reg.IsPending(contact) {
+			confirmed = append(confirmed, contact)
+		}
+	}
+	var complete bool
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		complete, err = reg.Save(tx)
+		if err != nil {
+			return apperrors.FromDB(err, "registration_update_failed", "Failed to save the verification")
+		}
+		action := "hospital.contact_verify"
+		if complete {
+			action = "hospital.registration_complete"
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     action,
+			EntityType: "hospital",
+			EntityID:   reg.Hospital.ID,
+			After:      gin.H{"confirmed": confirmed, "pending": reg.Pending()},
+			ActorID:    reg.Admin.ID,
+			HospitalID: reg.Hospital.ID,
+		})
+	})
+	return complete, err
+}
+
+func registrationConfirmed(c *gin.Context, reg registration.Registration, complete bool) {
+	message := "contact_verified"
+	if complete {
+		message = "registration_completed"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":              i18n.T(c, message),
+		"status":               reg.Hospital.Status,
+		"pending_verification": reg.Pending(),
+	})
+}
+
+// GetRegistration godoc
+// @Summary Get the verification status of the caller's hospital registration
+// @Description Lists the contacts still to be confirmed: hospital_email, hospital_phone, admin_email and admin_phone. Verified hospitals report status active and nothing pending
+// @Tags Registration
+// @Produce json
+// @Success 200 {object} map[string]interface{} "status and pending_verification"
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /registration [get]
+func GetRegistration(c *gin.Context) {
+	reg, err := registration.Load(config.DB, uint(c.GetInt("hospitalID")))
+	if errors.Is(err, registration.ErrNotFound) {
+		c.JSON(http.StatusOK, gin.H{"status": models.HospitalStatusActive, "pending_verification": []string{}})
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "registration_fetch_failed", "Failed to load the registration"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": reg.Hospital.Status, "pending_verification": reg.Pending()})
+}
+
+// VerifyRegistrationEmail godoc
+// @Summary Confirm an email address of a hospital registration
+// @Description Opened from the link mailed at registration. Confirms every pending contact with that address; once the phones are confirmed too, the hospital and its admin become active
+// @Tags Registration
+// @Produce json
+// @Param token query string true "Token from the verification link"
+// @Success 200 {object} map[string]interface{} "message, status and pending_verification"
+// @Failure 400 {object} apperrors.Problem "Invalid or expired link"
+// @Failure 404 {object} apperrors.Problem "Registration already verified"
+// @Failure 500 {object} apperrors.Problem
+// @Router /registration/verify-email [get]
+func VerifyRegistrationEmail(c *gin.Context) {
+	hospitalID, email, err := registration.ParseLink(c.Query("token"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_link", "Invalid or expired verification link"))
+		return
+	}
+
+	reg, err := registration.Load(config.DB, hospitalID)
+	if errors.Is(err, registration.ErrNotFound) {
+		apperrors.Abort(c, apperrors.NotFound("registration_not_found", "No unverified registration was found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "registration_fetch_failed", "Failed to load the registration"))
+		return
+	}
+	// The address may have been confirmed already, or changed since.
+	wasPending := reg.Pending()
+	if !reg.MarkVerified(notify.ChannelEmail, email, time.Now().UTC()) {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_link", "Invalid or expired verification link"))
+		return
+	}
+
+	complete, err := saveRegistration(c, &reg, wasPending)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	registrationConfirmed(c, reg, complete)
+}
+
+// VerifyRegistrationPhone godoc
+// @Summary Confirm a phone number of the caller's hospital registration
+// @Description Checks the SMS code sent to the hospital's or the admin's phone. A code is void after five wrong attempts; request a new one with POST /registration/resend
+// @Tags Registration
+// @Accept json
+// @Produce json
+// @Param request body RegistrationPhoneRequest true "Phone and the code sent to it"
+// @Success 200 {object} map[string]interface{} "message, status and pending_verification"
+// @Failure 400 {object} apperrors.Problem "Invalid or expired code"
+// @Failure 404 {object} apperrors.Problem "Registration already verified"
+// @Failure 409 {object} apperrors.Problem "Phone already confirmed"
+// @Failure 429 {object} apperrors.Problem "Too many wrong codes"
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /registration/verify-phone [post]
+func VerifyRegistrationPhone(c *gin.Context) {
+	var req RegistrationPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+
+	reg, err := callerRegistration(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	wasPending := reg.Pending()
+	err = reg.VerifyCode(c.Request.Context(), req.Contact, req.Code)
+	switch {
+	case errors.Is(err, registration.ErrNotPending):
+		apperrors.Abort(c, apperrors.Conflict("contact_already_verified", "This contact is already confirmed"))
+		return
+	case errors.Is(err, otp.ErrInvalid), errors.Is(err, otp.ErrExpired):
+		apperrors.Abort(c, apperrors.BadRequest("invalid_code", "Invalid or expired code"))
+		return
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		apperrors.Abort(c, apperrors.TooManyRequests("too_many_attempts", "Too many wrong codes; request a new one"))
+		return
+	case err != nil:
+		apperrors.Abort(c, apperrors.Internal(err, "contact_verify_failed", "Failed to verify code"))
+		return
+	}
+
+	complete, err := saveRegistration(c, &reg, wasPending)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	registrationConfirmed(c, reg, complete)
+}
+
+// ResendRegistrationVerification godoc
+// @Summary Send a contact's verification link or code again
+// @Description Emails get a new link and phones a new code, which replaces the one sent before
+// @Tags Registration
+// @Accept json
+// @Produce json
+// @Param request body RegistrationResendRequest true "Contact to confirm"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apperrors.Problem "Registration already verified"
+// @Failure 409 {object} apperrors.Problem "Contact already confirmed"
+// @Failure 429 {object} apperrors.Problem "Too many requests"
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /registration/resend [post]
+func ResendRegistrationVerification(c *gin.Context) {
+	var req RegistrationResendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+
+	reg, err := callerRegistration(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	if !reg.IsPending(req.Contact) {
+		apperrors.Abort(c, apperrors.Conflict("contact_already_verified", "This contact is already confirmed"))
+		return
+	}
+	if err := reg.Send(c.Request.Context(), req.Contact); err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "verification_send_failed", "Failed to send the verification"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "verification_sent")})
+}
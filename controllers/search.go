@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/masking"
+	"github.com/efecan/vatansoft-case/search"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	searchDefaultLimit = 20
+	searchMaxLimit     = 50
+)
+
+// SearchResult is a ranked search hit. Highlights holds the matched fields
+// with matching words wrapped in <mark> tags and everything else HTML-escaped.
+type SearchResult struct {
+	Type       string            `json:"type"`
+	ID         uint              `json:"id"`
+	Name       string            `json:"name"`
+	Surname    string            `json:"surname,omitempty"`
+	Email      string            `json:"email"`
+	Title      string            `json:"title,omitempty"`
+	Department string            `json:"department,omitempty"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+func searchThreshold() float64 {
+	threshold, err := strconv.ParseFloat(config.GetEnv("SEARCH_SIMILARITY_THRESHOLD", "0.4"), 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		return 0.4
+	}
+	return threshold
+}
+
+// Search godoc
+// @Summary Search staff and doctors of the caller's hospital
+// @Description Matches every word of q against name, surname, email, title and department, ignoring case and Turkish diacritics (ı/i, ş/s, ğ/g, ü/u, ö/o, ç/c) and tolerating typos. Results are ranked best match first. Emails are masked unless the caller may see them
+// @Tags Search
+// @Produce json
+// @Param q query string true "Search text"
+// @Param type query string false "Comma separated record types to search: user, doctor (default both)"
+// @Param limit query int false "Maximum number of results (default 20, max 50)"
+// @Success 200 {object} map[string][]SearchResult
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /search [get]
+func Search(c *gin.Context) {
+	terms := search.Terms(c.Query("q"))
+	if len(terms) == 0 {
+		apperrors.Abort(c, apperrors.BadRequest("search_query_required", "q must contain at least one letter or digit"))
+		return
+	}
+
+	types := search.Types
+	if value := c.Query("type"); value != "" {
+		types = nil
+		for _, typ := range strings.Split(value, ",") {
+			typ = strings.TrimSpace(typ)
+			if !slices.Contains(search.Types, typ) {
+				apperrors.Abort(c, apperrors.BadRequest("invalid_search_type", "type must be user, doctor or both"))
+				return
+			}
+			if !slices.Contains(types, typ) {
+				types = append(types, typ)
+			}
+		}
+	}
+
+	limit := searchDefaultLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			apperrors.Abort(c, apperrors.InvalidField("limit", "number", "field_number"))
+			return
+		}
+		limit = min(n, searchMaxLimit)
+	}
+
+	hits, err := search.Run(config.DB, terms, search.Options{
+		HospitalID: uint(c.GetInt("hospitalID")),
+		Types:      types,
+		Limit:      limit,
+		Threshold:  searchThreshold(),
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "search_failed", "Search failed"))
+		return
+	}
+
+	policy := masking.For(c)
+	results := make([]SearchResult, 0, len(hits))
+	for _, h := range hits {
+		owner := masking.Owner{HospitalID: uint(c.GetInt("hospitalID"))}
+		if h.Type == search.TypeUser {
+			owner.UserID = h.ID
+		}
+		result := SearchResult{
+			Type:       h.Type,
+			ID:         h.ID,
+			Name:       h.Name,
+			Surname:    h.Surname,
+			Email:      policy.Email(owner, h.Email),
+			Title:      h.Title,
+			Department: h.Department,
+			Score:      h.Score,
+			Highlights: map[string]string{},
+		}
+		for field, value := range map[string]string{
+			"name":       result.Name,
+			"surname":    result.Surname,
+			"email":      result.Email,
+			"title":      result.Title,
+			"department": result.Department,
+		} {
+			if marked := search.Highlight(value, terms); marked != "" {
+				result.Highlights[field] = marked
+			}
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": results})
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/session"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// ImpersonatorID is set on sessions a platform admin opened as the user.
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
+	// Current marks the session the request was made with.
+	Current bool `json:"current"`
+}
+
+// listSessions responds with the active sessions of userID, most recently
+// used first.
+func listSessions(c *gin.Context, userID uint) {
+	var sessions []models.Session
+	err := config.DB.Scopes(session.Active).Where("user_id = ?", userID).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "sessions_fetch_failed", "Failed to fetch sessions"))
+		return
+	}
+
+	current := uint(c.GetInt("sessionID"))
+	response := make([]SessionResponse, len(sessions))
+	for i, s := range sessions {
+		response[i] = SessionResponse{
+			ID:             s.ID,
+			Device:         s.Device,
+			UserAgent:      s.UserAgent,
+			IP:             s.IP,
+			CreatedAt:      s.CreatedAt,
+			LastSeenAt:     s.LastSeenAt,
+			ExpiresAt:      s.ExpiresAt,
+			Current:        s.ID == current,
+			ImpersonatorID: s.ImpersonatorID,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// revokeSessions ends the sessions matched by query and records an audit entry
+// for each. It reports how many were revoked.
+func revokeSessions(c *gin.Context, query func(tx *gorm.DB) *gorm.DB) (int, error) {
+	var revoked []models.Session
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revoked, err = session.Revoke(query(tx), uint(c.GetInt("userID")))
+		if err != nil {
+			return apperrors.Internal(err, "session_revoke_failed", "Failed to revoke session")
+		}
+		for _, s := range revoked {
+			err := audit.Record(tx, c, audit.Entry{
+				Action:     "session.revoke",
+				EntityType: "session",
+				EntityID:   s.ID,
+				Before:     s,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(revoked), err
+}
+
+// revokeOneSession revokes the session named by the path parameter param if
+// it is an active session of userID.
+func revokeOneSession(c *gin.Context, param string, userID uint) {
+	id, err := strconv.Atoi(c.Param(param))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_session_id", "Invalid session ID"))
+		return
+	}
+	n, err := revokeSessions(c, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("id = ? AND user_id = ?", id, userID)
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	if n == 0 {
+		apperrors.Abort(c, apperrors.NotFound("session_not_found", "Session not found"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "session_revoked_ok")})
+}
+
+// GetMySessions godoc
+// @Summary List where the logged in user is logged in
+// @Description Active sessions with device, user agent, IP and last use, most recent first. current marks the session of this request
+// @Tags Sessions
+// @Produce json
+// @Success 200 {array} SessionResponse
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /me/sessions [get]
+func GetMySessions(c *gin.Context) {
+	listSessions(c, uint(c.GetInt("userID")))
+}
+
+// RevokeMySession godoc
+// @Summary Log out one of the logged in user's sessions
+// @Description Its token stops working immediately. Revoking the current session logs this client out
+// @Tags Sessions
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /me/sessions/{id} [delete]
+func RevokeMySession(c *gin.Context) {
+	revokeOneSession(c, "id", uint(c.GetInt("userID")))
+}
+
+// RevokeMyOtherSessions godoc
+// @Summary Log out everywhere else
+// @Description Revokes every session of the logged in user except the current one
+// @Tags Sessions
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /me/sessions [delete]
+func RevokeMyOtherSessions(c *gin.Context) {
+	userID, current := c.GetInt("userID"), c.GetInt("sessionID")
+	n, err := revokeSessions(c, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("user_id = ? AND id <> ?", userID, current)
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "sessions_revoked"), "revoked": n})
+}
+
+// loadHospitalUser loads the user named in the path, scoped to the admin's
+// hospital.
+func loadHospitalUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_user_id", "Invalid user ID"))
+		return user, false
+	}
+	if err := config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")).First(&user, id).Error; err != nil {
+		apperrors.Abort(c, apperrors.NotFound("user_not_found", "User not found"))
+		return user, false
+	}
+	return user, true
+}
+
+// GetUserSessions godoc
+// @Summary List a user's active sessions (admin only)
+// @Tags Sessions
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {array} SessionResponse
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/{id}/sessions [get]
+func GetUserSessions(c *gin.Context) {
+	user, ok := loadHospitalUser(c)
+	if !ok {
+		return
+	}
+	listSessions(c, user.ID)
+}
+
+// RevokeUserSessions godoc
+// @Summary Log a user out everywhere (admin only)
+// @Description Revokes every active session of the user, e.g. after a lost device or a suspected compromise
+// @Tags Sessions
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/{id}/sessions [delete]
+func RevokeUserSessions(c *gin.Context) {
+	user, ok := loadHospitalUser(c)
+	if !ok {
+		return
+	}
+	n, err := revokeSessions(c, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("user_id = ?", user.ID)
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "sessions_revoked"), "revoked": n})
+}
+
+// RevokeUserSession godoc
+// @Summary Revoke one session of a user (admin only)
+// @Tags Sessions
+// @Produce json
+// @Param id path int true "User ID"
+// @Param sessionID path int true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/{id}/sessions/{sessionID} [delete]
+func RevokeUserSession(c *gin.Context) {
+	user, ok := loadHospitalUser(c)
+	if !ok {
+		return
+	}
+	revokeOneSession(c, "sessionID", user.ID)
+}
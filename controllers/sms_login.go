@@ -0,0 +1,298 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/authn"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/notify"
+	"github.com/efecan/vatansoft-case/otp"
+	"github.com/efecan/vatansoft-case/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SMSLoginConfigRequest struct {
+	Enabled     bool `json:"enabled"`
+	AllowAdmins bool `json:"allow_admins"`
+}
+
+type SMSLoginCodeRequest struct {
+	Phone string `json:"phone" binding:"required,tr_phone"`
+}
+
+type SMSLoginRequest struct {
+	Phone string `json:"phone" binding:"required,tr_phone"`
+	Code  string `json:"code" binding:"required,len=6,numeric"`
+	// Device names the session, e.g. "Ward 3 tablet"; the user agent is used otherwise.
+	Device string `json:"device" binding:"max=100"`
+}
+
+// smsLoginCodeTTL is how long a login code stays valid.
+func smsLoginCodeTTL() time.Duration {
+	ttl, err := time.ParseDuration(config.GetEnv("SMS_LOGIN_CODE_TTL", "5m"))
+	if err != nil || ttl <= 0 {
+		return 5 * time.Minute
+	}
+	return ttl
+}
+
+// smsLoginCooldown is how long a phone waits before it can be sent another
+// login code.
+func smsLoginCooldown() time.Duration {
+	cooldown, err := time.ParseDuration(config.GetEnv("SMS_LOGIN_COOLDOWN", "60s"))
+	if err != nil || cooldown <= 0 {
+		return time.Minute
+	}
+	return cooldown
+}
+
+func smsLoginKey(phone string) string {
+	return "sms_login:" + phone
+}
+
+func smsLoginCooldownKey(phone string) string {
+	return "sms_login_cooldown:" + phone
+}
+
+// smsLoginUser returns the active user registered with phone if their
+// hospital lets them log in with a code. Platform admins and users whose
+// hospital directory owns their account never can.
+func smsLoginUser(ctx context.Context, phone string) (models.User, bool, error) {
+	db := config.DB.WithContext(ctx)
+	var user models.User
+	err := db.Where("status = ? AND platform_admin = ?", models.UserStatusActive, false).
+		Scopes(models.UserWithPhone(phone)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, false, nil
+	}
+	if err != nil {
+		return user, false, err
+	}
+
+	var setting models.SMSLoginSetting
+	err = db.Where("hospital_id = ? AND enabled = ?", user.HospitalID, true).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, false, nil
+	}
+	if err != nil {
+		return user, false, err
+	}
+	if user.Role == "admin" && !setting.AllowAdmins {
+		return user, false, nil
+	}
+
+	var links int64
+	err = db.Model(&models.ExternalIdentity{}).Where("user_id = ? AND provider = ?", user.ID, authn.ProviderLDAP).Count(&links).Error
+	if err != nil {
+		return user, false, err
+	}
+	return user, links == 0, nil
+}
+
+// GetSMSLoginConfig godoc
+// @Summary Get the SMS login settings of the admin's hospital (admin only)
+// @Description Hospitals that never saved settings don't allow logging in with SMS codes
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} models.SMSLoginSetting
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /sms-login-config [get]
+func GetSMSLoginConfig(c *gin.Context) {
+	hospitalID := uint(c.GetInt("hospitalID"))
+	setting := models.SMSLoginSetting{HospitalID: hospitalID}
+	err := config.DB.Where("hospital_id = ?", hospitalID).First(&setting).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		apperrors.Abort(c, apperrors.Internal(err, "sms_login_config_fetch_failed", "Failed to load SMS login settings"))
+		return
+	}
+	c.JSON(http.StatusOK, setting)
+}
+
+// UpdateSMSLoginConfig godoc
+// @Summary Let staff log in with a one-time code sent to their phone (admin only)
+// @Description When enabled, staff can log in through POST /auth/sms/request and POST /auth/sms/verify instead of their password. Admins can only with allow_admins; platform admins and staff whose account belongs to the hospital's LDAP directory never can
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param config body SMSLoginConfigRequest true "SMS login settings"
+// @Success 200 {object} models.SMSLoginSetting
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /sms-login-config [put]
+func UpdateSMSLoginConfig(c *gin.Context) {
+	var req SMSLoginConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+
+	hospitalID := uint(c.GetInt("hospitalID"))
+	var setting models.SMSLoginSetting
+	err := config.DB.Where("hospital_id = ?", hospitalID).First(&setting).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		apperrors.Abort(c, apperrors.Internal(err, "sms_login_config_fetch_failed", "Failed to load SMS login settings"))
+		return
+	}
+	before := setting
+	setting.HospitalID = hospitalID
+	setting.Enabled = req.Enabled
+	setting.AllowAdmins = req.AllowAdmins
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&setting).Error; err != nil {
+			return apperrors.FromDB(err, "sms_login_config_update_failed", "Failed to save SMS login settings")
+		}
+		entry := audit.Entry{
+			Action:     "sms_login_config.update",
+			EntityType: "sms_login_setting",
+			EntityID:   setting.ID,
+			After:      setting,
+		}
+		if before.ID != 0 {
+			entry.Before = before
+		}
+		return audit.Record(tx, c, entry)
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setting)
+}
+
+// RequestSMSLoginCode godoc
+// @Summary Request a one-time login code by SMS
+// @Description Sends a six digit code to the phone if it belongs to staff whose hospital allows SMS login. The response is the same either way, so it doesn't tell which phones are registered. A phone is sent at most one code per SMS_LOGIN_COOLDOWN
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body SMSLoginCodeRequest true "Registered phone number"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 429 {object} apperrors.Problem "A code was sent to this phone moments ago"
+// @Failure 500 {object} apperrors.Problem
+// @Router /auth/sms/request [post]
+func RequestSMSLoginCode(c *gin.Context) {
+	var req SMSLoginCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+	req.Phone = utils.NormalizePhoneOrKeep(req.Phone)
+	ctx := c.Request.Context()
+
+	// The cooldown applies to unknown phones as well, so it doesn't tell them
+	// apart either.
+	cooldown := smsLoginCooldown()
+	started, err := config.REDIS.SetNX(ctx, smsLoginCooldownKey(req.Phone), 1, cooldown).Result()
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "sms_login_code_failed", "Failed to send login code"))
+		return
+	}
+	if !started {
+		if wait, err := config.REDIS.TTL(ctx, smsLoginCooldownKey(req.Phone)).Result(); err == nil && wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Round(time.Second).Seconds())))
+		}
+		apperrors.Abort(c, apperrors.TooManyRequests("sms_login_cooldown", "A code was just sent to this phone; wait before requesting another"))
+		return
+	}
+
+	user, ok, err := smsLoginUser(ctx, req.Phone)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "sms_login_code_failed", "Failed to send login code"))
+		return
+	}
+	if ok {
+		ttl := smsLoginCodeTTL()
+		code, err := otp.Issue(ctx, smsLoginKey(req.Phone), strconv.FormatUint(uint64(user.ID), 10), ttl)
+		if err != nil {
+			apperrors.Abort(c, apperrors.Internal(err, "sms_login_code_failed", "Failed to send login code"))
+			return
+		}
+		lang := user.Language
+		if !i18n.IsSupported(lang) {
+			lang = i18n.Default()
+		}
+		err = notify.Send(ctx, notify.Message{
+			Channel: notify.ChannelSMS,
+			To:      req.Phone,
+			Body: i18n.Message(lang, "sms_login_code_body", map[string]string{
+				"name":    user.Name,
+				"code":    code,
+				"minutes": strconv.Itoa(int(ttl.Minutes())),
+			}),
+		})
+		if err != nil {
+			apperrors.Abort(c, apperrors.Internal(err, "sms_login_code_failed", "Failed to send login code"))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "sms_login_code_sent")})
+}
+
+// SMSLogin godoc
+// @Summary Log in with a one-time SMS code
+// @Description Exchanges the code sent by POST /auth/sms/request for a JWT. A code is void after five wrong attempts; request a new one
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body SMSLoginRequest true "Phone, code and optional device name"
+// @Success 200 {object} map[string]string "token"
+// @Failure 400 {object} apperrors.Problem "Invalid or expired code"
+// @Failure 429 {object} apperrors.Problem "Too many wrong codes"
+// @Failure 500 {object} apperrors.Problem
+// @Router /auth/sms/verify [post]
+func SMSLogin(c *gin.Context) {
+	var req SMSLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.Validation(err))
+		return
+	}
+	req.Phone = utils.NormalizePhoneOrKeep(req.Phone)
+	ctx := c.Request.Context()
+
+	value, err := otp.Verify(ctx, smsLoginKey(req.Phone), req.Code)
+	switch {
+	case errors.Is(err, otp.ErrInvalid), errors.Is(err, otp.ErrExpired):
+		apperrors.Abort(c, apperrors.BadRequest("invalid_code", "Invalid or expired code"))
+		return
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		apperrors.Abort(c, apperrors.TooManyRequests("too_many_attempts", "Too many wrong codes; request a new one"))
+		return
+	case err != nil:
+		apperrors.Abort(c, apperrors.Internal(err, "login_failed", "Failed to log in"))
+		return
+	}
+
+	// The user may have been disabled, changed phones or lost SMS login
+	// since the code was sent.
+	user, ok, err := smsLoginUser(ctx, req.Phone)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "login_failed", "Failed to log in"))
+		return
+	}
+	if !ok || strconv.FormatUint(uint64(user.ID), 10) != value {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_code", "Invalid or expired code"))
+		return
+	}
+
+	tokenString, err := issueToken(c, user, req.Device)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"token": tokenString})
+}
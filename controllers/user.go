@@ -1,65 +1,150 @@
 package controllers
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
 	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/invite"
+	"github.com/efecan/vatansoft-case/listquery"
+	"github.com/efecan/vatansoft-case/masking"
 	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/notify"
+	"github.com/efecan/vatansoft-case/permissions"
+	"github.com/efecan/vatansoft-case/search"
+	"github.com/efecan/vatansoft-case/session"
+	"github.com/efecan/vatansoft-case/utils"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
 )
 
 type NewUserRequest struct {
 	Name              string `json:"name" binding:"required"`
 	Surname           string `json:"surname" binding:"required"`
-	TCKN              string `json:"tckn" binding:"required"`
+	TCKN              string `json:"tckn" binding:"required,tckn"`
 	Email             string `json:"email" binding:"required,email"`
-	Phone             string `json:"phone" binding:"required"`
-	Password          string `json:"password" binding:"required"`
-	Role              string `json:"role" binding:"required"`
+	Phone             string `json:"phone" binding:"required,tr_phone"`
+	Role              string `json:"role" binding:"required,oneof=admin user"`
+	Language          string `json:"language" binding:"omitempty,oneof=tr en"`
 	HospitalID        uint   `json:"hospital_id" binding:"required"`
 	ProfessionGroupID uint   `json:"profession_group_id" binding:"required"`
 	TitleID           uint   `json:"title_id" binding:"required"`
+	// InviteChannel is how the invitation is delivered, email by default.
+	InviteChannel string `json:"invite_channel" binding:"omitempty,oneof=email sms"`
 }
 
 type UserResponse struct {
-	ID                uint   `json:"id"`
-	Name              string `json:"name"`
-	Surname           string `json:"surname"`
-	Email             string `json:"email"`
-	Phone             string `json:"phone"`
-	TCKN              string `json:"tckn"`
-	Role              string `json:"role"`
-	HospitalID        uint   `json:"hospital_id"`
-	ProfessionGroupID uint   `json:"profession_group_id"`
-	ProfessionGroup   string `json:"profession_group"`
-	TitleID           uint   `json:"title_id"`
-	Title             string `json:"title"`
+	ID                 uint   `json:"id"`
+	Name               string `json:"name"`
+	Surname            string `json:"surname"`
+	Email              string `json:"email"`
+	Phone              string `json:"phone"`
+	TCKN               string `json:"tckn"`
+	Role               string `json:"role"`
+	Status             string `json:"status"`
+	MustChangePassword bool   `json:"must_change_password"`
+	Language           string `json:"language"`
+	HospitalID         uint   `json:"hospital_id"`
+	ProfessionGroupID  uint   `json:"profession_group_id"`
+	ProfessionGroup    string `json:"profession_group"`
+	TitleID            uint   `json:"title_id"`
+	Title              string `json:"title"`
+}
+
+// mayManageHospital aborts with 403 and returns false when hospitalID isn't
+// the caller's own hospital. Only platform admins, signed in as themselves,
+// may place users in another one; API keys never reach beyond their own.
+func mayManageHospital(c *gin.Context, hospitalID uint) bool {
+	if hospitalID == uint(c.GetInt("hospitalID")) {
+		return true
+	}
+	if c.GetInt("apiKeyID") != 0 {
+		apperrors.Abort(c, apperrors.Forbidden("api_key_hospital_scope", "API keys can only manage users of their own hospital"))
+		return false
+	}
+	if !c.GetBool("platformAdmin") || c.GetInt("impersonatorID") != 0 {
+		apperrors.Abort(c, apperrors.Forbidden("hospital_scope", "You can only manage users of your own hospital"))
+		return false
+	}
+	return true
+}
+
+// mayManageAdmins aborts with 403 and returns false when one of roles is
+// admin and the caller may not manage admins, like API keys without the
+// users:admin scope.
+func mayManageAdmins(c *gin.Context, roles ...string) bool {
+	for _, role := range roles {
+		if role == models.RoleAdmin && !permissions.Granted(c, permissions.ManageAdmins) {
+			apperrors.Abort(c, apperrors.Forbidden("admin_management_forbidden", "Managing admins requires the users:admin scope"))
+			return false
+		}
+	}
+	return true
+}
+
+// newUserResponse shapes u for the caller, masking personal fields the
+// caller's policy doesn't reveal.
+func newUserResponse(policy masking.Policy, u models.User) UserResponse {
+	owner := masking.Owner{UserID: u.ID, HospitalID: u.HospitalID}
+	resp := UserResponse{
+		ID:                 u.ID,
+		Name:               u.Name,
+		Surname:            u.Surname,
+		Email:              policy.Email(owner, u.Email),
+		Phone:              policy.Phone(owner, u.Phone),
+		TCKN:               policy.TCKN(owner, u.TCKN),
+		Role:               u.Role,
+		Status:             u.Status,
+		MustChangePassword: u.MustChangePassword,
+		Language:           u.Language,
+		HospitalID:         u.HospitalID,
+		ProfessionGroupID:  u.ProfessionGroupID,
+		TitleID:            u.TitleID,
+	}
+	if u.ProfessionGroup != nil {
+		resp.ProfessionGroup = u.ProfessionGroup.Name
+	}
+	if u.Title != nil {
+		resp.Title = u.Title.Name
+	}
+	return resp
 }
 
 // CreateUser godoc
-// @Summary Create a new user (admin only)
+// @Summary Invite a new user (admin only)
+// @Description Creates a pending user and sends them an invitation by email or SMS. The invitee sets their own password and accepts the terms through POST /invitations/accept; pending users can't log in until then
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param user body NewUserRequest true "New user data"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param Idempotency-Key header string false "Makes retries of this request safe"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem
+// @Failure 422 {object} apperrors.Problem "Idempotency-Key reused with a different payload"
+// @Failure 500 {object} apperrors.Problem
 // @Security BearerAuth
 // @Router /users [post]
 func CreateUser(c *gin.Context) {
 	var req NewUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		apperrors.Abort(c, apperrors.Validation(err))
 		return
 	}
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+	if req.InviteChannel == "" {
+		req.InviteChannel = notify.ChannelEmail
+	}
+	if !mayManageHospital(c, req.HospitalID) || !mayManageAdmins(c, req.Role) {
 		return
 	}
 
@@ -68,182 +153,460 @@ func CreateUser(c *gin.Context) {
 		Surname:           req.Surname,
 		TCKN:              req.TCKN,
 		Email:             req.Email,
-		Phone:             req.Phone,
-		Password:          string(hashedPassword),
-		Role:              strings.ToLower(req.Role),
+		Phone:             utils.NormalizePhoneOrKeep(req.Phone),
+		Role:              req.Role,
+		Status:            models.UserStatusPending,
+		Language:          req.Language,
 		HospitalID:        req.HospitalID,
 		ProfessionGroupID: req.ProfessionGroupID,
 		TitleID:           req.TitleID,
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	var inv models.Invitation
+	var token string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return apperrors.FromDB(err, "user_create_failed", "Failed to create user")
+		}
+		var err error
+		inv, token, err = invite.Create(tx, user, req.InviteChannel, uint(c.GetInt("userID")))
+		if err != nil {
+			return apperrors.Internal(err, "invitation_create_failed", "Failed to create invitation")
+		}
+		if err := tx.First(&user.Hospital, user.HospitalID).Error; err != nil {
+			return apperrors.FromDB(err, "user_create_failed", "Failed to create user")
+		}
+		if err := audit.Record(tx, c, audit.Entry{
+			Action:     "user.create",
+			EntityType: "user",
+			EntityID:   user.ID,
+			After:      user,
+		}); err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "invitation.create",
+			EntityType: "invitation",
+			EntityID:   inv.ID,
+			After:      inv,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User created successfully"})
+	// The user exists either way; a failed delivery can be retried with resend.
+	sent := true
+	if err := invite.Send(c, user, inv, token); err != nil {
+		log.Printf("⚠️ Could not deliver invitation %d: %v", inv.ID, err)
+		sent = false
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         i18n.T(c, "user_invited"),
+		"id":              user.ID,
+		"invitation_sent": sent,
+	})
 }
 
 // GetUsers godoc
 // @Summary Get all users in the current user's hospital
+// @Description TCKN, phone and email are masked unless the caller may see them. Sortable by id, name, surname, email, role, created_at and updated_at
 // @Tags Users
 // @Produce json
-// @Success 200 {array} models.User
-// @Failure 500 {object} map[string]string
+// @Param sort query string false "Comma separated fields, prefix with - for descending"
+// @Param page_size query int false "Items per page (max 100)"
+// @Param page query int false "Page number, ignored when cursor is set"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated fields to return"
+// @Success 200 {object} listquery.Page{data=[]UserResponse}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
 // @Security BearerAuth
 // @Router /users [get]
 func GetUsers(c *gin.Context) {
-	hospitalID := c.GetInt("hospitalID")
+	respondUsers(c, config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")))
+}
+
+// userListSpec is shared by the user collection endpoints.
+var userListSpec = listquery.Spec{
+	Model: &models.User{},
+	Sortable: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"surname":    "surname",
+		"email":      "email",
+		"role":       "role",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	DefaultSort:     "id",
+	DefaultPageSize: 10,
+	Fields:          listquery.FieldsOf(UserResponse{}),
+}
+
+// respondUsers writes one page of the users matched by query.
+func respondUsers(c *gin.Context, query *gorm.DB) {
+	q, err := listquery.Parse(c, userListSpec)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
 
 	var users []models.User
-	if err := config.DB.Where("hospital_id = ?", hospitalID).Find(&users).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+	result, err := q.Find(query, &users, "ProfessionGroup", "Title")
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "users_fetch_failed", "Failed to fetch users"))
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	policy := masking.For(c)
+	response := make([]UserResponse, 0, len(users))
+	for _, u := range users {
+		response = append(response, newUserResponse(policy, u))
+	}
+
+	q.Respond(c, response, result)
+}
+
+// filterUsers applies the ListUsers query filters, scoped to the caller's hospital.
+func filterUsers(c *gin.Context, query *gorm.DB) *gorm.DB {
+	query = query.Where("hospital_id = ?", c.GetInt("hospitalID"))
+
+	// Name filters ignore case and Turkish diacritics, see search.Fold.
+	if name := c.Query("name"); name != "" {
+		query = query.Where("tr_fold(name) LIKE ?", "%"+search.Fold(name)+"%")
+	}
+	if surname := c.Query("surname"); surname != "" {
+		query = query.Where("tr_fold(surname) LIKE ?", "%"+search.Fold(surname)+"%")
+	}
+	if tckn := c.Query("tckn"); tckn != "" {
+		query = query.Scopes(models.UserWithTCKN(tckn))
+	}
+	if professionGroupID := c.Query("profession_group_id"); professionGroupID != "" {
+		query = query.Where("profession_group_id = ?", professionGroupID)
+	}
+	if titleID := c.Query("title_id"); titleID != "" {
+		query = query.Where("title_id = ?", titleID)
+	}
+	return query
 }
 
 // ListUsers godoc
 // @Summary List users with filtering and pagination (admin only)
+// @Description TCKN, phone and email are masked unless the caller may see them. Sortable by id, name, surname, email, role, created_at and updated_at
 // @Tags Users
 // @Produce json
-// @Param page query int false "Page number"
+// @Param sort query string false "Comma separated fields, prefix with - for descending"
+// @Param page_size query int false "Items per page (max 100)"
+// @Param page query int false "Page number, ignored when cursor is set"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated fields to return"
 // @Param name query string false "Filter by name"
 // @Param surname query string false "Filter by surname"
 // @Param tckn query string false "Filter by TCKN"
 // @Param profession_group_id query string false "Filter by profession group ID"
 // @Param title_id query string false "Filter by title ID"
-// @Success 200 {object} map[string]interface{}
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} listquery.Page{data=[]UserResponse}
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
 // @Security BearerAuth
 // @Router /listusers [get]
 func ListUsers(c *gin.Context) {
-	hospitalID := c.GetInt("hospitalID")
+	respondUsers(c, filterUsers(c, config.DB))
+}
+
+// UserPatch lists the fields a merge patch may change. Absent fields are left
+// untouched; see nullableUserFields for fields that may be set to null.
+type UserPatch struct {
+	Name       *string `json:"name" binding:"omitnil,min=1"`
+	Surname    *string `json:"surname" binding:"omitnil,min=1"`
+	Email      *string `json:"email" binding:"omitnil,email"`
+	Phone      *string `json:"phone" binding:"omitnil,tr_phone"`
+	TCKN       *string `json:"tckn" binding:"omitnil,tckn"`
+	Role       *string `json:"role" binding:"omitnil,oneof=admin user"`
+	Language   *string `json:"language" binding:"omitnil,oneof=tr en"`
+	HospitalID *uint   `json:"hospital_id" binding:"omitnil,gt=0"`
+	// MustChangePassword true makes the user pick a new password at next login.
+	MustChangePassword *bool `json:"must_change_password"`
+	ProfessionGroupID  *uint `json:"profession_group_id" binding:"omitnil,gt=0"`
+	TitleID            *uint `json:"title_id" binding:"omitnil,gt=0"`
+}
+
+// nullableUserFields may be removed with null in a merge patch, resetting them
+// to their default. Every other field is required.
+var nullableUserFields = map[string]bool{
+	"language": true,
+}
 
-	pageStr := c.DefaultQuery("page", "1")
-	name := c.Query("name")
-	surname := c.Query("surname")
-	tckn := c.Query("tckn")
-	professionGroupID := c.Query("profession_group_id")
-	titleID := c.Query("title_id")
+// userETag is the entity tag of a user's current version.
+func userETag(u models.User) string {
+	return fmt.Sprintf(`"%d-%d"`, u.ID, u.Version)
+}
 
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		page = 1
+// etagMatches reports whether an If-Match header lists etag or is "*".
+func etagMatches(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
 	}
-	limit := 10
-	offset := (page - 1) * limit
+	return false
+}
 
-	var users []models.User
-	query := config.DB.Preload("ProfessionGroup").Preload("Title").Where("hospital_id = ?", hospitalID)
+// parseUserPatch decodes a JSON Merge Patch (RFC 7396) body. It returns the
+// patch and whether language was explicitly set to null.
+func parseUserPatch(c *gin.Context) (UserPatch, bool, error) {
+	var patch UserPatch
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return patch, false, apperrors.BadRequest("invalid_request", "Invalid request")
+	}
 
-	if name != "" {
-		query = query.Where("name ILIKE ?", "%"+name+"%")
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		return patch, false, apperrors.Validation(err)
 	}
-	if surname != "" {
-		query = query.Where("surname ILIKE ?", "%"+surname+"%")
+	fields := map[string]bool{}
+	t := reflect.TypeOf(patch)
+	for i := 0; i < t.NumField(); i++ {
+		fields[t.Field(i).Tag.Get("json")] = true
 	}
-	if tckn != "" {
-		query = query.Where("tckn = ?", tckn)
+	// language is the only nullable field, so any accepted null resets it.
+	resetLanguage := false
+	for name, value := range members {
+		if !fields[name] {
+			return patch, false, apperrors.InvalidField(name, "unknown", "field_unknown")
+		}
+		if string(value) == "null" {
+			if !nullableUserFields[name] {
+				return patch, false, apperrors.InvalidField(name, "required", "field_required")
+			}
+			resetLanguage = true
+		}
 	}
-	if professionGroupID != "" {
-		query = query.Where("profession_group_id = ?", professionGroupID)
+
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return patch, false, apperrors.Validation(err)
 	}
-	if titleID != "" {
-		query = query.Where("title_id = ?", titleID)
+	if err := binding.Validator.ValidateStruct(&patch); err != nil {
+		return patch, false, apperrors.Validation(err)
+	}
+	return patch, resetLanguage, nil
+}
+
+// checkUserReferences makes sure the hospital, profession group and title of
+// u exist and that the title belongs to the profession group.
+func checkUserReferences(tx *gorm.DB, u models.User) error {
+	var count int64
+	if err := tx.Model(&models.Hospital{}).Where("id = ?", u.HospitalID).Count(&count).Error; err != nil {
+		return apperrors.Internal(err, "user_update_failed", "Failed to update user")
+	}
+	if count == 0 {
+		return apperrors.InvalidField("hospital_id", "reference", "field_reference")
+	}
+	if err := tx.Model(&models.ProfessionGroup{}).Where("id = ?", u.ProfessionGroupID).Count(&count).Error; err != nil {
+		return apperrors.Internal(err, "user_update_failed", "Failed to update user")
+	}
+	if count == 0 {
+		return apperrors.InvalidField("profession_group_id", "reference", "field_reference")
 	}
 
-	if err := query.Offset(offset).Limit(limit).Find(&users).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+	var title models.Title
+	if err := tx.First(&title, u.TitleID).Error; err != nil {
+		return apperrors.InvalidField("title_id", "reference", "field_reference")
+	}
+	if title.ProfessionGroupID != u.ProfessionGroupID {
+		return apperrors.InvalidField("title_id", "profession_group", "field_title_group")
+	}
+	return nil
+}
+
+// GetUser godoc
+// @Summary Get a user of the admin's hospital (admin only)
+// @Description The ETag header carries the user's version for use in If-Match. TCKN, phone and email are masked unless the caller may see them
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} UserResponse
+// @Header 200 {string} ETag "Current version of the user"
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/{id} [get]
+func GetUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_user_id", "Invalid user ID"))
 		return
 	}
 
-	var response []UserResponse
-	for _, u := range users {
-		response = append(response, UserResponse{
-			ID:                u.ID,
-			Name:              u.Name,
-			Surname:           u.Surname,
-			Email:             u.Email,
-			Phone:             u.Phone,
-			TCKN:              u.TCKN,
-			Role:              u.Role,
-			HospitalID:        u.HospitalID,
-			ProfessionGroupID: u.ProfessionGroupID,
-			ProfessionGroup:   u.ProfessionGroup.Name,
-			TitleID:           u.TitleID,
-			Title:             u.Title.Name,
-		})
+	var user models.User
+	err = config.DB.Preload("ProfessionGroup").Preload("Title").
+		Where("hospital_id = ?", c.GetInt("hospitalID")).
+		First(&user, id).Error
+	if err != nil {
+		apperrors.Abort(c, apperrors.NotFound("user_not_found", "User not found"))
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"page":  page,
-		"limit": limit,
-		"data":  response,
-	})
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, newUserResponse(masking.For(c), user))
 }
 
 // UpdateUser godoc
-// @Summary Update a user's info (admin only)
+// @Summary Update a user with a JSON Merge Patch (admin only)
+// @Description Only the fields present in the body change. null resets language; other fields can't be removed. Send the user's ETag in If-Match; the update fails with 412 if someone changed the user in the meantime. Passwords can't be changed here; users set their own through their invitation or a password reset. Changing the role or hospital logs the user out of every session
 // @Tags Users
 // @Accept json
+// @Accept application/merge-patch+json
 // @Produce json
 // @Param id path int true "User ID"
-// @Param user body map[string]string true "Updated user data"
+// @Param If-Match header string true "ETag from GET /users/{id}"
+// @Param user body UserPatch true "Fields to change"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Header 200 {string} ETag "New version of the user"
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem
+// @Failure 412 {object} apperrors.Problem
+// @Failure 428 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
 // @Security BearerAuth
-// @Router /users/{id} [put]
+// @Router /users/{id} [patch]
 func UpdateUser(c *gin.Context) {
-
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		apperrors.Abort(c, apperrors.BadRequest("invalid_user_id", "Invalid user ID"))
 		return
 	}
 
 	var user models.User
-	if err := config.DB.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err := config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")).First(&user, id).Error; err != nil {
+		apperrors.Abort(c, apperrors.NotFound("user_not_found", "User not found"))
 		return
 	}
 
-	var input struct {
-		Name     string `json:"name"`
-		Surname  string `json:"surname"`
-		Email    string `json:"email"`
-		Phone    string `json:"phone"`
-		TCKN     string `json:"tckn"`
-		Role     string `json:"role"`
-		Password string `json:"password"`
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" && c.Request.Method == http.MethodPatch {
+		apperrors.Abort(c, apperrors.PreconditionRequired("if_match_required", "If-Match header with the user's ETag is required"))
+		return
 	}
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if ifMatch != "" && !etagMatches(ifMatch, userETag(user)) {
+		apperrors.Abort(c, apperrors.PreconditionFailed("version_mismatch", "The user was changed by someone else; reload and try again"))
 		return
 	}
 
-	user.Name = input.Name
-	user.Surname = input.Surname
-	user.Email = input.Email
-	user.Phone = input.Phone
-	user.TCKN = input.TCKN
-	user.Role = input.Role
-
-	if input.Password != "" {
-		hashed, _ := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
-		user.Password = string(hashed)
+	patch, resetLanguage, err := parseUserPatch(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
 	}
 
-	if err := config.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+	before := user
+	if patch.Name != nil {
+		user.Name = *patch.Name
+	}
+	if patch.Surname != nil {
+		user.Surname = *patch.Surname
+	}
+	if patch.Email != nil {
+		user.Email = *patch.Email
+	}
+	if patch.Phone != nil {
+		user.Phone = utils.NormalizePhoneOrKeep(*patch.Phone)
+	}
+	if patch.TCKN != nil {
+		user.TCKN = *patch.TCKN
+	}
+	if patch.Role != nil {
+		user.Role = *patch.Role
+	}
+	if patch.Language != nil {
+		user.Language = *patch.Language
+	} else if resetLanguage {
+		user.Language = ""
+	}
+	if patch.HospitalID != nil {
+		if !mayManageHospital(c, *patch.HospitalID) {
+			return
+		}
+		user.HospitalID = *patch.HospitalID
+	}
+	if patch.ProfessionGroupID != nil {
+		user.ProfessionGroupID = *patch.ProfessionGroupID
+	}
+	if patch.TitleID != nil {
+		user.TitleID = *patch.TitleID
+	}
+	if patch.MustChangePassword != nil {
+		user.MustChangePassword = *patch.MustChangePassword
+	}
+	if !mayManageAdmins(c, before.Role, user.Role) {
+		return
+	}
+	user.Version++
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkUserReferences(tx, user); err != nil {
+			return err
+		}
+		result := tx.Model(&user).Where("version = ?", before.Version).
+			Select("name", "surname", "email", "phone", "phone_index", "tckn", "tckn_index", "role",
+				"language", "hospital_id", "profession_group_id", "title_id", "must_change_password", "version").
+			Updates(&user)
+		if result.Error != nil {
+			return apperrors.FromDB(result.Error, "user_update_failed", "Failed to update user")
+		}
+		if result.RowsAffected == 0 {
+			return apperrors.PreconditionFailed("version_mismatch", "The user was changed by someone else; reload and try again")
+		}
+		// Tokens carry the role and hospital they were issued with, so they
+		// have to go when those change.
+		if user.Role != before.Role || user.HospitalID != before.HospitalID || user.Status != before.Status {
+			if _, err := session.RevokeUser(tx, user.ID, 0, uint(c.GetInt("userID"))); err != nil {
+				return apperrors.Internal(err, "session_revoke_failed", "Failed to revoke session")
+			}
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "user.update",
+			EntityType: "user",
+			EntityID:   user.ID,
+			Before:     before,
+			After:      user,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "user_updated")})
+}
+
+// ReplaceUser godoc
+// @Summary Update a user (admin only, deprecated)
+// @Description Deprecated alias of PATCH /users/{id} kept for older clients. It applies merge patch semantics; If-Match is optional
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param If-Match header string false "ETag from GET /users/{id}"
+// @Param user body UserPatch true "Fields to change"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 409 {object} apperrors.Problem
+// @Failure 412 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Deprecated
+// @Router /users/{id} [put]
+func ReplaceUser(c *gin.Context) {
+	UpdateUser(c)
 }
 
 // DeleteUser godoc
@@ -252,22 +615,46 @@ func UpdateUser(c *gin.Context) {
 // @Produce json
 // @Param id path int true "User ID"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} apperrors.Problem
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
 // @Security BearerAuth
 // @Router /users/{id} [delete]
 func DeleteUser(c *gin.Context) {
 
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		apperrors.Abort(c, apperrors.BadRequest("invalid_user_id", "Invalid user ID"))
 		return
 	}
 
-	if err := config.DB.Delete(&models.User{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+	var user models.User
+	if err := config.DB.Where("hospital_id = ?", c.GetInt("hospitalID")).First(&user, id).Error; err != nil {
+		apperrors.Abort(c, apperrors.NotFound("user_not_found", "User not found"))
+		return
+	}
+	if !mayManageAdmins(c, user.Role) {
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&user).Error; err != nil {
+			return apperrors.FromDB(err, "user_delete_failed", "Failed to delete user")
+		}
+		if _, err := session.RevokeUser(tx, user.ID, 0, uint(c.GetInt("userID"))); err != nil {
+			return apperrors.Internal(err, "session_revoke_failed", "Failed to revoke session")
+		}
+		return audit.Record(tx, c, audit.Entry{
+			Action:     "user.delete",
+			EntityType: "user",
+			EntityID:   user.ID,
+			Before:     user,
+		})
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "user_deleted")})
 }
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/importer"
+	"github.com/efecan/vatansoft-case/permissions"
+	"github.com/gin-gonic/gin"
+)
+
+// ImportUsers godoc
+// @Summary Import users from a CSV or XLSX file (admin only)
+// @Description The first row is a header with the columns name, surname, tckn, email, phone, role, language, profession_group_id and title_id; language is optional and role is admin or user. Admin rows need the users:admin scope when importing with an API key. Users are created as pending users in the admin's hospital and invited by email to set their own password.
+// @Description With mode=atomic (default) nothing is imported if any row is invalid; with mode=batch valid rows are committed in batches of batch_size.
+// @Description Files with up to IMPORT_SYNC_ROWS rows are processed right away (200). Larger files run in the background (202); poll the job at its Location.
+// @Tags Users
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX file"
+// @Param dry_run formData bool false "Only validate and report per-row errors"
+// @Param mode formData string false "atomic (default) or batch"
+// @Param batch_size formData int false "Rows per transaction in batch mode (default 100, max 1000)"
+// @Success 200 {object} importer.Job
+// @Success 202 {object} importer.Job
+// @Failure 400 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/import [post]
+func ImportUsers(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("import_file_required", "A CSV or XLSX file is required in the file field"))
+		return
+	}
+	maxBytes, _ := strconv.ParseInt(config.GetEnv("IMPORT_MAX_BYTES", "10485760"), 10, 64)
+	if header.Size > maxBytes {
+		apperrors.Abort(c, apperrors.BadRequest("import_file_too_large", "The import file is too large"))
+		return
+	}
+
+	mode := c.DefaultPostForm("mode", importer.ModeAtomic)
+	if mode != importer.ModeAtomic && mode != importer.ModeBatch {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_import_mode", "mode must be atomic or batch"))
+		return
+	}
+	batchSize, err := strconv.Atoi(c.DefaultPostForm("batch_size", "100"))
+	if err != nil || batchSize < 1 || batchSize > 1000 {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_batch_size", "batch_size must be between 1 and 1000"))
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.DefaultPostForm("dry_run", "false"))
+
+	file, err := header.Open()
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "import_failed", "Import failed"))
+		return
+	}
+	defer file.Close()
+
+	records, err := importer.Parse(header.Filename, file)
+	if errors.Is(err, importer.ErrUnsupportedFormat) {
+		apperrors.Abort(c, apperrors.BadRequest("import_unsupported_format", "Only .csv and .xlsx files are supported"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, apperrors.KindInvalid, "import_file_invalid", "The import file could not be read"))
+		return
+	}
+	if len(records) == 0 {
+		apperrors.Abort(c, apperrors.BadRequest("import_file_empty", "The import file has no rows"))
+		return
+	}
+	maxRows, _ := strconv.Atoi(config.GetEnv("IMPORT_MAX_ROWS", "5000"))
+	if len(records) > maxRows {
+		apperrors.Abort(c, apperrors.BadRequest("import_too_many_rows", "The import file has too many rows"))
+		return
+	}
+
+	job, err := importer.NewJob(c, importer.Job{
+		HospitalID:     uint(c.GetInt("hospitalID")),
+		CreatedBy:      uint(c.GetInt("userID")),
+		APIKeyID:       uint(c.GetInt("apiKeyID")),
+		ImpersonatorID: uint(c.GetInt("impersonatorID")),
+		IP:             c.ClientIP(),
+		RequestID:      c.GetString("requestID"),
+		DryRun:         dryRun,
+		Mode:           mode,
+		BatchSize:      batchSize,
+		Language:       i18n.Lang(c),
+		AllowAdmins:    permissions.Granted(c, permissions.ManageAdmins),
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "import_failed", "Import failed"))
+		return
+	}
+
+	syncRows, _ := strconv.Atoi(config.GetEnv("IMPORT_SYNC_ROWS", "200"))
+	if len(records) <= syncRows {
+		importer.Run(c, config.DB, job, records)
+		c.JSON(http.StatusOK, job)
+		return
+	}
+
+	go importer.Run(context.Background(), config.DB, job, records)
+	c.Header("Location", "/users/import/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetImportJob godoc
+// @Summary Get the status of a user import job (admin only)
+// @Tags Users
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} importer.Job
+// @Failure 404 {object} apperrors.Problem
+// @Failure 500 {object} apperrors.Problem
+// @Security BearerAuth
+// @Router /users/import/{id} [get]
+func GetImportJob(c *gin.Context) {
+	job, err := importer.LoadJob(c, c.Param("id"))
+	if errors.Is(err, importer.ErrJobNotFound) || (err == nil && job.HospitalID != uint(c.GetInt("hospitalID"))) {
+		apperrors.Abort(c, apperrors.NotFound("import_job_not_found", "Import job not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "import_job_fetch_failed", "Failed to fetch import job"))
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
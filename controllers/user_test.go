@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// requestAs builds a request context with a JSON body, signed in as described
+// by auth.
+func requestAs(method, target, body string, auth gin.H) (*gin.Context, *httptest.ResponseRecorder) {
+	utils.SetupValidator()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	for k, v := range auth {
+		c.Set(k, v)
+	}
+	return c, w
+}
+
+func wantProblem(t *testing.T, w *httptest.ResponseRecorder, status int, code string) apperrors.Problem {
+	t.Helper()
+	var p apperrors.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("got %d %s, want a problem: %v", w.Code, w.Body, err)
+	}
+	if w.Code != status || p.Code != code {
+		t.Fatalf("got %d %s, want %d %s", w.Code, p.Code, status, code)
+	}
+	return p
+}
+
+func newUserBody(role string, hospitalID uint) string {
+	return `{"name":"Ayşe","surname":"Yılmaz","tckn":"10000000146","email":"ayse@example.com",` +
+		`"phone":"05551112233","role":"` + role + `","hospital_id":` + strconv.Itoa(int(hospitalID)) +
+		`,"profession_group_id":1,"title_id":1}`
+}
+
+func TestCreateUserRejects(t *testing.T) {
+	admin := gin.H{"userID": 1, "userRole": "admin", "hospitalID": 1}
+	platformAdmin := gin.H{"userID": 1, "userRole": "admin", "hospitalID": 1, "platformAdmin": true, "impersonatorID": 2}
+	apiKey := gin.H{"apiKeyID": 1, "hospitalID": 1, "apiKeyScopes": []string{"users:write"}}
+
+	tests := []struct {
+		name   string
+		auth   gin.H
+		body   string
+		status int
+		code   string
+	}{
+		{"unknown role", admin, newUserBody("superuser", 1), http.StatusBadRequest, "validation_failed"},
+		{"another hospital", admin, newUserBody("user", 2), http.StatusForbidden, "hospital_scope"},
+		{"another hospital while impersonating", platformAdmin, newUserBody("user", 2), http.StatusForbidden, "hospital_scope"},
+		{"another hospital by API key", apiKey, newUserBody("user", 2), http.StatusForbidden, "api_key_hospital_scope"},
+		{"admin by API key without users:admin", apiKey, newUserBody("admin", 1), http.StatusForbidden, "admin_management_forbidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := requestAs("POST", "/users", tt.body, tt.auth)
+			CreateUser(c)
+			p := wantProblem(t, w, tt.status, tt.code)
+			if tt.code == "validation_failed" && (len(p.Errors) != 1 || p.Errors[0].Field != "role" || p.Errors[0].Code != "oneof") {
+				t.Fatalf("got field errors %+v, want role oneof", p.Errors)
+			}
+		})
+	}
+}
+
+func TestUpdateUserRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   func(other models.Hospital) string
+		status int
+		code   string
+	}{
+		{
+			name:   "unknown role",
+			body:   func(other models.Hospital) string { return `{"role":"superuser"}` },
+			status: http.StatusBadRequest,
+			code:   "validation_failed",
+		},
+		{
+			name:   "another hospital",
+			body:   func(other models.Hospital) string { return `{"hospital_id":` + strconv.Itoa(int(other.ID)) + `}` },
+			status: http.StatusForbidden,
+			code:   "hospital_scope",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := testDB(t)
+			hospital := createHospital(t, tx, "Ankara")
+			other := createHospital(t, tx, "İzmir")
+			user := createUser(t, tx, models.User{Name: "Ayşe", Email: "ayse@example.com", Role: "user", HospitalID: hospital.ID})
+			if err := tx.First(&user, user.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			id := strconv.Itoa(int(user.ID))
+			auth := gin.H{"userID": 1, "userRole": "admin", "hospitalID": int(hospital.ID)}
+			c, w := requestAs("PATCH", "/users/"+id, tt.body(other), auth)
+			c.Request.Header.Set("If-Match", userETag(user))
+			c.Params = gin.Params{{Key: "id", Value: id}}
+			UpdateUser(c)
+			wantProblem(t, w, tt.status, tt.code)
+
+			var after models.User
+			if err := tx.First(&after, user.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if after.Role != user.Role || after.HospitalID != user.HospitalID || after.Version != user.Version {
+				t.Fatalf("user changed to %+v", after)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Ciphertexts are stored as "enc:<key id>:<base64(nonce || sealed)>". Values
+// without the prefix are legacy plaintext and are returned unchanged by Decrypt.
+const prefix = "enc:"
+
+var (
+	mu          sync.RWMutex
+	keys        map[string]cipher.AEAD
+	activeKeyID string
+	indexKey    []byte
+)
+
+var ErrUnknownKey = errors.New("encryption: ciphertext uses an unknown key id")
+
+// Init loads the keyring. keySpec is a comma separated list of
+// "<id>:<base64 32-byte key>" pairs; activeID picks the key used for new
+// ciphertexts and defaults to the last listed key. blindIndexKey is the
+// base64 HMAC key for blind indexes. Empty values fall back to fixed
+// development keys so a local setup works without configuration.
+func Init(keySpec, activeID, blindIndexKey string) error {
+	if keySpec == "" {
+		log.Println("⚠️ ENCRYPTION_KEYS not set, using an insecure development key")
+		keySpec = "dev:" + base64.StdEncoding.EncodeToString(devKey("encryption"))
+	}
+	if blindIndexKey == "" {
+		log.Println("⚠️ BLIND_INDEX_KEY not set, using an insecure development key")
+		blindIndexKey = base64.StdEncoding.EncodeToString(devKey("blind-index"))
+	}
+
+	ring := map[string]cipher.AEAD{}
+	lastID := ""
+	for _, entry := range strings.Split(keySpec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return fmt.Errorf("encryption: invalid key entry %q, expected <id>:<base64 key>", entry)
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != 32 {
+			return fmt.Errorf("encryption: key %q must be 32 bytes, base64 encoded", id)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		ring[id] = aead
+		lastID = id
+	}
+
+	if activeID == "" {
+		activeID = lastID
+	}
+	if _, ok := ring[activeID]; !ok {
+		return fmt.Errorf("encryption: active key %q is not in ENCRYPTION_KEYS", activeID)
+	}
+
+	idxKey, err := base64.StdEncoding.DecodeString(blindIndexKey)
+	if err != nil || len(idxKey) < 32 {
+		return errors.New("encryption: BLIND_INDEX_KEY must be at least 32 bytes, base64 encoded")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	keys, activeKeyID, indexKey = ring, activeID, idxKey
+	return nil
+}
+
+func devKey(purpose string) []byte {
+	sum := sha256.Sum256([]byte("vatansoft-dev-" + purpose))
+	return sum[:]
+}
+
+// Encrypt seals plaintext with the active key. Empty strings stay empty so
+// optional fields don't need special casing.
+func Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	mu.RLock()
+	aead, id := keys[activeKeyID], activeKeyID
+	mu.RUnlock()
+	if aead == nil {
+		return "", errors.New("encryption: keyring not initialized")
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(id))
+	return prefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Legacy plaintext is passed through.
+func Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", errors.New("encryption: malformed ciphertext")
+	}
+
+	mu.RLock()
+	aead := keys[id]
+	mu.RUnlock()
+	if aead == nil {
+		return "", ErrUnknownKey
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("encryption: malformed ciphertext")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("encryption: decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether a stored value is plaintext or sealed with a
+// key other than the active one.
+func NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	if !strings.HasPrefix(value, prefix) {
+		return true
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+
+	mu.RLock()
+	defer mu.RUnlock()
+	return id != activeKeyID
+}
+
+// BlindIndex returns a deterministic keyed hash of value for exact-match
+// lookups on encrypted columns. purpose keeps indexes of different fields
+// unrelated. Empty values have no index so they don't collide in unique indexes.
+func BlindIndex(purpose, value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	mu.RLock()
+	mac := hmac.New(sha256.New, indexKey)
+	mu.RUnlock()
+	mac.Write([]byte(purpose + ":" + value))
+	index := hex.EncodeToString(mac.Sum(nil))
+	return &index
+}
@@ -0,0 +1,156 @@
+package i18n
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/tr"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	trTranslations "github.com/go-playground/validator/v10/translations/tr"
+	"golang.org/x/text/language"
+)
+
+const (
+	English = "en"
+	Turkish = "tr"
+)
+
+// Supported lists the languages we ship catalogs for. The first entry is the
+// fallback when neither the user nor the request names a supported language.
+var Supported = []string{English, Turkish}
+
+var catalogs = map[string]map[string]string{
+	English: messagesEN,
+	Turkish: messagesTR,
+}
+
+var (
+	defaultLang = English
+	matcher     = language.NewMatcher([]language.Tag{language.English, language.Turkish})
+	universal   *ut.UniversalTranslator
+)
+
+// Init loads DEFAULT_LANGUAGE and registers tr/en translations for Gin's
+// validator, so binding errors can be rendered in the caller's language.
+// It must run after any custom validation tags are registered.
+func Init() {
+	if lang, ok := os.LookupEnv("DEFAULT_LANGUAGE"); ok && IsSupported(lang) {
+		defaultLang = lang
+	}
+
+	universal = ut.New(en.New(), en.New(), tr.New())
+
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	enTrans, _ := universal.GetTranslator(English)
+	if err := enTranslations.RegisterDefaultTranslations(v, enTrans); err != nil {
+		log.Printf("⚠️ Failed to register English validation messages: %v", err)
+	}
+	trTrans, _ := universal.GetTranslator(Turkish)
+	if err := trTranslations.RegisterDefaultTranslations(v, trTrans); err != nil {
+		log.Printf("⚠️ Failed to register Turkish validation messages: %v", err)
+	}
+
+	registerCustomTags(v, English, enTrans)
+	registerCustomTags(v, Turkish, trTrans)
+}
+
+// registerCustomTags adds translations for our own validation tags from the
+// "validation.<tag>" catalog entries.
+func registerCustomTags(v *validator.Validate, lang string, trans ut.Translator) {
+	for key, text := range catalogs[lang] {
+		tag, ok := strings.CutPrefix(key, "validation.")
+		if !ok {
+			continue
+		}
+		err := v.RegisterTranslation(tag, trans,
+			func(t ut.Translator) error {
+				return t.Add(tag, text, true)
+			},
+			func(t ut.Translator, fe validator.FieldError) string {
+				msg, _ := t.T(fe.Tag(), fe.Field())
+				return msg
+			},
+		)
+		if err != nil {
+			log.Printf("⚠️ Failed to register %s translation for %q: %v", lang, tag, err)
+		}
+	}
+}
+
+// Default is the language used when nothing else picks one.
+func Default() string {
+	return defaultLang
+}
+
+func IsSupported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Lang picks the response language: the authenticated user's saved
+// preference first, then the Accept-Language header, then the default.
+func Lang(c *gin.Context) string {
+	if lang := c.GetString("userLang"); IsSupported(lang) {
+		return lang
+	}
+	if header := c.GetHeader("Accept-Language"); header != "" {
+		if tags, _, err := language.ParseAcceptLanguage(header); err == nil && len(tags) > 0 {
+			_, index, confidence := matcher.Match(tags...)
+			if confidence != language.No {
+				return Supported[index]
+			}
+		}
+	}
+	return defaultLang
+}
+
+// Lookup returns the catalog entry for key in lang, falling back to English.
+func Lookup(lang, key string) (string, bool) {
+	if msg, ok := catalogs[lang][key]; ok {
+		return msg, true
+	}
+	msg, ok := catalogs[English][key]
+	return msg, ok
+}
+
+// Message renders key in lang, replacing {name} placeholders from params.
+// Unknown keys are returned unchanged.
+func Message(lang, key string, params map[string]string) string {
+	msg, ok := Lookup(lang, key)
+	if !ok {
+		msg = key
+	}
+	for name, value := range params {
+		msg = strings.ReplaceAll(msg, "{"+name+"}", value)
+	}
+	return msg
+}
+
+// T renders key in the language of the current request and sets Content-Language.
+func T(c *gin.Context, key string) string {
+	lang := Lang(c)
+	c.Header("Content-Language", lang)
+	return Message(lang, key, nil)
+}
+
+// Translator returns the validator translator for lang, or nil before Init.
+func Translator(lang string) ut.Translator {
+	if universal == nil {
+		return nil
+	}
+	trans, found := universal.GetTranslator(lang)
+	if !found {
+		trans, _ = universal.GetTranslator(English)
+	}
+	return trans
+}
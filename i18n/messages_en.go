@@ -0,0 +1,281 @@
+package i18n
+
+var messagesEN = map[string]string{
+	// Generic errors
+	"internal_error":          "Internal server error",
+	"invalid_request":         "Invalid request",
+	"malformed_body":          "Request body is not valid JSON",
+	"validation_failed":       "One or more fields are invalid",
+	"not_found":               "Record not found",
+	"already_exists":          "A record with the same value already exists",
+	"invalid_reference":       "A referenced record does not exist",
+	"rate_limited":            "Too many requests, please try again later",
+	"unauthorized":            "Unauthorized",
+	"access_denied":           "Access denied",
+	"admin_required":          "Admin access required",
+	"missing_auth_header":     "Missing auth header",
+	"invalid_auth_format":     "Invalid auth format",
+	"invalid_token":           "Invalid or expired token",
+	"invalid_token_payload":   "Invalid token payload",
+	"idempotency_key_invalid": "Idempotency-Key must be at most 255 characters",
+	"idempotency_key_reused":  "Idempotency-Key was already used with a different request",
+	"idempotency_in_progress": "A request with this Idempotency-Key is already being processed",
+
+	// List queries
+	"invalid_sort":   "sort uses a field that can't be sorted by",
+	"invalid_cursor": "cursor is invalid or doesn't match the sort",
+	"invalid_fields": "fields lists a field this endpoint doesn't return",
+
+	// Custom validation tags, {0} is the field name
+	"validation.tckn":     "{0} must be a valid Turkish ID number",
+	"validation.vkn":      "{0} must be a valid tax ID number",
+	"validation.tr_phone": "{0} must be a valid Turkish phone number",
+
+	// Field errors
+	"field_unique":           "{field} is already in use",
+	"field_reference":        "{field} does not exist",
+	"field_required":         "{field} is required",
+	"field_type":             "{field} must be of type {type}",
+	"field_unknown":          "{field} can't be changed",
+	"field_title_group":      "{field} must belong to the selected profession group",
+	"field_number":           "{field} must be a number",
+	"field_duplicate_row":    "{field} is used by an earlier row of the file",
+	"field_scope":            "{field} contains an unknown scope",
+	"field_cidr":             "{field} must contain IP addresses or CIDR ranges",
+	"field_future":           "{field} must be in the future",
+	"field_oidc_discovery":   "{field} must be an OpenID Connect issuer whose discovery document can be fetched",
+	"field_https_url":        "{field} must be an https:// URL",
+	"field_internal_address": "{field} must not point at a private or local network address",
+	"field_ldap_url":         "{field} must be an ldap:// or ldaps:// URL",
+	"field_ldap_connect":     "{field} could not be connected to with the given settings",
+	"field_pem":              "{field} must contain PEM encoded certificates",
+	"field_admin_role":       "{field} can only be admin for callers that may manage admins",
+
+	// Password policy field errors
+	"password_too_short":     "{field} must be at least {min} characters long",
+	"password_too_long":      "{field} must be at most {max} bytes long",
+	"password_needs_upper":   "{field} must contain an upper-case letter",
+	"password_needs_lower":   "{field} must contain a lower-case letter",
+	"password_needs_digit":   "{field} must contain a digit",
+	"password_needs_symbol":  "{field} must contain a symbol",
+	"password_personal_info": "{field} must not contain your name, email or national ID number",
+	"password_breached":      "{field} appears in a list of breached passwords; choose another one",
+	"password_reused":        "{field} must differ from your last {count} passwords",
+
+	// Auth
+	"admin_role_required":     "Only admin registration is allowed on this endpoint",
+	"hospital_exists":         "Hospital already exists with provided tax/email/phone",
+	"hospital_admin_exists":   "This hospital already has an admin user",
+	"hospital_registered":     "Hospital and admin user registered; confirm their email addresses and phone numbers to activate them",
+	"invalid_credentials":     "Invalid credentials",
+	"token_generation_failed": "Could not generate token",
+	"phone_not_registered":    "User not found for the provided phone number",
+	"reset_code_failed":       "Error creating reset code",
+	"reset_code_sent":         "Reset code has been sent to your phone number. Please use this code to reset your password.",
+	"password_mismatch":       "Passwords do not match",
+	"invalid_code":            "Invalid or expired code",
+	"password_hash_failed":    "Failed to hash password",
+	"password_update_failed":  "Failed to update password",
+	"password_reset":          "Password reset successfully",
+
+	// Password policy
+	"password_policy_failed":        "Password does not meet the password policy",
+	"password_policy_fetch_failed":  "Failed to load the password policy",
+	"password_policy_update_failed": "Failed to update the password policy",
+	"password_change_required":      "Password must be changed before logging in; use /auth/change-password",
+	"password_changed":              "Password changed successfully",
+
+	// Sessions
+	"session_create_failed": "Failed to create session",
+	"session_revoked":       "Session was revoked or has expired; log in again",
+	"session_check_failed":  "Failed to check session",
+	"sessions_fetch_failed": "Failed to fetch sessions",
+	"session_revoke_failed": "Failed to revoke session",
+	"invalid_session_id":    "Invalid session ID",
+	"session_not_found":     "Session not found",
+	"session_revoked_ok":    "Session revoked",
+	"sessions_revoked":      "Sessions revoked",
+
+	// Impersonation
+	"platform_admin_required":   "Platform admin access required",
+	"impersonation_forbidden":   "Not allowed while impersonating a user",
+	"impersonation_not_allowed": "This user can't be impersonated",
+
+	// API keys
+	"api_key_not_allowed":        "API keys can't be used on this endpoint",
+	"invalid_api_key":            "Invalid, revoked or expired API key",
+	"api_key_ip_not_allowed":     "This API key can't be used from your IP address",
+	"api_key_check_failed":       "Failed to check API key",
+	"insufficient_scope":         "The API key lacks a scope this endpoint requires",
+	"api_key_create_failed":      "Failed to create API key",
+	"api_keys_fetch_failed":      "Failed to fetch API keys",
+	"invalid_api_key_id":         "Invalid API key ID",
+	"api_key_not_found":          "API key not found",
+	"api_key_revoke_failed":      "Failed to revoke API key",
+	"api_key_revoked":            "API key revoked",
+	"api_key_hospital_scope":     "API keys can only manage users of their own hospital",
+	"admin_management_forbidden": "Managing admins requires the users:admin scope",
+
+	// Single sign-on
+	"sso_not_configured":       "Single sign-on isn't set up for this hospital",
+	"sso_config_fetch_failed":  "Failed to load single sign-on settings",
+	"sso_config_update_failed": "Failed to save single sign-on settings",
+	"sso_config_delete_failed": "Failed to remove single sign-on settings",
+	"sso_config_deleted":       "Single sign-on settings removed",
+	"sso_unavailable":          "The identity provider can't be reached",
+	"sso_state_invalid":        "The sign-in is unknown or expired; please start over",
+	"sso_failed":               "Signing in with the identity provider failed",
+	"sso_email_missing":        "The identity provider didn't share a verified email address",
+	"sso_no_role":              "Your identity provider account isn't mapped to a role here",
+	"sso_account_not_found":    "No account here matches your identity; ask your hospital admin to add you",
+	"sso_invitation_pending":   "Accept your invitation before signing in",
+
+	// Directory
+	"ldap_not_configured":       "No LDAP directory is set up for this hospital",
+	"ldap_config_fetch_failed":  "Failed to load directory settings",
+	"ldap_config_update_failed": "Failed to save directory settings",
+	"ldap_config_delete_failed": "Failed to remove directory settings",
+	"ldap_config_deleted":       "Directory settings removed",
+	"directory_no_role":         "Your directory account isn't mapped to a role here",
+	"directory_unavailable":     "Your hospital's directory can't be reached",
+	"login_failed":              "Failed to log in",
+
+	// Registration
+	"verification_required":      "Confirm the email addresses and phone numbers of your registration first",
+	"registration_not_found":     "No unverified registration was found",
+	"registration_fetch_failed":  "Failed to load the registration",
+	"registration_update_failed": "Failed to save the verification",
+	"invalid_link":               "Invalid or expired verification link",
+	"contact_already_verified":   "This contact is already confirmed",
+	"contact_verified":           "Contact confirmed",
+	"registration_completed":     "Registration verified; your hospital is now active",
+	"verification_send_failed":   "Failed to send the verification",
+	"verification_sent":          "Verification sent",
+	"registration_email_subject": "Confirm your email address for {hospital}",
+	"registration_email_body":    "Hello {name}, confirm this email address for the registration of {hospital} within {hours} hours: {link}",
+	"registration_code_body":     "Hello {name}, your code to confirm this phone number for the registration of {hospital} is {code}. It expires in {minutes} minutes.",
+
+	// SMS login
+	"sms_login_config_fetch_failed":  "Failed to load SMS login settings",
+	"sms_login_config_update_failed": "Failed to save SMS login settings",
+	"sms_login_code_failed":          "Failed to send login code",
+	"sms_login_code_sent":            "If the phone can be used to log in, a code was sent to it",
+	"sms_login_cooldown":             "A code was just sent to this phone; wait before requesting another",
+	"sms_login_code_body":            "Hello {name}, your login code is {code}. It expires in {minutes} minutes. Never share it; if you didn't ask for it, ignore this message.",
+
+	// Profile
+	"profile_updated":           "Profile updated successfully",
+	"contact_verification_sent": "A verification code was sent to the new address; the change applies once it is confirmed",
+	"email_taken":               "This email address is already in use",
+	"phone_taken":               "This phone number is already in use",
+	"contact_code_failed":       "Failed to send verification code",
+	"contact_verify_failed":     "Failed to verify code",
+	"too_many_attempts":         "Too many wrong codes; request a new one",
+	"wrong_current_password":    "Current password is incorrect",
+	"email_changed":             "Email address changed successfully",
+	"phone_changed":             "Phone number changed successfully",
+	"contact_code_subject":      "Your verification code",
+	"contact_code_body":         "Hello {name}, your verification code is {code}. It expires in {minutes} minutes. If you didn't ask for it, ignore this message.",
+	"email_changed_subject":     "Your email address was changed",
+	"email_changed_body":        "Hello {name}, the email address of your account was changed to {email}. If you didn't do this, contact your administrator right away.",
+
+	// Hospitals
+	"address_create_failed":  "Failed to create address",
+	"hospital_create_failed": "Failed to create hospital",
+	"admin_create_failed":    "Failed to create admin user",
+	"hospital_admin_created": "Hospital and admin created",
+	"hospitals_fetch_failed": "Failed to fetch hospitals",
+
+	// Users
+	"invalid_user_id":        "Invalid user ID",
+	"user_not_found":         "User not found",
+	"hospital_scope":         "You can only manage users of your own hospital",
+	"user_create_failed":     "Failed to create user",
+	"user_created":           "User created successfully",
+	"users_fetch_failed":     "Failed to fetch users",
+	"user_update_failed":     "Failed to update user",
+	"user_updated":           "User updated successfully",
+	"if_match_required":      "If-Match header with the user's ETag is required",
+	"version_mismatch":       "The user was changed by someone else; reload and try again",
+	"user_delete_failed":     "Failed to delete user",
+	"user_deleted":           "User deleted successfully",
+	"deleted_user_not_found": "Deleted user not found",
+	"user_restore_failed":    "Failed to restore user",
+	"user_restored":          "User restored successfully",
+	"user_still_retained":    "User is still within its retention period",
+	"user_purge_failed":      "Failed to purge user",
+	"user_purged":            "User permanently deleted",
+
+	// Invitations
+	"user_invited":             "User created and invitation sent",
+	"invitation_create_failed": "Failed to create invitation",
+	"invitation_not_found":     "Invitation not found",
+	"invitation_expired":       "Invitation has expired; ask your administrator to resend it",
+	"invitation_revoked":       "Invitation was revoked",
+	"invitation_accepted":      "Invitation was already accepted",
+	"invitation_fetch_failed":  "Failed to fetch invitation",
+	"invitation_accept_failed": "Failed to accept invitation",
+	"invitation_accepted_ok":   "Invitation accepted, you can now log in",
+	"terms_not_accepted":       "The terms must be accepted",
+	"user_not_pending":         "User has already accepted their invitation",
+	"invitation_resent":        "Invitation sent again",
+	"invitation_revoke_failed": "Failed to revoke invitation",
+	"invitation_revoked_ok":    "Invitation revoked",
+	"invite_subject":           "You're invited to {hospital}",
+	"invite_body":              "Hello {name}, you've been invited to join {hospital}. Set your password and accept the terms before {expires}: {link}",
+
+	// User import
+	"import_file_required":      "A CSV or XLSX file is required in the file field",
+	"import_file_too_large":     "The import file is too large",
+	"import_unsupported_format": "Only .csv and .xlsx files are supported",
+	"import_file_invalid":       "The import file could not be read",
+	"import_file_empty":         "The import file has no rows",
+	"import_too_many_rows":      "The import file has too many rows",
+	"invalid_import_mode":       "mode must be atomic or batch",
+	"invalid_batch_size":        "batch_size must be between 1 and 1000",
+	"import_failed":             "Import failed",
+	"import_job_not_found":      "Import job not found",
+	"import_job_fetch_failed":   "Failed to fetch import job",
+
+	// Exports
+	"unsupported_export_format": "Unsupported export format",
+	"export_failed":             "Export failed",
+
+	// Roster PDF
+	"roster_title":     "Staff roster",
+	"roster_generated": "Generated",
+	"roster_name":      "Name",
+	"roster_email":     "Email",
+	"roster_empty":     "No doctors in this department",
+	"roster_page":      "Page",
+
+	// Departments
+	"department_type_not_found": "Department type not found",
+	"department_create_failed":  "Could not create department",
+	"department_created":        "Department created successfully",
+	"departments_fetch_failed":  "Failed to retrieve departments",
+	"doctors_fetch_failed":      "Failed to fetch doctors",
+
+	// Audit log
+	"invalid_time_filter":     "from and to must be RFC 3339 timestamps",
+	"audit_logs_fetch_failed": "Failed to fetch audit logs",
+	"audit_verify_failed":     "Failed to verify audit logs",
+
+	// Privacy
+	"invalid_doctor_id":          "Invalid doctor ID",
+	"doctor_not_found":           "Doctor not found",
+	"invalid_export_format":      "format must be json or zip",
+	"subject_export_failed":      "Failed to export personal data",
+	"subject_erase_failed":       "Failed to erase personal data",
+	"subject_already_anonymized": "Personal data has already been erased",
+	"subject_erased":             "Personal data erased successfully",
+
+	// Search
+	"search_query_required": "q must contain at least one letter or digit",
+	"invalid_search_type":   "type must be user, doctor or both",
+	"search_failed":         "Search failed",
+
+	// Reference data
+	"profession_groups_fetch_failed": "Failed to retrieve profession groups",
+	"cities_fetch_failed":            "Failed to retrieve cities",
+}
@@ -0,0 +1,281 @@
+package i18n
+
+var messagesTR = map[string]string{
+	// Generic errors
+	"internal_error":          "Sunucu hatası",
+	"invalid_request":         "Geçersiz istek",
+	"malformed_body":          "İstek gövdesi geçerli bir JSON değil",
+	"validation_failed":       "Bir veya daha fazla alan geçersiz",
+	"not_found":               "Kayıt bulunamadı",
+	"already_exists":          "Aynı değere sahip bir kayıt zaten mevcut",
+	"invalid_reference":       "Başvurulan kayıt mevcut değil",
+	"rate_limited":            "Çok fazla istek gönderildi, lütfen daha sonra tekrar deneyin",
+	"unauthorized":            "Yetkisiz erişim",
+	"access_denied":           "Erişim reddedildi",
+	"admin_required":          "Yönetici yetkisi gerekli",
+	"missing_auth_header":     "Yetkilendirme başlığı eksik",
+	"invalid_auth_format":     "Geçersiz yetkilendirme biçimi",
+	"invalid_token":           "Geçersiz veya süresi dolmuş oturum anahtarı",
+	"invalid_token_payload":   "Geçersiz oturum anahtarı içeriği",
+	"idempotency_key_invalid": "Idempotency-Key en fazla 255 karakter olabilir",
+	"idempotency_key_reused":  "Bu Idempotency-Key farklı bir istekle zaten kullanıldı",
+	"idempotency_in_progress": "Bu Idempotency-Key ile gönderilen istek hâlâ işleniyor",
+
+	// List queries
+	"invalid_sort":   "sort sıralanamayan bir alan içeriyor",
+	"invalid_cursor": "cursor geçersiz veya sıralamayla uyuşmuyor",
+	"invalid_fields": "fields bu uç noktanın döndürmediği bir alan içeriyor",
+
+	// Custom validation tags, {0} is the field name
+	"validation.tckn":     "{0} geçerli bir T.C. kimlik numarası olmalıdır",
+	"validation.vkn":      "{0} geçerli bir vergi kimlik numarası olmalıdır",
+	"validation.tr_phone": "{0} geçerli bir Türkiye telefon numarası olmalıdır",
+
+	// Field errors
+	"field_unique":           "{field} zaten kullanımda",
+	"field_reference":        "{field} mevcut değil",
+	"field_required":         "{field} zorunludur",
+	"field_type":             "{field} alanı {type} türünde olmalıdır",
+	"field_unknown":          "{field} değiştirilemez",
+	"field_title_group":      "{field} seçilen meslek grubuna ait olmalıdır",
+	"field_number":           "{field} sayı olmalıdır",
+	"field_duplicate_row":    "{field} dosyanın önceki bir satırında kullanılmış",
+	"field_scope":            "{field} bilinmeyen bir yetki içeriyor",
+	"field_cidr":             "{field} IP adresleri veya CIDR aralıkları içermeli",
+	"field_future":           "{field} gelecekte bir zaman olmalı",
+	"field_oidc_discovery":   "{field} keşif belgesi alınabilen bir OpenID Connect sağlayıcısı olmalı",
+	"field_https_url":        "{field} https:// ile başlayan bir adres olmalı",
+	"field_internal_address": "{field} özel ya da yerel bir ağ adresini gösteremez",
+	"field_ldap_url":         "{field} ldap:// veya ldaps:// ile başlayan bir adres olmalı",
+	"field_ldap_connect":     "{field} adresine verilen ayarlarla bağlanılamadı",
+	"field_pem":              "{field} PEM biçiminde sertifikalar içermeli",
+	"field_admin_role":       "{field} yalnızca yöneticileri yönetebilenler için admin olabilir",
+
+	// Password policy field errors
+	"password_too_short":     "{field} en az {min} karakter olmalıdır",
+	"password_too_long":      "{field} en fazla {max} bayt olabilir",
+	"password_needs_upper":   "{field} en az bir büyük harf içermelidir",
+	"password_needs_lower":   "{field} en az bir küçük harf içermelidir",
+	"password_needs_digit":   "{field} en az bir rakam içermelidir",
+	"password_needs_symbol":  "{field} en az bir sembol içermelidir",
+	"password_personal_info": "{field} adınızı, e-postanızı veya T.C. kimlik numaranızı içeremez",
+	"password_breached":      "{field} sızdırılmış şifreler listesinde yer alıyor; başka bir şifre seçin",
+	"password_reused":        "{field} son {count} şifrenizden farklı olmalıdır",
+
+	// Auth
+	"admin_role_required":     "Bu uç noktada yalnızca yönetici kaydı yapılabilir",
+	"hospital_exists":         "Bu vergi numarası, e-posta veya telefon ile kayıtlı bir hastane zaten var",
+	"hospital_admin_exists":   "Bu hastanenin zaten bir yöneticisi var",
+	"hospital_registered":     "Hastane ve yönetici kullanıcı kaydedildi; etkinleştirmek için e-posta adreslerini ve telefon numaralarını doğrulayın",
+	"invalid_credentials":     "Geçersiz kullanıcı bilgileri",
+	"token_generation_failed": "Oturum anahtarı oluşturulamadı",
+	"phone_not_registered":    "Bu telefon numarasına kayıtlı kullanıcı bulunamadı",
+	"reset_code_failed":       "Sıfırlama kodu oluşturulamadı",
+	"reset_code_sent":         "Sıfırlama kodu telefonunuza gönderildi. Şifrenizi sıfırlamak için bu kodu kullanın.",
+	"password_mismatch":       "Şifreler eşleşmiyor",
+	"invalid_code":            "Geçersiz veya süresi dolmuş kod",
+	"password_hash_failed":    "Şifre işlenemedi",
+	"password_update_failed":  "Şifre güncellenemedi",
+	"password_reset":          "Şifre başarıyla sıfırlandı",
+
+	// Password policy
+	"password_policy_failed":        "Şifre, şifre politikasını karşılamıyor",
+	"password_policy_fetch_failed":  "Şifre politikası yüklenemedi",
+	"password_policy_update_failed": "Şifre politikası güncellenemedi",
+	"password_change_required":      "Giriş yapmadan önce şifrenizi değiştirmelisiniz; /auth/change-password kullanın",
+	"password_changed":              "Şifre başarıyla değiştirildi",
+
+	// Sessions
+	"session_create_failed": "Oturum oluşturulamadı",
+	"session_revoked":       "Oturum sonlandırılmış veya süresi dolmuş; tekrar giriş yapın",
+	"session_check_failed":  "Oturum kontrol edilemedi",
+	"sessions_fetch_failed": "Oturumlar getirilemedi",
+	"session_revoke_failed": "Oturum sonlandırılamadı",
+	"invalid_session_id":    "Geçersiz oturum kimliği",
+	"session_not_found":     "Oturum bulunamadı",
+	"session_revoked_ok":    "Oturum sonlandırıldı",
+	"sessions_revoked":      "Oturumlar sonlandırıldı",
+
+	// Impersonation
+	"platform_admin_required":   "Platform yöneticisi yetkisi gerekli",
+	"impersonation_forbidden":   "Başka bir kullanıcı adına işlem yaparken buna izin verilmez",
+	"impersonation_not_allowed": "Bu kullanıcının yerine geçilemez",
+
+	// API keys
+	"api_key_not_allowed":        "API anahtarları bu uç noktada kullanılamaz",
+	"invalid_api_key":            "Geçersiz, iptal edilmiş veya süresi dolmuş API anahtarı",
+	"api_key_ip_not_allowed":     "Bu API anahtarı IP adresinizden kullanılamaz",
+	"api_key_check_failed":       "API anahtarı kontrol edilemedi",
+	"insufficient_scope":         "API anahtarı bu uç noktanın gerektirdiği bir yetkiye sahip değil",
+	"api_key_create_failed":      "API anahtarı oluşturulamadı",
+	"api_keys_fetch_failed":      "API anahtarları getirilemedi",
+	"invalid_api_key_id":         "Geçersiz API anahtarı kimliği",
+	"api_key_not_found":          "API anahtarı bulunamadı",
+	"api_key_revoke_failed":      "API anahtarı iptal edilemedi",
+	"api_key_revoked":            "API anahtarı iptal edildi",
+	"api_key_hospital_scope":     "API anahtarları yalnızca kendi hastanelerinin kullanıcılarını yönetebilir",
+	"admin_management_forbidden": "Yöneticileri yönetmek için users:admin yetkisi gerekir",
+
+	// Single sign-on
+	"sso_not_configured":       "Bu hastane için tek oturum açma ayarlanmamış",
+	"sso_config_fetch_failed":  "Tek oturum açma ayarları yüklenemedi",
+	"sso_config_update_failed": "Tek oturum açma ayarları kaydedilemedi",
+	"sso_config_delete_failed": "Tek oturum açma ayarları kaldırılamadı",
+	"sso_config_deleted":       "Tek oturum açma ayarları kaldırıldı",
+	"sso_unavailable":          "Kimlik sağlayıcıya ulaşılamıyor",
+	"sso_state_invalid":        "Oturum açma isteği bilinmiyor veya süresi dolmuş; lütfen baştan başlayın",
+	"sso_failed":               "Kimlik sağlayıcı ile oturum açılamadı",
+	"sso_email_missing":        "Kimlik sağlayıcı doğrulanmış bir e-posta adresi paylaşmadı",
+	"sso_no_role":              "Kimlik sağlayıcı hesabınız burada bir role eşlenmemiş",
+	"sso_account_not_found":    "Kimliğinizle eşleşen bir hesap yok; hastane yöneticinizden sizi eklemesini isteyin",
+	"sso_invitation_pending":   "Oturum açmadan önce davetinizi kabul edin",
+
+	// Directory
+	"ldap_not_configured":       "Bu hastane için LDAP dizini ayarlanmamış",
+	"ldap_config_fetch_failed":  "Dizin ayarları yüklenemedi",
+	"ldap_config_update_failed": "Dizin ayarları kaydedilemedi",
+	"ldap_config_delete_failed": "Dizin ayarları kaldırılamadı",
+	"ldap_config_deleted":       "Dizin ayarları kaldırıldı",
+	"directory_no_role":         "Dizin hesabınız burada bir role eşlenmemiş",
+	"directory_unavailable":     "Hastanenizin dizinine ulaşılamıyor",
+	"login_failed":              "Giriş yapılamadı",
+
+	// Registration
+	"verification_required":      "Önce kaydınızdaki e-posta adreslerini ve telefon numaralarını doğrulayın",
+	"registration_not_found":     "Doğrulanmamış bir kayıt bulunamadı",
+	"registration_fetch_failed":  "Kayıt yüklenemedi",
+	"registration_update_failed": "Doğrulama kaydedilemedi",
+	"invalid_link":               "Doğrulama bağlantısı geçersiz veya süresi dolmuş",
+	"contact_already_verified":   "Bu iletişim bilgisi zaten doğrulanmış",
+	"contact_verified":           "İletişim bilgisi doğrulandı",
+	"registration_completed":     "Kayıt doğrulandı; hastaneniz artık aktif",
+	"verification_send_failed":   "Doğrulama gönderilemedi",
+	"verification_sent":          "Doğrulama gönderildi",
+	"registration_email_subject": "{hospital} için e-posta adresinizi doğrulayın",
+	"registration_email_body":    "Merhaba {name}, {hospital} kaydı için bu e-posta adresini {hours} saat içinde doğrulayın: {link}",
+	"registration_code_body":     "Merhaba {name}, {hospital} kaydı için bu telefon numarasını doğrulama kodunuz {code}. Kod {minutes} dakika içinde geçersiz olur.",
+
+	// SMS login
+	"sms_login_config_fetch_failed":  "SMS ile giriş ayarları yüklenemedi",
+	"sms_login_config_update_failed": "SMS ile giriş ayarları kaydedilemedi",
+	"sms_login_code_failed":          "Giriş kodu gönderilemedi",
+	"sms_login_code_sent":            "Telefon girişte kullanılabiliyorsa bir kod gönderildi",
+	"sms_login_cooldown":             "Bu telefona az önce bir kod gönderildi; yenisini istemeden önce bekleyin",
+	"sms_login_code_body":            "Merhaba {name}, giriş kodunuz {code}. Kod {minutes} dakika içinde geçersiz olur. Kodu kimseyle paylaşmayın; siz istemediyseniz bu mesajı dikkate almayın.",
+
+	// Profile
+	"profile_updated":           "Profil başarıyla güncellendi",
+	"contact_verification_sent": "Yeni adrese bir doğrulama kodu gönderildi; değişiklik kod onaylandığında uygulanır",
+	"email_taken":               "Bu e-posta adresi zaten kullanılıyor",
+	"phone_taken":               "Bu telefon numarası zaten kullanılıyor",
+	"contact_code_failed":       "Doğrulama kodu gönderilemedi",
+	"contact_verify_failed":     "Kod doğrulanamadı",
+	"too_many_attempts":         "Çok fazla hatalı kod girildi; yeni bir kod isteyin",
+	"wrong_current_password":    "Mevcut şifre hatalı",
+	"email_changed":             "E-posta adresi başarıyla değiştirildi",
+	"phone_changed":             "Telefon numarası başarıyla değiştirildi",
+	"contact_code_subject":      "Doğrulama kodunuz",
+	"contact_code_body":         "Merhaba {name}, doğrulama kodunuz {code}. Kod {minutes} dakika içinde geçersiz olur. Bu kodu siz istemediyseniz bu mesajı dikkate almayın.",
+	"email_changed_subject":     "E-posta adresiniz değiştirildi",
+	"email_changed_body":        "Merhaba {name}, hesabınızın e-posta adresi {email} olarak değiştirildi. Bu işlemi siz yapmadıysanız hemen yöneticinizle iletişime geçin.",
+
+	// Hospitals
+	"address_create_failed":  "Adres oluşturulamadı",
+	"hospital_create_failed": "Hastane oluşturulamadı",
+	"admin_create_failed":    "Yönetici kullanıcı oluşturulamadı",
+	"hospital_admin_created": "Hastane ve yönetici oluşturuldu",
+	"hospitals_fetch_failed": "Hastaneler getirilemedi",
+
+	// Users
+	"invalid_user_id":        "Geçersiz kullanıcı kimliği",
+	"user_not_found":         "Kullanıcı bulunamadı",
+	"hospital_scope":         "Yalnızca kendi hastanenizin kullanıcılarını yönetebilirsiniz",
+	"user_create_failed":     "Kullanıcı oluşturulamadı",
+	"user_created":           "Kullanıcı başarıyla oluşturuldu",
+	"users_fetch_failed":     "Kullanıcılar getirilemedi",
+	"user_update_failed":     "Kullanıcı güncellenemedi",
+	"user_updated":           "Kullanıcı başarıyla güncellendi",
+	"if_match_required":      "Kullanıcının ETag değeriyle If-Match başlığı gönderilmelidir",
+	"version_mismatch":       "Kullanıcı başka biri tarafından değiştirildi; yenileyip tekrar deneyin",
+	"user_delete_failed":     "Kullanıcı silinemedi",
+	"user_deleted":           "Kullanıcı başarıyla silindi",
+	"deleted_user_not_found": "Silinmiş kullanıcı bulunamadı",
+	"user_restore_failed":    "Kullanıcı geri yüklenemedi",
+	"user_restored":          "Kullanıcı başarıyla geri yüklendi",
+	"user_still_retained":    "Kullanıcı hâlâ saklama süresi içinde",
+	"user_purge_failed":      "Kullanıcı kalıcı olarak silinemedi",
+	"user_purged":            "Kullanıcı kalıcı olarak silindi",
+
+	// Invitations
+	"user_invited":             "Kullanıcı oluşturuldu ve davet gönderildi",
+	"invitation_create_failed": "Davet oluşturulamadı",
+	"invitation_not_found":     "Davet bulunamadı",
+	"invitation_expired":       "Davetin süresi doldu; yöneticinizden yeniden göndermesini isteyin",
+	"invitation_revoked":       "Davet iptal edildi",
+	"invitation_accepted":      "Davet zaten kabul edildi",
+	"invitation_fetch_failed":  "Davet getirilemedi",
+	"invitation_accept_failed": "Davet kabul edilemedi",
+	"invitation_accepted_ok":   "Davet kabul edildi, artık giriş yapabilirsiniz",
+	"terms_not_accepted":       "Kullanım koşulları kabul edilmelidir",
+	"user_not_pending":         "Kullanıcı davetini zaten kabul etti",
+	"invitation_resent":        "Davet yeniden gönderildi",
+	"invitation_revoke_failed": "Davet iptal edilemedi",
+	"invitation_revoked_ok":    "Davet iptal edildi",
+	"invite_subject":           "{hospital} sizi davet ediyor",
+	"invite_body":              "Merhaba {name}, {hospital} ekibine davet edildiniz. {expires} tarihine kadar şifrenizi belirleyip kullanım koşullarını kabul edin: {link}",
+
+	// User import
+	"import_file_required":      "file alanında bir CSV veya XLSX dosyası gönderilmelidir",
+	"import_file_too_large":     "İçe aktarma dosyası çok büyük",
+	"import_unsupported_format": "Yalnızca .csv ve .xlsx dosyaları desteklenir",
+	"import_file_invalid":       "İçe aktarma dosyası okunamadı",
+	"import_file_empty":         "İçe aktarma dosyasında satır yok",
+	"import_too_many_rows":      "İçe aktarma dosyasında çok fazla satır var",
+	"invalid_import_mode":       "mode atomic veya batch olmalıdır",
+	"invalid_batch_size":        "batch_size 1 ile 1000 arasında olmalıdır",
+	"import_failed":             "İçe aktarma başarısız oldu",
+	"import_job_not_found":      "İçe aktarma işi bulunamadı",
+	"import_job_fetch_failed":   "İçe aktarma işi getirilemedi",
+
+	// Exports
+	"unsupported_export_format": "Desteklenmeyen dışa aktarma biçimi",
+	"export_failed":             "Dışa aktarma başarısız oldu",
+
+	// Roster PDF
+	"roster_title":     "Personel listesi",
+	"roster_generated": "Oluşturulma",
+	"roster_name":      "Ad",
+	"roster_email":     "E-posta",
+	"roster_empty":     "Bu bölümde doktor yok",
+	"roster_page":      "Sayfa",
+
+	// Departments
+	"department_type_not_found": "Bölüm türü bulunamadı",
+	"department_create_failed":  "Bölüm oluşturulamadı",
+	"department_created":        "Bölüm başarıyla oluşturuldu",
+	"departments_fetch_failed":  "Bölümler getirilemedi",
+	"doctors_fetch_failed":      "Doktorlar getirilemedi",
+
+	// Audit log
+	"invalid_time_filter":     "from ve to RFC 3339 biçiminde zaman olmalıdır",
+	"audit_logs_fetch_failed": "Denetim kayıtları getirilemedi",
+	"audit_verify_failed":     "Denetim kayıtları doğrulanamadı",
+
+	// Privacy
+	"invalid_doctor_id":          "Geçersiz doktor kimliği",
+	"doctor_not_found":           "Doktor bulunamadı",
+	"invalid_export_format":      "format json veya zip olmalıdır",
+	"subject_export_failed":      "Kişisel veriler dışa aktarılamadı",
+	"subject_erase_failed":       "Kişisel veriler silinemedi",
+	"subject_already_anonymized": "Kişisel veriler zaten silinmiş",
+	"subject_erased":             "Kişisel veriler başarıyla silindi",
+
+	// Search
+	"search_query_required": "q en az bir harf veya rakam içermelidir",
+	"invalid_search_type":   "type user, doctor veya ikisi birden olmalıdır",
+	"search_failed":         "Arama yapılamadı",
+
+	// Reference data
+	"profession_groups_fetch_failed": "Meslek grupları getirilemedi",
+	"cities_fetch_failed":            "Şehirler getirilemedi",
+}
@@ -0,0 +1,273 @@
+package importer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/invite"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/notify"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// Job statuses.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Commit modes.
+const (
+	// ModeAtomic imports all rows in one transaction and nothing at all if
+	// any row is invalid.
+	ModeAtomic = "atomic"
+	// ModeBatch imports the valid rows in batches, each in its own
+	// transaction, and skips invalid ones.
+	ModeBatch = "batch"
+)
+
+// jobTTL is how long job status stays available after the last update.
+const jobTTL = 24 * time.Hour
+
+var ErrJobNotFound = errors.New("importer: job not found")
+
+// Job tracks one import. It is stored in Redis so its status can be polled
+// while it runs in the background.
+type Job struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	HospitalID uint   `json:"hospital_id"`
+	CreatedBy  uint   `json:"created_by"`
+	// APIKeyID is the key the import was started with, if any.
+	APIKeyID uint `json:"api_key_id,omitempty"`
+	// ImpersonatorID, IP and RequestID describe the request that started
+	// the import, for the audit log.
+	ImpersonatorID uint   `json:"-"`
+	IP             string `json:"-"`
+	RequestID      string `json:"-"`
+	DryRun         bool   `json:"dry_run"`
+	Mode           string `json:"mode"`
+	BatchSize      int    `json:"batch_size"`
+	Language       string `json:"-"`
+	// AllowAdmins lets rows create admins. Only callers that may manage
+	// admins set it.
+	AllowAdmins bool       `json:"-"`
+	Total       int        `json:"total"`
+	Valid       int        `json:"valid"`
+	Imported    int        `json:"imported"`
+	Failed      int        `json:"failed"`
+	Errors      []RowError `json:"errors"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// NewJob creates and stores a pending job.
+func NewJob(ctx context.Context, job Job) (*Job, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	job.ID = hex.EncodeToString(id)
+	job.Status = StatusPending
+	job.Errors = []RowError{}
+	job.CreatedAt = time.Now().UTC()
+	if job.Mode == "" {
+		job.Mode = ModeAtomic
+	}
+	if job.BatchSize <= 0 {
+		job.BatchSize = 100
+	}
+	return &job, save(ctx, &job)
+}
+
+func jobKey(id string) string {
+	return "import_job:" + id
+}
+
+func save(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return config.REDIS.Set(ctx, jobKey(job.ID), string(data), jobTTL).Err()
+}
+
+// LoadJob returns the job with the given ID.
+func LoadJob(ctx context.Context, id string) (*Job, error) {
+	data, err := config.REDIS.Get(ctx, jobKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Run validates records and, unless the job is a dry run, creates the users.
+// It keeps the stored job up to date and can run in its own goroutine.
+func Run(ctx context.Context, db *gorm.DB, job *Job, records []Record) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ Import job %s panicked: %v", job.ID, r)
+			finish(ctx, job, StatusFailed)
+		}
+	}()
+
+	job.Status = StatusRunning
+	job.Total = len(records)
+	if err := save(ctx, job); err != nil {
+		log.Printf("⚠️ Could not update import job %s: %v", job.ID, err)
+	}
+
+	rows, errs, err := validate(db, records, job.Language, job.AllowAdmins)
+	if err != nil {
+		log.Printf("❌ Import job %s: validation failed: %v", job.ID, err)
+		job.Errors = append(job.Errors, rowErrors(0, apperrors.Internal(err, "import_failed", "Import failed"), job.Language)...)
+		finish(ctx, job, StatusFailed)
+		return
+	}
+	job.Valid = len(rows)
+	job.Failed = job.Total - job.Valid
+	job.Errors = append(job.Errors, errs...)
+
+	if job.DryRun || len(rows) == 0 || (job.Mode == ModeAtomic && len(errs) > 0) {
+		finish(ctx, job, StatusCompleted)
+		return
+	}
+
+	batchSize := job.BatchSize
+	if job.Mode == ModeAtomic {
+		batchSize = len(rows)
+	}
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[start:end]
+
+		if err := importBatch(ctx, db, job, batch); err != nil {
+			job.Failed += len(batch)
+			job.Errors = append(job.Errors, err...)
+		} else {
+			job.Imported += len(batch)
+		}
+		if err := save(ctx, job); err != nil {
+			log.Printf("⚠️ Could not update import job %s: %v", job.ID, err)
+		}
+	}
+
+	status := StatusCompleted
+	if job.Imported == 0 {
+		status = StatusFailed
+	}
+	finish(ctx, job, status)
+}
+
+// importBatch creates the users of one batch as pending users with an
+// invitation each, in a transaction. On failure the whole batch is rolled back
+// and the row that failed is reported. Invitations go out once the batch is
+// committed.
+func importBatch(ctx context.Context, db *gorm.DB, job *Job, batch []UserRow) []RowError {
+	type invitation struct {
+		user  models.User
+		inv   models.Invitation
+		token string
+	}
+	var invitations []invitation
+
+	failedLine := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var hospital models.Hospital
+		if err := tx.First(&hospital, job.HospitalID).Error; err != nil {
+			return apperrors.Internal(err, "user_create_failed", "Failed to create user")
+		}
+		for _, row := range batch {
+			failedLine = row.Line
+			user := newUser(job.HospitalID, row)
+			if err := tx.Create(&user).Error; err != nil {
+				return apperrors.FromDB(err, "user_create_failed", "Failed to create user")
+			}
+			inv, token, err := invite.Create(tx, user, notify.ChannelEmail, job.CreatedBy)
+			if err != nil {
+				return apperrors.Internal(err, "invitation_create_failed", "Failed to create invitation")
+			}
+			err = audit.RecordDetached(tx, audit.Entry{
+				Action:         "user.import",
+				EntityType:     "user",
+				EntityID:       user.ID,
+				After:          user,
+				ActorID:        job.CreatedBy,
+				HospitalID:     job.HospitalID,
+				ImpersonatorID: job.ImpersonatorID,
+				APIKeyID:       job.APIKeyID,
+			}, job.IP, job.RequestID)
+			if err != nil {
+				return apperrors.Internal(err, "user_create_failed", "Failed to create user")
+			}
+			user.Hospital = hospital
+			invitations = append(invitations, invitation{user: user, inv: inv, token: token})
+		}
+		return nil
+	})
+	if err == nil {
+		for _, i := range invitations {
+			if err := invite.Send(ctx, i.user, i.inv, i.token); err != nil {
+				log.Printf("⚠️ Import job %s: could not deliver invitation %d: %v", job.ID, i.inv.ID, err)
+			}
+		}
+		return nil
+	}
+
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) {
+		appErr = apperrors.Internal(err, "user_create_failed", "Failed to create user")
+	}
+	if appErr.Kind == apperrors.KindInternal {
+		log.Printf("❌ Import job %s: row %d: %v", job.ID, failedLine, err)
+	}
+	return rowErrors(failedLine, appErr, job.Language)
+}
+
+// newUser builds the pending user for row. Imported users set their own
+// password when they accept their invitation.
+func newUser(hospitalID uint, row UserRow) models.User {
+	return models.User{
+		Name:              row.Name,
+		Surname:           row.Surname,
+		TCKN:              row.TCKN,
+		Email:             row.Email,
+		Phone:             row.Phone,
+		Role:              row.Role,
+		Status:            models.UserStatusPending,
+		Language:          row.Language,
+		HospitalID:        hospitalID,
+		ProfessionGroupID: row.ProfessionGroupID,
+		TitleID:           row.TitleID,
+	}
+}
+
+func finish(ctx context.Context, job *Job, status string) {
+	now := time.Now().UTC()
+	job.Status = status
+	job.FinishedAt = &now
+	if err := save(ctx, job); err != nil {
+		log.Printf("⚠️ Could not update import job %s: %v", job.ID, err)
+	}
+}
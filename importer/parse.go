@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Record is one data row of an uploaded file, keyed by lower-cased header.
+// Line is the 1-based row number in the file, counting the header.
+type Record struct {
+	Line   int
+	Values map[string]string
+}
+
+var ErrUnsupportedFormat = errors.New("importer: only .csv and .xlsx files are supported")
+
+// Parse reads the records of a CSV or XLSX file, picked by the extension of
+// filename. The first row must be a header; blank rows are skipped.
+func Parse(filename string, r io.Reader) ([]Record, error) {
+	var rows [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		rows, err = readCSV(r)
+	case ".xlsx":
+		rows, err = readXLSX(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	header := make([]string, len(rows[0]))
+	for i, h := range rows[0] {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	}
+
+	var records []Record
+	for i, row := range rows[1:] {
+		values := map[string]string{}
+		blank := true
+		for j, cell := range row {
+			if j >= len(header) || header[j] == "" {
+				continue
+			}
+			cell = strings.TrimSpace(cell)
+			if cell != "" {
+				blank = false
+			}
+			values[header[j]] = cell
+		}
+		if blank {
+			continue
+		}
+		records = append(records, Record{Line: i + 2, Values: values})
+	}
+	return records, nil
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("importer: read csv: %w", err)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	// Excel in Turkish locales saves CSV with semicolons; sniff the header.
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("importer: read csv: %w", err)
+	}
+	return rows, nil
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("importer: open xlsx: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, nil
+	}
+	rows, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("importer: read xlsx: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,234 @@
+package importer
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/encryption"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/utils"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// UserRow is a user as read from one record. The json names double as the
+// expected column headers.
+type UserRow struct {
+	Line              int    `json:"-"`
+	Name              string `json:"name" binding:"required"`
+	Surname           string `json:"surname" binding:"required"`
+	TCKN              string `json:"tckn" binding:"required,tckn"`
+	Email             string `json:"email" binding:"required,email"`
+	Phone             string `json:"phone" binding:"required,tr_phone"`
+	Role              string `json:"role" binding:"required,oneof=admin user"`
+	Language          string `json:"language" binding:"omitempty,oneof=tr en"`
+	ProfessionGroupID uint   `json:"profession_group_id" binding:"required"`
+	TitleID           uint   `json:"title_id" binding:"required"`
+}
+
+// Columns lists the headers an import file may use.
+func Columns() []string {
+	var columns []string
+	t := reflect.TypeOf(UserRow{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("json"); name != "-" {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// RowError is a problem with one row. Row 0 means the whole import.
+type RowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func rowErrors(line int, err *apperrors.Error, lang string) []RowError {
+	fields := err.LocalizedFields(lang)
+	if len(fields) == 0 {
+		message, ok := i18n.Lookup(lang, err.Code)
+		if !ok {
+			message = err.Message
+		}
+		return []RowError{{Row: line, Code: err.Code, Message: message}}
+	}
+	var errs []RowError
+	for _, f := range fields {
+		errs = append(errs, RowError{Row: line, Field: f.Field, Code: f.Code, Message: f.Message})
+	}
+	return errs
+}
+
+// decode turns a record into a UserRow, reporting cells that aren't numbers
+// where numbers are expected.
+func decode(rec Record, lang string) (UserRow, []RowError) {
+	row := UserRow{
+		Line:     rec.Line,
+		Name:     rec.Values["name"],
+		Surname:  rec.Values["surname"],
+		TCKN:     rec.Values["tckn"],
+		Email:    rec.Values["email"],
+		Phone:    rec.Values["phone"],
+		Role:     strings.ToLower(rec.Values["role"]),
+		Language: strings.ToLower(rec.Values["language"]),
+	}
+
+	var errs []RowError
+	for column, dst := range map[string]*uint{
+		"profession_group_id": &row.ProfessionGroupID,
+		"title_id":            &row.TitleID,
+	} {
+		value := rec.Values[column]
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			errs = append(errs, rowErrors(rec.Line, apperrors.InvalidField(column, "type", "field_number"), lang)...)
+			continue
+		}
+		*dst = uint(n)
+	}
+	return row, errs
+}
+
+// validate checks every record and returns the rows that can be imported
+// together with the errors of the rest. Besides field rules it catches
+// duplicates within the file, values already used by active users and
+// unknown or mismatched profession groups and titles. Admin rows are refused
+// unless allowAdmins is set.
+func validate(db *gorm.DB, records []Record, lang string, allowAdmins bool) ([]UserRow, []RowError, error) {
+	var rows []UserRow
+	var errs []RowError
+	for _, rec := range records {
+		row, decodeErrs := decode(rec, lang)
+		if len(decodeErrs) > 0 {
+			errs = append(errs, decodeErrs...)
+			continue
+		}
+		if err := binding.Validator.ValidateStruct(&row); err != nil {
+			errs = append(errs, rowErrors(row.Line, apperrors.Validation(err), lang)...)
+			continue
+		}
+		row.Phone = utils.NormalizePhoneOrKeep(row.Phone)
+		rows = append(rows, row)
+	}
+
+	titles, err := loadTitles(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	taken, err := takenValues(db, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := map[string]bool{}
+	var valid []UserRow
+	for _, row := range rows {
+		var problems []RowError
+		for field, value := range map[string]string{"email": strings.ToLower(row.Email), "tckn": row.TCKN, "phone": row.Phone} {
+			key := field + ":" + value
+			if seen[key] {
+				problems = append(problems, rowErrors(row.Line, apperrors.InvalidField(field, "duplicate", "field_duplicate_row"), lang)...)
+				continue
+			}
+			seen[key] = true
+			if taken[key] {
+				problems = append(problems, rowErrors(row.Line, apperrors.InvalidField(field, "unique", "field_unique"), lang)...)
+			}
+		}
+
+		if row.Role == models.RoleAdmin && !allowAdmins {
+			problems = append(problems, rowErrors(row.Line, apperrors.InvalidField("role", "admin", "field_admin_role"), lang)...)
+		}
+
+		groupID, ok := titles[row.TitleID]
+		switch {
+		case !ok:
+			problems = append(problems, rowErrors(row.Line, apperrors.InvalidField("title_id", "reference", "field_reference"), lang)...)
+		case groupID != row.ProfessionGroupID:
+			problems = append(problems, rowErrors(row.Line, apperrors.InvalidField("title_id", "profession_group", "field_title_group"), lang)...)
+		}
+
+		if len(problems) > 0 {
+			errs = append(errs, problems...)
+			continue
+		}
+		valid = append(valid, row)
+	}
+	return valid, errs, nil
+}
+
+// loadTitles maps every title ID to its profession group ID. Profession
+// groups without titles can't be assigned, so titles are all that's needed.
+func loadTitles(db *gorm.DB) (map[uint]uint, error) {
+	var titles []models.Title
+	if err := db.Select("id", "profession_group_id").Find(&titles).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]uint, len(titles))
+	for _, t := range titles {
+		byID[t.ID] = t.ProfessionGroupID
+	}
+	return byID, nil
+}
+
+// takenValues returns the emails, TCKNs and phones of rows that active users
+// already have, keyed like "email:<value>".
+func takenValues(db *gorm.DB, rows []UserRow) (map[string]bool, error) {
+	taken := map[string]bool{}
+	const chunk = 500
+	for start := 0; start < len(rows); start += chunk {
+		end := start + chunk
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		var emails, tcknIndexes, phoneIndexes []string
+		lookup := map[string]string{}
+		for _, row := range rows[start:end] {
+			emails = append(emails, row.Email)
+			if idx := encryption.BlindIndex("tckn", row.TCKN); idx != nil {
+				tcknIndexes = append(tcknIndexes, *idx)
+				lookup["tckn:"+*idx] = "tckn:" + row.TCKN
+			}
+			if idx := encryption.BlindIndex("phone", row.Phone); idx != nil {
+				phoneIndexes = append(phoneIndexes, *idx)
+				lookup["phone:"+*idx] = "phone:" + row.Phone
+			}
+		}
+
+		var existing []struct {
+			Email      string
+			TCKNIndex  *string
+			PhoneIndex *string
+		}
+		err := db.Model(&models.User{}).Select("email", "tckn_index", "phone_index").
+			Where("email IN ? OR tckn_index IN ? OR phone_index IN ?", emails, tcknIndexes, phoneIndexes).
+			Find(&existing).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range existing {
+			taken["email:"+strings.ToLower(u.Email)] = true
+			if u.TCKNIndex != nil {
+				if key, ok := lookup["tckn:"+*u.TCKNIndex]; ok {
+					taken[key] = true
+				}
+			}
+			if u.PhoneIndex != nil {
+				if key, ok := lookup["phone:"+*u.PhoneIndex]; ok {
+					taken[key] = true
+				}
+			}
+		}
+	}
+	return taken, nil
+}
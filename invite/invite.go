@@ -0,0 +1,158 @@
+package invite
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/notify"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotFound = errors.New("invite: invitation not found")
+	ErrExpired  = errors.New("invite: invitation expired")
+	ErrRevoked  = errors.New("invite: invitation revoked")
+	ErrAccepted = errors.New("invite: invitation already accepted")
+)
+
+// TTL is how long an invitation token stays valid after it was sent.
+func TTL() time.Duration {
+	ttl, err := time.ParseDuration(config.GetEnv("INVITE_TTL", "72h"))
+	if err != nil || ttl <= 0 {
+		return 72 * time.Hour
+	}
+	return ttl
+}
+
+// TermsVersion is the version of the terms invitees accept.
+func TermsVersion() string {
+	return config.GetEnv("TERMS_VERSION", "1")
+}
+
+func newToken() (token, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create stores an invitation for the pending user u and returns the token to
+// deliver with Send.
+func Create(tx *gorm.DB, u models.User, channel string, invitedBy uint) (models.Invitation, string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return models.Invitation{}, "", err
+	}
+	now := time.Now().UTC()
+	inv := models.Invitation{
+		UserID:      u.ID,
+		HospitalID:  u.HospitalID,
+		InvitedByID: invitedBy,
+		TokenHash:   hash,
+		Channel:     channel,
+		ExpiresAt:   now.Add(TTL()),
+		SentAt:      now,
+		SendCount:   1,
+	}
+	if err := tx.Create(&inv).Error; err != nil {
+		return models.Invitation{}, "", err
+	}
+	return inv, token, nil
+}
+
+// Renew replaces the token of inv, restarts its expiry and lifts a
+// revocation, invalidating the previously sent token. channel may switch the
+// delivery channel; empty keeps the current one.
+func Renew(tx *gorm.DB, inv *models.Invitation, channel string) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	inv.TokenHash = hash
+	inv.ExpiresAt = now.Add(TTL())
+	inv.SentAt = now
+	inv.SendCount++
+	inv.RevokedAt = nil
+	if channel != "" {
+		inv.Channel = channel
+	}
+	err = tx.Model(inv).
+		Select("token_hash", "expires_at", "sent_at", "send_count", "revoked_at", "channel").
+		Updates(inv).Error
+	return token, err
+}
+
+// Lookup returns the open invitation for token and its pending user.
+func Lookup(db *gorm.DB, token string) (models.Invitation, models.User, error) {
+	var inv models.Invitation
+	var user models.User
+	if token == "" {
+		return inv, user, ErrNotFound
+	}
+	err := db.Where("token_hash = ?", hashToken(token)).First(&inv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return inv, user, ErrNotFound
+	}
+	if err != nil {
+		return inv, user, err
+	}
+
+	switch {
+	case inv.AcceptedAt != nil:
+		return inv, user, ErrAccepted
+	case inv.RevokedAt != nil:
+		return inv, user, ErrRevoked
+	case time.Now().After(inv.ExpiresAt):
+		return inv, user, ErrExpired
+	}
+
+	// Deleted or erased users can't accept an invitation they got earlier.
+	err = db.Where("status = ? AND anonymized_at IS NULL", models.UserStatusPending).
+		Preload("Hospital").First(&user, inv.UserID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return inv, user, ErrNotFound
+	}
+	return inv, user, err
+}
+
+// Send delivers token to u over the invitation's channel, in u's language.
+// u.Hospital must be loaded; its name appears in the message.
+func Send(ctx context.Context, u models.User, inv models.Invitation, token string) error {
+	lang := u.Language
+	if !i18n.IsSupported(lang) {
+		lang = i18n.Default()
+	}
+	params := map[string]string{
+		"name":     u.Name,
+		"hospital": u.Hospital.Name,
+		"link":     config.GetEnv("INVITE_URL", "http://localhost:3000/invite?token=") + token,
+		"expires":  inv.ExpiresAt.Format("2006-01-02 15:04 MST"),
+	}
+	msg := notify.Message{
+		Channel: inv.Channel,
+		Subject: i18n.Message(lang, "invite_subject", params),
+		Body:    i18n.Message(lang, "invite_body", params),
+	}
+	if inv.Channel == notify.ChannelSMS {
+		msg.To = u.Phone
+	} else {
+		msg.To = u.Email
+	}
+	return notify.Send(ctx, msg)
+}
@@ -0,0 +1,400 @@
+// Package listquery parses and applies the common query parameters of
+// collection endpoints: sort, page_size, page, cursor and fields. Results are
+// written in one envelope with the total, the next cursor and links.
+package listquery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Spec describes what a collection endpoint supports.
+type Spec struct {
+	// Model is the GORM model listed, e.g. &models.User{}.
+	Model interface{}
+	// Sortable maps names accepted in sort to columns of Model's table.
+	Sortable map[string]string
+	// DefaultSort is used when the request has no sort, e.g. "-created_at".
+	DefaultSort string
+	// DefaultPageSize and MaxPageSize default to 20 and 100.
+	DefaultPageSize int
+	MaxPageSize     int
+	// Fields lists the response fields that can be picked with fields.
+	// See FieldsOf.
+	Fields []string
+}
+
+// SortField is one column of the sort order.
+type SortField struct {
+	Name   string
+	Column string
+	Desc   bool
+}
+
+// Query is a parsed list request.
+type Query struct {
+	spec     Spec
+	schema   *schema.Schema
+	Sort     []SortField
+	PageSize int
+	// Page is only used when there is no cursor.
+	Page   int
+	Fields []string
+	cursor []interface{}
+}
+
+// Page is the envelope every collection endpoint responds with.
+type Page struct {
+	Data       interface{} `json:"data"`
+	Total      int64       `json:"total"`
+	PageSize   int         `json:"page_size"`
+	Page       int         `json:"page,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Links      Links       `json:"links"`
+}
+
+// Links point at the current and adjacent pages.
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// Result is what Find learned about the listed rows.
+type Result struct {
+	Total      int64
+	NextCursor string
+}
+
+var schemaCache sync.Map
+
+// Parse reads the list parameters of the request. Unsupported sort or field
+// names and malformed cursors are reported as 400s; page_size is capped.
+func Parse(c *gin.Context, spec Spec) (*Query, error) {
+	if spec.DefaultPageSize == 0 {
+		spec.DefaultPageSize = 20
+	}
+	if spec.MaxPageSize == 0 {
+		spec.MaxPageSize = 100
+	}
+	s, err := schema.Parse(spec.Model, &schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, apperrors.Internal(err, "internal_error", "Internal server error")
+	}
+	q := &Query{spec: spec, schema: s, Page: 1}
+
+	q.PageSize, err = strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(spec.DefaultPageSize)))
+	if err != nil || q.PageSize < 1 {
+		q.PageSize = spec.DefaultPageSize
+	}
+	if q.PageSize > spec.MaxPageSize {
+		q.PageSize = spec.MaxPageSize
+	}
+	if page, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil && page > 1 {
+		q.Page = page
+	}
+
+	if err := q.parseSort(c.DefaultQuery("sort", spec.DefaultSort)); err != nil {
+		return nil, err
+	}
+	if err := q.parseFields(c.Query("fields")); err != nil {
+		return nil, err
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		if err := q.decodeCursor(cursor); err != nil {
+			return nil, apperrors.BadRequest("invalid_cursor", "cursor is invalid or doesn't match the sort")
+		}
+		q.Page = 0
+	}
+	return q, nil
+}
+
+func (q *Query) parseSort(param string) error {
+	hasID := false
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		column, ok := q.spec.Sortable[name]
+		if !ok || q.schema.LookUpField(column) == nil {
+			return apperrors.BadRequest("invalid_sort", "sort uses a field that can't be sorted by")
+		}
+		q.Sort = append(q.Sort, SortField{Name: name, Column: column, Desc: desc})
+		hasID = hasID || column == "id"
+	}
+	// The primary key makes the order total, which cursors rely on.
+	if !hasID {
+		q.Sort = append(q.Sort, SortField{Name: "id", Column: "id"})
+	}
+	return nil
+}
+
+func (q *Query) parseFields(param string) error {
+	if param == "" {
+		return nil
+	}
+	allowed := map[string]bool{}
+	for _, f := range q.spec.Fields {
+		allowed[f] = true
+	}
+	for _, f := range strings.Split(param, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !allowed[f] {
+			return apperrors.BadRequest("invalid_fields", "fields lists a field this endpoint doesn't return")
+		}
+		q.Fields = append(q.Fields, f)
+	}
+	return nil
+}
+
+// sortKey identifies the sort order a cursor was made for.
+func (q *Query) sortKey() string {
+	parts := make([]string, len(q.Sort))
+	for i, s := range q.Sort {
+		parts[i] = s.Column
+		if s.Desc {
+			parts[i] = "-" + parts[i]
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Key identifies the page q selects, e.g. to cache it. Fields aren't part of
+// it since Respond picks them from the full rows.
+func (q *Query) Key() string {
+	cursor, _ := json.Marshal(q.cursor)
+	return fmt.Sprintf("%s:%d:%d:%s", q.sortKey(), q.PageSize, q.Page, cursor)
+}
+
+type cursorPayload struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// decodeCursor restores the sort values of the last row of the previous
+// page, typed like the model fields so they bind as proper parameters.
+func (q *Query) decodeCursor(cursor string) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return err
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return err
+	}
+	if payload.Sort != q.sortKey() || len(payload.Values) != len(q.Sort) {
+		return fmt.Errorf("cursor was made for sort %q", payload.Sort)
+	}
+	for i, s := range q.Sort {
+		field := q.schema.LookUpField(s.Column)
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(payload.Values[i], value.Interface()); err != nil {
+			return err
+		}
+		q.cursor = append(q.cursor, value.Elem().Interface())
+	}
+	return nil
+}
+
+func (q *Query) encodeCursor(row reflect.Value) (string, error) {
+	payload := cursorPayload{Sort: q.sortKey()}
+	for _, s := range q.Sort {
+		value, _ := q.schema.LookUpField(s.Column).ValueOf(context.Background(), row)
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		payload.Values = append(payload.Values, data)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// apply adds the cursor condition, order and limit to db. One extra row is
+// requested to tell whether there is a next page.
+func (q *Query) apply(db *gorm.DB) *gorm.DB {
+	if q.cursor != nil {
+		db = db.Where(q.keyset())
+	} else if q.Page > 1 {
+		db = db.Offset((q.Page - 1) * q.PageSize)
+	}
+	for _, s := range q.Sort {
+		db = db.Order(clause.OrderByColumn{
+			Column: clause.Column{Table: q.schema.Table, Name: s.Column},
+			Desc:   s.Desc,
+		})
+	}
+	return db.Limit(q.PageSize + 1)
+}
+
+// keyset builds the condition selecting rows after the cursor:
+// (a > x) OR (a = x AND b > y) ..., with < for descending columns.
+func (q *Query) keyset() clause.Expr {
+	var ors []string
+	var args []interface{}
+	for i, s := range q.Sort {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, q.column(q.Sort[j])+" = ?")
+			args = append(args, q.cursor[j])
+		}
+		op := " > ?"
+		if s.Desc {
+			op = " < ?"
+		}
+		ands = append(ands, q.column(s)+op)
+		args = append(args, q.cursor[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return clause.Expr{SQL: "(" + strings.Join(ors, " OR ") + ")", Vars: args}
+}
+
+func (q *Query) column(s SortField) string {
+	return `"` + q.schema.Table + `"."` + s.Column + `"`
+}
+
+// Find counts the rows matched by db and loads one page of them into dest, a
+// pointer to a slice of the model. db should carry the endpoint's filters;
+// preloads are applied to the page query only.
+func (q *Query) Find(db *gorm.DB, dest interface{}, preloads ...string) (Result, error) {
+	var result Result
+	db = db.Session(&gorm.Session{})
+
+	if err := db.Model(q.spec.Model).Count(&result.Total).Error; err != nil {
+		return result, err
+	}
+
+	page := q.apply(db)
+	for _, p := range preloads {
+		page = page.Preload(p)
+	}
+	if err := page.Find(dest).Error; err != nil {
+		return result, err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() > q.PageSize {
+		rows.Set(rows.Slice(0, q.PageSize))
+		last := reflect.Indirect(rows.Index(q.PageSize - 1))
+		cursor, err := q.encodeCursor(last)
+		if err != nil {
+			return result, err
+		}
+		result.NextCursor = cursor
+	}
+	return result, nil
+}
+
+// Respond writes data, the already shaped rows of the page, in the list
+// envelope, keeping only the requested fields.
+func (q *Query) Respond(c *gin.Context, data interface{}, result Result) {
+	if reflect.ValueOf(data).Kind() == reflect.Slice && reflect.ValueOf(data).IsNil() {
+		data = []struct{}{}
+	}
+	if len(q.Fields) > 0 {
+		picked, err := pickFields(data, q.Fields)
+		if err != nil {
+			apperrors.Abort(c, apperrors.Internal(err, "internal_error", "Internal server error"))
+			return
+		}
+		data = picked
+	}
+
+	page := Page{
+		Data:       data,
+		Total:      result.Total,
+		PageSize:   q.PageSize,
+		Page:       q.Page,
+		NextCursor: result.NextCursor,
+		Links:      Links{Self: c.Request.URL.RequestURI()},
+	}
+	if result.NextCursor != "" {
+		page.Links.Next = withParams(c.Request.URL, map[string]string{"cursor": result.NextCursor, "page": ""})
+	}
+	if q.Page > 1 {
+		page.Links.Prev = withParams(c.Request.URL, map[string]string{"page": strconv.Itoa(q.Page - 1)})
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func withParams(u *url.URL, params map[string]string) string {
+	values := u.Query()
+	for k, v := range params {
+		if v == "" {
+			values.Del(k)
+		} else {
+			values.Set(k, v)
+		}
+	}
+	next := *u
+	next.RawQuery = values.Encode()
+	return next.RequestURI()
+}
+
+// pickFields keeps only fields (and always id) of every element of data.
+func pickFields(data interface{}, fields []string) ([]map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, err
+	}
+	keep := map[string]bool{"id": true, "ID": true}
+	for _, f := range fields {
+		keep[f] = true
+	}
+	for _, row := range rows {
+		for k := range row {
+			if !keep[k] {
+				delete(row, k)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// FieldsOf lists the JSON field names of a response struct, for Spec.Fields.
+func FieldsOf(v interface{}) []string {
+	var fields []string
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, FieldsOf(reflect.Zero(f.Type).Interface())...)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		if name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
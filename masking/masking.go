@@ -0,0 +1,92 @@
+package masking
+
+import (
+	"strings"
+
+	"github.com/efecan/vatansoft-case/permissions"
+	"github.com/gin-gonic/gin"
+)
+
+// Policy decides which personal fields a caller may see in full. Permissions
+// only apply to records of the caller's own hospital; everything else is
+// masked, except the caller's own record.
+type Policy struct {
+	userID     uint
+	hospitalID uint
+	nationalID bool
+	contact    bool
+}
+
+// For builds the policy of the authenticated caller.
+func For(c *gin.Context) Policy {
+	return Policy{
+		userID:     uint(c.GetInt("userID")),
+		hospitalID: uint(c.GetInt("hospitalID")),
+		nationalID: permissions.Granted(c, permissions.ViewNationalID),
+		contact:    permissions.Granted(c, permissions.ViewContact),
+	}
+}
+
+// Owner identifies whose data a value is, so a policy can tell whether the
+// caller's permissions cover it.
+type Owner struct {
+	UserID     uint
+	HospitalID uint
+}
+
+func (p Policy) reveals(granted bool, owner Owner) bool {
+	if owner.UserID != 0 && owner.UserID == p.userID {
+		return true
+	}
+	return granted && owner.HospitalID == p.hospitalID
+}
+
+// TCKN returns the national ID number, masked unless the caller may see it.
+func (p Policy) TCKN(owner Owner, tckn string) string {
+	if p.reveals(p.nationalID, owner) {
+		return tckn
+	}
+	return Middle(tckn, 4, 2)
+}
+
+// Phone returns the phone number, masked unless the caller may see it.
+func (p Policy) Phone(owner Owner, phone string) string {
+	if p.reveals(p.contact, owner) {
+		return phone
+	}
+	return Middle(phone, 4, 2)
+}
+
+// Email returns the email address, masked unless the caller may see it.
+func (p Policy) Email(owner Owner, email string) string {
+	if p.reveals(p.contact, owner) {
+		return email
+	}
+	return Email(email)
+}
+
+// Middle replaces everything but the first keepStart and last keepEnd
+// characters with asterisks, e.g. 12345678990 becomes 1234*****90. Values too
+// short to keep both ends are masked completely.
+func Middle(value string, keepStart, keepEnd int) string {
+	runes := []rune(value)
+	if len(runes) == 0 {
+		return ""
+	}
+	if len(runes) <= keepStart+keepEnd {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:keepStart]) +
+		strings.Repeat("*", len(runes)-keepStart-keepEnd) +
+		string(runes[len(runes)-keepEnd:])
+}
+
+// Email masks the local part of an address but keeps its first two
+// characters and the domain, e.g. ay***@example.com.
+func Email(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return Middle(email, 2, 0)
+	}
+	return Middle(local, 2, 0) + "@" + domain
+}
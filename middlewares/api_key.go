@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/efecan/vatansoft-case/apikey"
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/permissions"
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyHeader carries an API key for clients that can't set Authorization.
+const apiKeyHeader = "X-API-Key"
+
+// Authenticate accepts the bearer JWTs RequireAuth does and, in addition, API
+// keys that were granted every one of scopes. Keys may be sent as
+// "Authorization: Bearer vsk_..." or in the X-API-Key header. A key acts for
+// its hospital with no user behind it, so userID stays zero and apiKeyID is
+// set instead. Without scopes, API keys are refused.
+func Authenticate(scopes ...permissions.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(apiKeyHeader)
+		if key == "" {
+			if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && apikey.IsKey(bearer) {
+				key = bearer
+			}
+		}
+		if key == "" || len(scopes) == 0 {
+			RequireAuth(c)
+			return
+		}
+
+		k, err := apikey.Authenticate(config.DB, key, c.ClientIP())
+		switch {
+		case errors.Is(err, apikey.ErrIPNotAllowed):
+			apperrors.Abort(c, apperrors.Forbidden("api_key_ip_not_allowed", "This API key can't be used from your IP address"))
+			return
+		case errors.Is(err, apikey.ErrInvalid), errors.Is(err, apikey.ErrRevoked), errors.Is(err, apikey.ErrExpired):
+			apperrors.Abort(c, apperrors.Unauthorized("invalid_api_key", "Invalid, revoked or expired API key"))
+			return
+		case err != nil:
+			apperrors.Abort(c, apperrors.Internal(err, "api_key_check_failed", "Failed to check API key"))
+			return
+		}
+
+		c.Set("apiKeyID", int(k.ID))
+		c.Set("apiKeyScopes", k.Scopes)
+		c.Set("userName", k.Name)
+		c.Set("hospitalID", int(k.HospitalID))
+		for _, scope := range scopes {
+			if !permissions.Granted(c, scope) {
+				apperrors.Abort(c, apperrors.Forbidden("insufficient_scope", "The API key lacks a scope this endpoint requires"))
+				return
+			}
+		}
+		c.Set("apiKeyScopesChecked", true)
+
+		c.Next()
+	}
+}
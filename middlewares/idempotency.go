@@ -0,0 +1,147 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyHeader       = "Idempotency-Key"
+	idempotencyMaxKeyLength = 255
+)
+
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// responseRecorder copies everything the handler writes so it can be replayed.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func idempotencyTTL() time.Duration {
+	ttl, err := time.ParseDuration(config.GetEnv("IDEMPOTENCY_TTL", "24h"))
+	if err != nil || ttl <= 0 {
+		return 24 * time.Hour
+	}
+	return ttl
+}
+
+// Idempotency makes create endpoints safe to retry. When a request carries an
+// Idempotency-Key header, the first response for that key is stored in Redis
+// and replayed for later requests with the same key and payload. Reusing a key
+// with a different payload is rejected with 422. Requests without the header,
+// or arriving while Redis is unavailable, are processed normally.
+func Idempotency(c *gin.Context) {
+	key := c.GetHeader(idempotencyHeader)
+	if key == "" {
+		c.Next()
+		return
+	}
+	if len(key) > idempotencyMaxKeyLength {
+		apperrors.Abort(c, apperrors.BadRequest("idempotency_key_invalid", "Idempotency-Key must be at most 255 characters"))
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		apperrors.Abort(c, apperrors.BadRequest("invalid_request", "Invalid request"))
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	sum := sha256.Sum256([]byte(c.Request.Method + "\n" + c.FullPath() + "\n" + string(body)))
+	fingerprint := hex.EncodeToString(sum[:])
+
+	// Keys are scoped to the caller so one client can't read another's responses.
+	cacheKey := "idempotency:" + KeyByUser(c) + ":" + key
+	ttl := idempotencyTTL()
+
+	pending, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+	acquired, err := config.REDIS.SetNX(c, cacheKey, pending, ttl).Result()
+	if err != nil {
+		log.Printf("⚠️ Idempotency disabled for request, Redis error: %v", err)
+		c.Next()
+		return
+	}
+
+	if !acquired {
+		replayIdempotent(c, cacheKey, fingerprint)
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError {
+		// Let the client retry server errors with the same key.
+		config.REDIS.Del(c, cacheKey)
+		return
+	}
+
+	record, _ := json.Marshal(idempotencyRecord{
+		Fingerprint: fingerprint,
+		Completed:   true,
+		Status:      status,
+		ContentType: recorder.Header().Get("Content-Type"),
+		Body:        recorder.body.Bytes(),
+	})
+	if err := config.REDIS.Set(c, cacheKey, record, ttl).Err(); err != nil {
+		log.Printf("⚠️ Failed to store idempotent response: %v", err)
+	}
+}
+
+func replayIdempotent(c *gin.Context, cacheKey, fingerprint string) {
+	raw, err := config.REDIS.Get(c, cacheKey).Bytes()
+	if err != nil {
+		// The record expired or was released between SETNX and GET.
+		apperrors.Abort(c, apperrors.Conflict("idempotency_in_progress", "A request with this Idempotency-Key is already being processed"))
+		return
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		apperrors.Abort(c, apperrors.Internal(err, "internal_error", "Internal server error"))
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		apperrors.Abort(c, apperrors.Unprocessable("idempotency_key_reused", "Idempotency-Key was already used with a different request"))
+		return
+	}
+	if !record.Completed {
+		apperrors.Abort(c, apperrors.Conflict("idempotency_in_progress", "A request with this Idempotency-Key is already being processed"))
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(record.Status, record.ContentType, record.Body)
+	c.Abort()
+}
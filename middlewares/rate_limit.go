@@ -0,0 +1,262 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// RateLimitKeyFunc returns the bucket a request is counted against.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// KeyByIP counts requests per client IP. X-Forwarded-For only counts when it
+// comes from one of config.TrustedProxies; otherwise clients could get a
+// fresh bucket for every request by rotating it.
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser counts requests per authenticated user, falling back to the client IP.
+func KeyByUser(c *gin.Context) string {
+	if apiKeyID := c.GetInt("apiKeyID"); apiKeyID != 0 {
+		return "api-key:" + strconv.Itoa(apiKeyID)
+	}
+	if userID := c.GetInt("userID"); userID != 0 {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return KeyByIP(c)
+}
+
+// KeyByHospital counts requests per hospital of the authenticated user, falling back to the client IP.
+func KeyByHospital(c *gin.Context) string {
+	if hospitalID := c.GetInt("hospitalID"); hospitalID != 0 {
+		return "hospital:" + strconv.Itoa(hospitalID)
+	}
+	return KeyByIP(c)
+}
+
+type RateLimitOptions struct {
+	// Name identifies the route in Redis keys and in the RATE_LIMIT_<NAME> env override.
+	Name   string
+	Limit  int
+	Window time.Duration
+	Key    RateLimitKeyFunc
+}
+
+type rateLimitResult struct {
+	allowed   bool
+	remaining int
+	reset     time.Duration
+}
+
+// slidingWindowScript keeps a sorted set of request timestamps per key and
+// only records the current request if it fits in the window.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
+
+var (
+	rateLimitSeq uint64
+	// redisRetryAt holds the unix time before which Redis is not retried
+	// after a failure, so an outage doesn't add a dial timeout to every request.
+	redisRetryAt int64
+)
+
+const redisRetryDelay = 10 * time.Second
+
+var errRedisUnavailable = errors.New("redis marked unavailable")
+
+func redisAllow(ctx context.Context, key string, limit int, window time.Duration) (rateLimitResult, error) {
+	if config.REDIS == nil {
+		return rateLimitResult{}, fmt.Errorf("redis is not initialized")
+	}
+	if time.Now().Unix() < atomic.LoadInt64(&redisRetryAt) {
+		return rateLimitResult{}, errRedisUnavailable
+	}
+
+	now := time.Now()
+	member := fmt.Sprintf("%d-%d", now.UnixNano(), atomic.AddUint64(&rateLimitSeq, 1))
+	res, err := slidingWindowScript.Run(ctx, config.REDIS, []string{key},
+		now.UnixMilli(), window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		atomic.StoreInt64(&redisRetryAt, time.Now().Add(redisRetryDelay).Unix())
+		return rateLimitResult{}, err
+	}
+
+	return rateLimitResult{
+		allowed:   res[0] == 1,
+		remaining: limit - int(res[1]),
+		reset:     time.Duration(res[2]) * time.Millisecond,
+	}, nil
+}
+
+// inMemoryLimiter is used when Redis is unreachable. Limits are per process,
+// so they are only an approximation when several instances are running.
+type inMemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	lastGC  time.Time
+	gcEvery time.Duration
+}
+
+// memoryBucket holds the hits of a key along with the window of the route
+// it belongs to, so routes with shorter windows don't evict it early.
+type memoryBucket struct {
+	window time.Duration
+	hits   []time.Time
+}
+
+var memoryLimiter = &inMemoryLimiter{
+	buckets: make(map[string]*memoryBucket),
+	gcEvery: time.Minute,
+}
+
+func (l *inMemoryLimiter) allow(key string, limit int, window time.Duration) rateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastGC) > l.gcEvery {
+		for k, b := range l.buckets {
+			if len(b.hits) == 0 || now.Sub(b.hits[len(b.hits)-1]) > b.window {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastGC = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &memoryBucket{}
+		l.buckets[key] = b
+	}
+	b.window = window
+	hits := b.hits
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	hits = hits[i:]
+
+	allowed := len(hits) < limit
+	if allowed {
+		hits = append(hits, now)
+	}
+	b.hits = hits
+
+	reset := window
+	if len(hits) > 0 {
+		reset = hits[0].Add(window).Sub(now)
+	}
+
+	return rateLimitResult{
+		allowed:   allowed,
+		remaining: limit - len(hits),
+		reset:     reset,
+	}
+}
+
+// parseRateLimit parses overrides in the "<limit>/<window>" form, e.g. "5/1m".
+func parseRateLimit(value string) (int, time.Duration, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected <limit>/<window>, got %q", value)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || limit < 1 {
+		return 0, 0, fmt.Errorf("invalid limit in %q", value)
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || window <= 0 {
+		return 0, 0, fmt.Errorf("invalid window in %q", value)
+	}
+	return limit, window, nil
+}
+
+// RateLimit throttles requests with a sliding window stored in Redis. If Redis
+// is down it falls back to an in-memory window so the route stays protected.
+func RateLimit(opts RateLimitOptions) gin.HandlerFunc {
+	if opts.Key == nil {
+		opts.Key = KeyByIP
+	}
+
+	envKey := "RATE_LIMIT_" + strings.ToUpper(strings.ReplaceAll(opts.Name, "-", "_"))
+	if value, ok := os.LookupEnv(envKey); ok {
+		limit, window, err := parseRateLimit(value)
+		if err != nil {
+			log.Printf("⚠️ Ignoring %s: %v", envKey, err)
+		} else {
+			opts.Limit, opts.Window = limit, window
+		}
+	}
+
+	policy := fmt.Sprintf("%d;w=%d", opts.Limit, int(opts.Window.Seconds()))
+
+	return func(c *gin.Context) {
+		key := "rate_limit:" + opts.Name + ":" + opts.Key(c)
+
+		result, err := redisAllow(c, key, opts.Limit, opts.Window)
+		if err != nil {
+			if err != errRedisUnavailable {
+				log.Printf("⚠️ Rate limiter falling back to memory: %v", err)
+			}
+			result = memoryLimiter.allow(key, opts.Limit, opts.Window)
+		}
+
+		resetSeconds := int(math.Ceil(result.reset.Seconds()))
+		if resetSeconds < 1 {
+			resetSeconds = 1
+		}
+		remaining := result.remaining
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(opts.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(resetSeconds))
+
+		if !result.allowed {
+			c.Header("Retry-After", strconv.Itoa(resetSeconds))
+			apperrors.Abort(c, apperrors.TooManyRequests("rate_limited", "Too many requests, please try again later"))
+			return
+		}
+
+		c.Next()
+	}
+}
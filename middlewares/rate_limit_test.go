@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInMemoryLimiterKeepsLongerWindows(t *testing.T) {
+	l := &inMemoryLimiter{buckets: make(map[string]*memoryBucket)}
+
+	if !l.allow("rate_limit:register:ip:1", 1, time.Hour).allowed {
+		t.Fatal("first register request refused")
+	}
+	if !l.allow("rate_limit:login:ip:1", 5, 10*time.Millisecond).allowed {
+		t.Fatal("first login request refused")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// Collecting garbage for the short-window route must not reset the
+	// hour-long one, whose hit is still within its window.
+	if !l.allow("rate_limit:login:ip:2", 5, 10*time.Millisecond).allowed {
+		t.Fatal("login request refused")
+	}
+	if res := l.allow("rate_limit:register:ip:1", 1, time.Hour); res.allowed {
+		t.Fatal("register limit was reset by the login limiter")
+	}
+	if _, ok := l.buckets["rate_limit:login:ip:1"]; ok {
+		t.Fatal("stale login bucket wasn't evicted")
+	}
+}
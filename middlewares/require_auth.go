@@ -1,36 +1,52 @@
 package middlewares
 
 import (
-	"net/http"
+	"errors"
+	"log"
+	"strconv"
 	"strings"
 
+	"github.com/efecan/vatansoft-case/apikey"
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/audit"
 	"github.com/efecan/vatansoft-case/config"
 	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/session"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// RequireAuth authenticates users by their bearer JWT. Endpoints that
+// integrations may call with an API key use Authenticate instead.
 func RequireAuth(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" && c.GetHeader(apiKeyHeader) != "" {
+		apperrors.Abort(c, apperrors.Forbidden("api_key_not_allowed", "API keys can't be used on this endpoint"))
+		return
+	}
 	if authHeader == "" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing auth header"})
+		apperrors.Abort(c, apperrors.Unauthorized("missing_auth_header", "Missing auth header"))
 		return
 	}
 
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth format"})
+		apperrors.Abort(c, apperrors.Unauthorized("invalid_auth_format", "Invalid auth format"))
 		return
 	}
 
 	tokenString := parts[1]
+	if apikey.IsKey(tokenString) {
+		apperrors.Abort(c, apperrors.Forbidden("api_key_not_allowed", "API keys can't be used on this endpoint"))
+		return
+	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		return []byte(config.GetEnv("JWT_SECRET", "devsecret")), nil
 	})
 
 	if err != nil || !token.Valid {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		apperrors.Abort(c, apperrors.Unauthorized("invalid_token", "Invalid or expired token"))
 		return
 	}
 
@@ -38,26 +54,135 @@ func RequireAuth(c *gin.Context) {
 
 	sub, ok := claims["sub"].(float64)
 	if !ok {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token payload"})
+		apperrors.Abort(c, apperrors.Unauthorized("invalid_token_payload", "Invalid token payload"))
+		return
+	}
+
+	// Every token is bound to a session, which can be revoked before the
+	// token expires.
+	sid, _ := claims["sid"].(string)
+	if sid == "" {
+		apperrors.Abort(c, apperrors.Unauthorized("invalid_token_payload", "Invalid token payload"))
+		return
+	}
+	s, err := session.Check(config.DB, sid, uint(sub), c.ClientIP())
+	if err != nil {
+		if errors.Is(err, session.ErrNotFound) || errors.Is(err, session.ErrRevoked) || errors.Is(err, session.ErrExpired) {
+			apperrors.Abort(c, apperrors.Unauthorized("session_revoked", "Session was revoked or has expired; log in again"))
+		} else {
+			apperrors.Abort(c, apperrors.Internal(err, "session_check_failed", "Failed to check session"))
+		}
+		return
+	}
+
+	// Admins of a hospital whose registration isn't verified yet may only
+	// reach the endpoints that finish it.
+	if s.Unverified && !c.GetBool("allowUnverified") {
+		apperrors.Abort(c, apperrors.Forbidden("verification_required", "Confirm the email addresses and phone numbers of your registration first"))
 		return
 	}
 
 	name, _ := claims["name"].(string)
 	role, _ := claims["role"].(string)
 	hospitalID, _ := claims["hospital_id"].(float64)
+	lang, _ := claims["lang"].(string)
 
 	c.Set("userID", int(sub))
 	c.Set("userName", name)
 	c.Set("userRole", role)
 	c.Set("hospitalID", int(hospitalID))
+	c.Set("userLang", lang)
+	c.Set("sessionID", int(s.ID))
+	platformAdmin, _ := claims["platform_admin"].(bool)
+	c.Set("platformAdmin", platformAdmin)
+
+	// The session, not the token, decides whether this is an impersonation;
+	// the act claim only has to agree with it.
+	if s.ImpersonatorID != 0 {
+		act, _ := claims["act"].(map[string]interface{})
+		actorID, _ := act["sub"].(float64)
+		if uint(actorID) != s.ImpersonatorID {
+			apperrors.Abort(c, apperrors.Unauthorized("invalid_token_payload", "Invalid token payload"))
+			return
+		}
+		actorName, _ := act["name"].(string)
+		c.Set("impersonatorID", int(s.ImpersonatorID))
+		c.Set("impersonatorName", actorName)
+		c.Header("X-Impersonated-By", strconv.FormatUint(uint64(s.ImpersonatorID), 10))
+		c.Next()
+		recordImpersonatedRequest(c)
+		return
+	}
+
+	c.Next()
+}
 
+// recordImpersonatedRequest adds every request made while impersonating to
+// the audit log, reads included, so the user's hospital can see exactly what
+// support looked at.
+func recordImpersonatedRequest(c *gin.Context) {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	err := audit.Record(config.DB, c, audit.Entry{
+		Action:     "impersonation.request",
+		EntityType: "user",
+		EntityID:   uint(c.GetInt("userID")),
+		After: gin.H{
+			"method": c.Request.Method,
+			"route":  route,
+			"path":   c.Request.URL.RequestURI(),
+			"status": c.Writer.Status(),
+		},
+	})
+	if err != nil {
+		log.Printf("⚠️ Could not record impersonated request %s: %v", c.GetString("requestID"), err)
+	}
+}
+
+// AllowUnverified lets the RequireAuth after it through for admins whose
+// hospital registration isn't verified yet.
+func AllowUnverified(c *gin.Context) {
+	c.Set("allowUnverified", true)
 	c.Next()
 }
 
+// RequirePlatformAdmin lets only platform admins through. Impersonated
+// sessions never qualify, even when the target is one.
+func RequirePlatformAdmin(c *gin.Context) {
+	if !c.GetBool("platformAdmin") || c.GetInt("impersonatorID") != 0 {
+		apperrors.Abort(c, apperrors.Forbidden("platform_admin_required", "Platform admin access required"))
+		return
+	}
+	c.Next()
+}
+
+// ForbidImpersonation keeps impersonating admins away from endpoints only the
+// user themselves may use, like changing their password.
+func ForbidImpersonation(c *gin.Context) {
+	if c.GetInt("impersonatorID") != 0 {
+		apperrors.Abort(c, apperrors.Forbidden("impersonation_forbidden", "Not allowed while impersonating a user"))
+		return
+	}
+	c.Next()
+}
+
+// RequireAdmin lets admins through, and API keys whose scopes Authenticate
+// checked for the endpoint. Keys don't act as admins beyond that: handlers
+// that create or change admins check permissions.ManageAdmins themselves.
 func RequireAdmin(c *gin.Context) {
+	if c.GetInt("apiKeyID") != 0 {
+		if !c.GetBool("apiKeyScopesChecked") {
+			apperrors.Abort(c, apperrors.Forbidden("admin_required", "Admin access required"))
+			return
+		}
+		c.Next()
+		return
+	}
 	role, exists := c.Get("userRole")
 	if !exists || role != "admin" {
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		apperrors.Abort(c, apperrors.Forbidden("admin_required", "Admin access required"))
 		return
 	}
 	c.Next()
@@ -71,12 +196,12 @@ func IsAdminOfSameHospitalOrAbort(c *gin.Context) {
 
 	var target models.User
 	if err := config.DB.First(&target, id).Error; err != nil {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		apperrors.Abort(c, apperrors.NotFound("user_not_found", "User not found"))
 		return
 	}
 
 	if userRole != "admin" || target.HospitalID != uint(userHospitalID) {
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		apperrors.Abort(c, apperrors.Forbidden("access_denied", "Access denied"))
 		return
 	}
 }
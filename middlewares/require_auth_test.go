@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		auth   gin.H
+		status int
+	}{
+		{"admin", gin.H{"userID": 1, "userRole": "admin"}, http.StatusOK},
+		{"user", gin.H{"userID": 1, "userRole": "user"}, http.StatusForbidden},
+		{"API key checked by Authenticate", gin.H{"apiKeyID": 1, "apiKeyScopesChecked": true}, http.StatusOK},
+		{"API key nobody checked", gin.H{"apiKeyID": 1}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/users/1", nil)
+			for k, v := range tt.auth {
+				c.Set(k, v)
+			}
+			RequireAdmin(c)
+			if c.IsAborted() {
+				if w.Code != tt.status {
+					t.Fatalf("got %d, want %d", w.Code, tt.status)
+				}
+			} else if tt.status != http.StatusOK {
+				t.Fatalf("passed, want %d", tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey lets an integration such as a lab or PACS system call the API on
+// behalf of a hospital without a user login. Only a SHA-256 hash of the secret
+// is stored; Prefix identifies the key in lists and logs.
+type APIKey struct {
+	gorm.Model
+	HospitalID uint     `json:"hospital_id" gorm:"not null;index"`
+	Name       string   `json:"name" gorm:"not null"`
+	Prefix     string   `json:"prefix" gorm:"not null;uniqueIndex"`
+	SecretHash string   `json:"-" gorm:"not null"`
+	Scopes     []string `json:"scopes" gorm:"serializer:json;type:jsonb;not null"`
+	// AllowedIPs holds CIDR ranges the key may be used from; empty allows any.
+	AllowedIPs  []string   `json:"allowed_ips" gorm:"serializer:json;type:jsonb"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP  string     `json:"last_used_ip,omitempty"`
+	CreatedByID uint       `json:"created_by_id"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RevokedByID uint       `json:"revoked_by_id,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditLog is an append-only record of a change made through the API. Rows
+// are hash-chained per hospital: Hash covers the entry and PrevHash, so editing
+// or removing an entry breaks every hash after it.
+type AuditLog struct {
+	ID         uint            `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time       `json:"created_at" gorm:"index"`
+	ActorID    uint            `json:"actor_id" gorm:"index"`
+	HospitalID uint            `json:"hospital_id" gorm:"index"`
+	Action     string          `json:"action" gorm:"not null;index"`
+	EntityType string          `json:"entity_type" gorm:"not null;index:idx_audit_logs_entity"`
+	EntityID   uint            `json:"entity_id" gorm:"index:idx_audit_logs_entity"`
+	Before     json.RawMessage `json:"before,omitempty" gorm:"type:jsonb" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" gorm:"type:jsonb" swaggertype:"object"`
+	Diff       json.RawMessage `json:"diff,omitempty" gorm:"type:jsonb" swaggertype:"object"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id" gorm:"index"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash" gorm:"uniqueIndex;not null"`
+	// ImpersonatorID is the platform admin who acted as ActorID, if any.
+	ImpersonatorID uint `json:"impersonator_id,omitempty" gorm:"index"`
+	// APIKeyID is the API key the change was made with; ActorID is then zero.
+	APIKeyID uint `json:"api_key_id,omitempty" gorm:"index"`
+}
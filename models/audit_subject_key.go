@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// AuditSubjectKey encrypts the personal data that audit entries about one data
+// subject hold. Erasing the subject deletes its key, which leaves that data
+// unreadable while the append-only entries and their hash chain stay intact.
+type AuditSubjectKey struct {
+	ID          uint   `gorm:"primarykey"`
+	SubjectType string `gorm:"not null;uniqueIndex:idx_audit_subject_keys_subject"`
+	SubjectID   uint   `gorm:"not null;uniqueIndex:idx_audit_subject_keys_subject"`
+	Key         string `gorm:"not null;serializer:encrypted"`
+	CreatedAt   time.Time
+}
@@ -1,16 +1,24 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type Doctor struct {
 	gorm.Model
 	Name         string `json:"name" gorm:"not null"`
-	Email        string `json:"email" gorm:"unique;not null"`
+	Email        string `json:"email" gorm:"not null;uniqueIndex:idx_doctors_email_active,where:deleted_at IS NULL"`
 	Password     string `gorm:"not null"`
 	HospitalID   uint   `json:"hospital_id"`
 	Hospital     Hospital
 	DepartmentID uint `json:"department_id"`
 	Department   Department
+
+	// AnonymizedAt is set once the doctor's personal data has been erased.
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
+
+	// SearchDocument is maintained by a database trigger, see search.Install.
+	SearchDocument string `json:"-" gorm:"->:false;<-:false"`
 }
@@ -1,6 +1,17 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Hospital statuses. Self-registered hospitals stay unverified until their
+// and their admin's email addresses and phone numbers are confirmed.
+const (
+	HospitalStatusActive     = "active"
+	HospitalStatusUnverified = "unverified"
+)
 
 type Hospital struct {
 	gorm.Model
@@ -11,4 +22,8 @@ type Hospital struct {
 	AddressID uint    `json:"address_id"`
 	Address   Address `gorm:"foreignKey:AddressID" json:"address"`
 	Users     []User  `json:"users,omitempty"`
+
+	Status          string     `json:"status" gorm:"not null;default:active"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty"`
 }
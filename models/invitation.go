@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invitation lets a pending user set their own password and accept the terms.
+// Only a SHA-256 hash of the token is stored; the token itself is delivered to
+// the invitee and never persisted. Resending replaces the token.
+type Invitation struct {
+	gorm.Model
+	UserID      uint       `json:"user_id" gorm:"not null;uniqueIndex"`
+	HospitalID  uint       `json:"hospital_id" gorm:"not null;index"`
+	InvitedByID uint       `json:"invited_by_id"`
+	TokenHash   string     `json:"-" gorm:"not null;uniqueIndex"`
+	Channel     string     `json:"channel" gorm:"not null"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	SentAt      time.Time  `json:"sent_at"`
+	SendCount   int        `json:"send_count" gorm:"not null;default:1"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
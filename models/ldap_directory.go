@@ -0,0 +1,55 @@
+package models
+
+import "gorm.io/gorm"
+
+// LDAPDirectory is a hospital's LDAP directory, such as Active Directory, that
+// its staff log in against with their directory password. BindPassword is
+// encrypted at rest and never returned by the API.
+type LDAPDirectory struct {
+	gorm.Model
+	HospitalID uint `json:"hospital_id" gorm:"not null;uniqueIndex"`
+	Enabled    bool `json:"enabled"`
+	// URL is ldaps://host:636, or ldap://host:389 with StartTLS.
+	URL      string `json:"url" gorm:"not null"`
+	StartTLS bool   `json:"start_tls"`
+	// CACertificate is a PEM bundle trusted in addition to the system roots,
+	// for directories with certificates from a private CA.
+	CACertificate string `json:"ca_certificate"`
+
+	// BindDN and BindPassword are the service account users are looked up
+	// with before binding as them.
+	BindDN       string `json:"bind_dn"`
+	BindPassword string `json:"-" gorm:"serializer:encrypted"`
+	BaseDN       string `json:"base_dn" gorm:"not null"`
+	// UserFilter finds the user logging in; {email} is replaced with their
+	// escaped email address.
+	UserFilter string               `json:"user_filter"`
+	Attributes LDAPAttributeMapping `json:"attributes" gorm:"serializer:json;type:jsonb"`
+	// EmailDomains route logins of users we don't know yet to this
+	// directory, so they can be provisioned.
+	EmailDomains []string `json:"email_domains" gorm:"serializer:json;type:jsonb"`
+
+	// RoleMapping maps groups, by DN or CN, to our roles. The first of the
+	// user's groups found wins; DefaultRole applies when none matches and,
+	// if empty, such users can't log in.
+	RoleMapping map[string]string `json:"role_mapping" gorm:"serializer:json;type:jsonb"`
+	DefaultRole string            `json:"default_role"`
+
+	// AutoProvision creates users that log in for the first time, with the
+	// default profession group and title.
+	AutoProvision            bool `json:"auto_provision"`
+	DefaultProfessionGroupID uint `json:"default_profession_group_id"`
+	DefaultTitleID           uint `json:"default_title_id"`
+}
+
+// LDAPAttributeMapping names the directory attributes user fields are read
+// from. Empty names fall back to the Active Directory defaults.
+type LDAPAttributeMapping struct {
+	// ID is a stable identifier of the entry that survives renames, e.g.
+	// objectGUID or entryUUID.
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+	Phone   string `json:"phone"`
+	Groups  string `json:"groups"`
+}
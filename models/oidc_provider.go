@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OIDCProvider is a hospital's own identity provider that its staff sign in
+// with. ClientSecret is encrypted at rest and never returned by the API.
+type OIDCProvider struct {
+	gorm.Model
+	HospitalID   uint     `json:"hospital_id" gorm:"not null;uniqueIndex"`
+	Enabled      bool     `json:"enabled"`
+	Issuer       string   `json:"issuer" gorm:"not null"`
+	ClientID     string   `json:"client_id" gorm:"not null"`
+	ClientSecret string   `json:"-" gorm:"serializer:encrypted"`
+	Scopes       []string `json:"scopes" gorm:"serializer:json;type:jsonb"`
+
+	Claims OIDCClaimMapping `json:"claims" gorm:"serializer:json;type:jsonb"`
+	// RoleMapping maps values of the roles claim, e.g. IdP groups, to our
+	// roles. The first match in the user's claim wins; DefaultRole applies
+	// when nothing matches and, if empty, such users can't sign in.
+	RoleMapping map[string]string `json:"role_mapping" gorm:"serializer:json;type:jsonb"`
+	DefaultRole string            `json:"default_role"`
+
+	// AutoProvision creates users that sign in for the first time, with the
+	// default profession group and title.
+	AutoProvision            bool `json:"auto_provision"`
+	DefaultProfessionGroupID uint `json:"default_profession_group_id"`
+	DefaultTitleID           uint `json:"default_title_id"`
+}
+
+// OIDCClaimMapping names the ID token claims user fields are read from.
+// Empty names fall back to the standard OIDC claims.
+type OIDCClaimMapping struct {
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+	Phone   string `json:"phone"`
+	Roles   string `json:"roles"`
+}
+
+// ExternalIdentity links a user to their account at an external identity
+// provider, so renaming the account there doesn't lose the link.
+type ExternalIdentity struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	Provider    string    `json:"provider" gorm:"not null;uniqueIndex:idx_external_identity"`
+	Issuer      string    `json:"issuer" gorm:"not null;uniqueIndex:idx_external_identity"`
+	Subject     string    `json:"subject" gorm:"not null;uniqueIndex:idx_external_identity"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
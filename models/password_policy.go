@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordPolicy holds the password rules of a hospital. Hospitals without a
+// row use the defaults of password.DefaultPolicy.
+type PasswordPolicy struct {
+	gorm.Model
+	HospitalID         uint `json:"hospital_id" gorm:"not null;uniqueIndex"`
+	MinLength          int  `json:"min_length" gorm:"not null"`
+	RequireUpper       bool `json:"require_upper"`
+	RequireLower       bool `json:"require_lower"`
+	RequireDigit       bool `json:"require_digit"`
+	RequireSymbol      bool `json:"require_symbol"`
+	ForbidPersonalInfo bool `json:"forbid_personal_info"`
+	// HistorySize is how many previous passwords can't be reused.
+	HistorySize int `json:"history_size"`
+	// MaxAgeDays forces a password change after this many days; 0 disables it.
+	MaxAgeDays    int  `json:"max_age_days"`
+	CheckBreached bool `json:"check_breached"`
+}
+
+// PasswordHistory keeps the hashes of a user's previous passwords so they
+// can't be reused.
+type PasswordHistory struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;index"`
+	Hash      string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
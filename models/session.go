@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Session is one login of a user, or a platform admin impersonating them. Its
+// TokenID travels in the JWT as the sid claim, so revoking the session
+// invalidates the token before it expires.
+type Session struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	TokenID     string     `json:"-" gorm:"not null;uniqueIndex"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	HospitalID  uint       `json:"hospital_id" gorm:"not null;index"`
+	Device      string     `json:"device"`
+	UserAgent   string     `json:"user_agent"`
+	IP          string     `json:"ip"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"index"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RevokedByID uint       `json:"revoked_by_id,omitempty"`
+	// ImpersonatorID is the platform admin acting as the user in this
+	// session, zero for the user's own logins.
+	ImpersonatorID uint `json:"impersonator_id,omitempty" gorm:"index"`
+	// Unverified sessions belong to admins of hospitals whose registration
+	// isn't verified yet and only reach the endpoints that finish it.
+	Unverified bool `json:"unverified,omitempty" gorm:"not null;default:false"`
+}
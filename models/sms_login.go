@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// SMSLoginSetting lets a hospital's staff log in with a one-time code sent to
+// their registered phone instead of their password. Hospitals without a row
+// don't allow it.
+type SMSLoginSetting struct {
+	gorm.Model
+	HospitalID uint `json:"hospital_id" gorm:"not null;uniqueIndex"`
+	Enabled    bool `json:"enabled"`
+	// AllowAdmins extends it to admins, who otherwise keep using their
+	// password: a code only proves the phone is at hand.
+	AllowAdmins bool `json:"allow_admins"`
+}
@@ -1,17 +1,42 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
 
+	"github.com/efecan/vatansoft-case/encryption"
+	"gorm.io/gorm"
+)
+
+// User statuses. Pending users were invited but haven't accepted yet and
+// can't log in. Unverified users registered a hospital and may only log in to
+// confirm its and their own contact details.
+const (
+	UserStatusActive     = "active"
+	UserStatusPending    = "pending"
+	UserStatusUnverified = "unverified"
+)
+
+// User roles. Admins manage their hospital; everyone else is a user.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// User is a staff member of a hospital. Email, phone and TCKN are unique only
+// among users that aren't deleted, so a deleted user can be hired again.
 type User struct {
 	gorm.Model
-	Name       string `json:"name"`
-	Surname    string `json:"surname"`
-	Email      string `json:"email" gorm:"unique"`
-	Password   string `json:"-"`
-	Phone      string `json:"phone" gorm:"unique"`
-	TCKN       string `json:"tckn" gorm:"unique"`
-	Role       string `json:"role"`
-	HospitalID uint   `json:"hospital_id"`
+	Name       string  `json:"name"`
+	Surname    string  `json:"surname"`
+	Email      string  `json:"email" gorm:"uniqueIndex:idx_users_email_active,where:deleted_at IS NULL"`
+	Password   string  `json:"-"`
+	Phone      string  `json:"phone" gorm:"serializer:encrypted"`
+	PhoneIndex *string `json:"-" gorm:"uniqueIndex:idx_users_phone_active,where:deleted_at IS NULL"`
+	TCKN       string  `json:"tckn" gorm:"serializer:encrypted"`
+	TCKNIndex  *string `json:"-" gorm:"uniqueIndex:idx_users_tckn_active,where:deleted_at IS NULL"`
+	Role       string  `json:"role"`
+	Language   string  `json:"language"`
+	HospitalID uint    `json:"hospital_id"`
 	Hospital   Hospital
 
 	ProfessionGroupID uint             `json:"profession_group_id"`
@@ -19,4 +44,57 @@ type User struct {
 
 	TitleID uint   `json:"title_id"`
 	Title   *Title `json:"title,omitempty" gorm:"foreignKey:TitleID"`
+
+	Status          string     `json:"status" gorm:"not null;default:active;index"`
+	TermsAcceptedAt *time.Time `json:"terms_accepted_at,omitempty"`
+	TermsVersion    string     `json:"terms_version,omitempty"`
+
+	// EmailVerifiedAt and PhoneVerifiedAt are set once the user proved they
+	// own the address, so far only when registering a hospital.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty"`
+
+	// PasswordChangedAt drives the rotation interval of the password policy.
+	// MustChangePassword makes login refuse until the password is changed.
+	PasswordChangedAt  *time.Time `json:"password_changed_at,omitempty"`
+	MustChangePassword bool       `json:"must_change_password" gorm:"not null;default:false"`
+
+	// PlatformAdmin marks support staff of the platform operator, who may
+	// impersonate users of any hospital. It is only granted with
+	// cmd/platformadmin, never through the API.
+	PlatformAdmin bool `json:"platform_admin" gorm:"not null;default:false"`
+
+	// Version is bumped on every update through the API and backs the
+	// ETag/If-Match optimistic locking of user edits.
+	Version uint `json:"version" gorm:"not null;default:1"`
+
+	// AnonymizedAt is set once the user's personal data has been erased.
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
+
+	// SearchDocument is the folded text the search endpoint matches against.
+	// A database trigger maintains it (see search.Install), so GORM never
+	// reads or writes it.
+	SearchDocument string `json:"-" gorm:"->:false;<-:false"`
+}
+
+// BeforeSave keeps the blind indexes used for exact-match lookups on the
+// encrypted TCKN and phone columns in sync with their values.
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	u.PhoneIndex = encryption.BlindIndex("phone", u.Phone)
+	u.TCKNIndex = encryption.BlindIndex("tckn", u.TCKN)
+	return nil
+}
+
+// UserWithPhone scopes a query to the user whose (normalized) phone matches.
+func UserWithPhone(phone string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("phone_index = ?", encryption.BlindIndex("phone", phone))
+	}
+}
+
+// UserWithTCKN scopes a query to the user with the given national ID number.
+func UserWithTCKN(tckn string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("tckn_index = ?", encryption.BlindIndex("tckn", tckn))
+	}
 }
@@ -0,0 +1,102 @@
+// Package netguard keeps connections to addresses hospital admins configure,
+// like their identity provider or LDAP directory, off the server's own
+// network.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/efecan/vatansoft-case/config"
+)
+
+var ErrForbidden = errors.New("netguard: address is not publicly routable")
+
+// sharedAddressSpace is the carrier-grade NAT range, which net.IP doesn't
+// count as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0).To4(), Mask: net.CIDRMask(10, 32)}
+
+func parseNetwork(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, n, err := net.ParseCIDR(s)
+		return n, err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", s)
+	}
+	bits := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// internalNetworks parses config.InternalNetworks.
+func internalNetworks() ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, s := range config.InternalNetworks() {
+		n, err := parseNetwork(s)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+// Validate reports whether INTERNAL_NETWORKS_ALLOWED can be parsed. Call it
+// on startup; addresses are otherwise checked as if it were empty.
+func Validate() error {
+	_, err := internalNetworks()
+	return err
+}
+
+// Allowed reports whether ip may be connected to: it must be publicly
+// routable, or in one of the networks of config.InternalNetworks.
+func Allowed(ip net.IP) bool {
+	networks, _ := internalNetworks()
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip))
+}
+
+// control refuses connections to addresses that aren't Allowed. It runs once
+// the host name is resolved, for every connection including redirects, so a
+// name can't be pointed at an internal address after it was checked.
+func control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !Allowed(ip) {
+		return fmt.Errorf("%w: %s", ErrForbidden, host)
+	}
+	return nil
+}
+
+// Dialer returns a dialer that only connects to Allowed addresses.
+func Dialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{Timeout: timeout, Control: control}
+}
+
+// HTTPClient returns a client that only connects to Allowed addresses. It
+// ignores proxies set in the environment, since the dialer would check the
+// proxy rather than the server behind it.
+func HTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = Dialer(timeout).DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Channels a message can be delivered over.
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Message is a single notification. Subject is ignored for SMS.
+type Message struct {
+	Channel string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages, e.g. through an email or SMS provider.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var (
+	mu     sync.RWMutex
+	sender Sender = LogSender{}
+)
+
+// SetSender replaces the sender used by Send. The default only logs
+// messages, which is enough for development and the case study setup.
+func SetSender(s Sender) {
+	mu.Lock()
+	defer mu.Unlock()
+	sender = s
+}
+
+// Send delivers msg with the configured sender.
+func Send(ctx context.Context, msg Message) error {
+	mu.RLock()
+	s := sender
+	mu.RUnlock()
+	return s.Send(ctx, msg)
+}
+
+// LogSender writes messages to the application log instead of sending them.
+type LogSender struct{}
+
+func (LogSender) Send(_ context.Context, msg Message) error {
+	log.Printf("📨 [%s] to %s: %s %s", msg.Channel, msg.To, msg.Subject, msg.Body)
+	return nil
+}
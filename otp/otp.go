@@ -0,0 +1,101 @@
+package otp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/go-redis/redis/v8"
+)
+
+// MaxAttempts is how many wrong codes a challenge tolerates before it is
+// discarded and a new code has to be requested.
+const MaxAttempts = 5
+
+var (
+	ErrExpired         = errors.New("otp: no pending code")
+	ErrInvalid         = errors.New("otp: wrong code")
+	ErrTooManyAttempts = errors.New("otp: too many attempts")
+)
+
+// Generate returns a random six digit code.
+func Generate() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue starts a challenge under key, replacing any pending one, and returns
+// the code to deliver. value is handed back by Verify once the code is
+// confirmed, e.g. the new email address being verified. Only a hash of the
+// code is stored.
+func Issue(ctx context.Context, key, value string, ttl time.Duration) (string, error) {
+	code, err := Generate()
+	if err != nil {
+		return "", err
+	}
+	pipe := config.REDIS.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, "code", hash(code), "value", value, "attempts", 0)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// verifyScript counts the attempt and consumes the challenge on a match in
+// one step, so concurrent guesses can't exceed the limit or reuse a code.
+// It returns {status, value}: 1 matched, 0 wrong code, -1 no challenge, -2
+// too many attempts.
+var verifyScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return {-1, ''}
+end
+local attempts = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+if attempts > tonumber(ARGV[2]) then
+	redis.call('DEL', KEYS[1])
+	return {-2, ''}
+end
+if redis.call('HGET', KEYS[1], 'code') ~= ARGV[1] then
+	return {0, ''}
+end
+local value = redis.call('HGET', KEYS[1], 'value')
+redis.call('DEL', KEYS[1])
+return {1, value}
+`)
+
+// Verify checks code against the challenge under key and, if it matches,
+// consumes the challenge and returns its value. Every wrong code counts
+// towards MaxAttempts.
+func Verify(ctx context.Context, key, code string) (string, error) {
+	result, err := verifyScript.Run(ctx, config.REDIS, []string{key}, hash(code), MaxAttempts).Slice()
+	if err != nil {
+		return "", err
+	}
+	status, _ := result[0].(int64)
+	value, _ := result[1].(string)
+	switch status {
+	case 1:
+		return value, nil
+	case 0:
+		return "", ErrInvalid
+	case -2:
+		return "", ErrTooManyAttempts
+	default:
+		return "", ErrExpired
+	}
+}
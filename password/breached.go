@@ -0,0 +1,137 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/efecan/vatansoft-case/config"
+)
+
+// breachedList holds the upper-case SHA-1 hashes of very common passwords,
+// one per line. Only hashes are shipped, the same format the Have I Been
+// Pwned k-anonymity range files use.
+//
+//go:embed breached.txt
+var breachedList string
+
+var (
+	breachedOnce   sync.Once
+	breachedHashes map[string]bool
+	breachedFile   *os.File
+	breachedSize   int64
+)
+
+func loadBreached() {
+	breachedHashes = map[string]bool{}
+	for _, line := range strings.Split(breachedList, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			breachedHashes[line] = true
+		}
+	}
+
+	path := config.GetEnv("BREACHED_PASSWORDS_FILE", "")
+	if path == "" {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("⚠️ Breached password file not available: %v", err)
+		return
+	}
+	info, err := f.Stat()
+	if err != nil {
+		log.Printf("⚠️ Breached password file not available: %v", err)
+		f.Close()
+		return
+	}
+	breachedFile, breachedSize = f, info.Size()
+	log.Printf("✅ Checking passwords against %s", path)
+}
+
+// Breached reports whether password appears in the bundled list of common
+// passwords or in BREACHED_PASSWORDS_FILE. That file is an HIBP style dump of
+// "HASH:COUNT" lines sorted by SHA-1 hash; it is binary searched on disk, so
+// the full multi-gigabyte list works without loading it into memory.
+func Breached(password string) bool {
+	breachedOnce.Do(loadBreached)
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	if breachedHashes[hash] {
+		return true
+	}
+	if breachedFile == nil {
+		return false
+	}
+	found, err := searchSorted(breachedFile, breachedSize, []byte(hash))
+	if err != nil {
+		log.Printf("⚠️ Could not search breached password file: %v", err)
+	}
+	return found
+}
+
+// searchSorted binary searches r, a file of newline separated lines sorted by
+// their leading hash, for a line starting with hash. The search range is the
+// set of offsets where the wanted line may start.
+func searchSorted(r io.ReaderAt, size int64, hash []byte) (bool, error) {
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, next, line, err := lineFrom(r, size, mid)
+		if err != nil {
+			return false, err
+		}
+		if start >= hi || line == nil {
+			hi = mid
+			continue
+		}
+		key := line
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			key = line[:i]
+		}
+		switch bytes.Compare(bytes.ToUpper(bytes.TrimSpace(key)), hash) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = next
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+// lineFrom returns the first line starting at or after pos, its offset and
+// the offset of the line after it. line is nil past the last line.
+func lineFrom(r io.ReaderAt, size, pos int64) (start, next int64, line []byte, err error) {
+	start = pos
+	if pos > 0 {
+		// Skip the rest of the line pos falls into, unless pos starts a line.
+		reader := bufio.NewReader(io.NewSectionReader(r, pos-1, size-pos+1))
+		skipped, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return size, size, nil, nil
+		}
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		start = pos - 1 + int64(len(skipped))
+	}
+	reader := bufio.NewReader(io.NewSectionReader(r, start, size-start))
+	raw, err := reader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return 0, 0, nil, err
+	}
+	if len(raw) == 0 {
+		return start, start, nil, nil
+	}
+	return start, start + int64(len(raw)), bytes.TrimRight(raw, "\r\n"), nil
+}
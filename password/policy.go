@@ -0,0 +1,217 @@
+package password
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/efecan/vatansoft-case/apperrors"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/search"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// maxBytes is the longest password bcrypt hashes in full.
+const maxBytes = 72
+
+// minPersonalLength keeps initials and two-letter names like "Su" from ruling
+// out every password that happens to contain them.
+const minPersonalLength = 3
+
+// DefaultPolicy applies to hospitals that haven't configured their own.
+func DefaultPolicy() models.PasswordPolicy {
+	return models.PasswordPolicy{
+		MinLength:          10,
+		RequireUpper:       true,
+		RequireLower:       true,
+		RequireDigit:       true,
+		ForbidPersonalInfo: true,
+		HistorySize:        5,
+		CheckBreached:      true,
+	}
+}
+
+// PolicyFor returns the policy of a hospital, or the default policy.
+func PolicyFor(db *gorm.DB, hospitalID uint) (models.PasswordPolicy, error) {
+	var policy models.PasswordPolicy
+	err := db.Where("hospital_id = ?", hospitalID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		policy = DefaultPolicy()
+		policy.HospitalID = hospitalID
+		return policy, nil
+	}
+	return policy, err
+}
+
+// Subject is whose password is being checked. UserID is zero for users that
+// don't exist yet, which skips the history check.
+type Subject struct {
+	UserID     uint
+	HospitalID uint
+	Name       string
+	Surname    string
+	Email      string
+	TCKN       string
+}
+
+// SubjectOf describes u for Check.
+func SubjectOf(u models.User) Subject {
+	return Subject{
+		UserID:     u.ID,
+		HospitalID: u.HospitalID,
+		Name:       u.Name,
+		Surname:    u.Surname,
+		Email:      u.Email,
+		TCKN:       u.TCKN,
+	}
+}
+
+// Check validates password against the policy of the subject's hospital.
+// Violations are reported together as a 400 on field; other errors mean the
+// check itself failed.
+func Check(db *gorm.DB, field, password string, s Subject) error {
+	policy, err := PolicyFor(db, s.HospitalID)
+	if err != nil {
+		return apperrors.Internal(err, "password_policy_fetch_failed", "Failed to load the password policy")
+	}
+
+	var problems []apperrors.FieldError
+	add := func(code, key string, params map[string]string) {
+		problems = append(problems, apperrors.FieldProblem(field, code, key, params))
+	}
+
+	if n := len([]rune(password)); n < policy.MinLength {
+		add("min", "password_too_short", map[string]string{"min": strconv.Itoa(policy.MinLength)})
+	}
+	if len(password) > maxBytes {
+		add("max", "password_too_long", map[string]string{"max": strconv.Itoa(maxBytes)})
+	}
+	classes := []struct {
+		required bool
+		in       func(rune) bool
+		code     string
+	}{
+		{policy.RequireUpper, unicode.IsUpper, "upper"},
+		{policy.RequireLower, unicode.IsLower, "lower"},
+		{policy.RequireDigit, unicode.IsDigit, "digit"},
+		{policy.RequireSymbol, isSymbol, "symbol"},
+	}
+	for _, class := range classes {
+		if class.required && !strings.ContainsFunc(password, class.in) {
+			add(class.code, "password_needs_"+class.code, nil)
+		}
+	}
+	if policy.ForbidPersonalInfo && containsPersonalInfo(password, s) {
+		add("personal_info", "password_personal_info", nil)
+	}
+	if policy.CheckBreached && Breached(password) {
+		add("breached", "password_breached", nil)
+	}
+	if len(problems) == 0 && s.UserID != 0 && policy.HistorySize > 0 {
+		reused, err := reused(db, s.UserID, password, policy.HistorySize)
+		if err != nil {
+			return apperrors.Internal(err, "password_policy_fetch_failed", "Failed to load the password policy")
+		}
+		if reused {
+			add("reused", "password_reused", map[string]string{"count": strconv.Itoa(policy.HistorySize)})
+		}
+	}
+
+	if len(problems) > 0 {
+		return apperrors.InvalidFields("password_policy_failed", "Password does not meet the password policy", problems...)
+	}
+	return nil
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
+
+// containsPersonalInfo reports whether password contains the subject's name,
+// surname, the local part of their email or their TCKN, ignoring case and
+// Turkish diacritics.
+func containsPersonalInfo(password string, s Subject) bool {
+	folded := search.Fold(password)
+	local, _, _ := strings.Cut(s.Email, "@")
+	for _, value := range []string{s.Name, s.Surname, local, s.TCKN} {
+		for _, part := range strings.Fields(search.Fold(value)) {
+			if len([]rune(part)) >= minPersonalLength && strings.Contains(folded, part) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reused reports whether password matches the user's current password or one
+// of their last historySize passwords.
+func reused(db *gorm.DB, userID uint, password string, historySize int) (bool, error) {
+	var hashes []string
+	if err := db.Model(&models.User{}).Where("id = ?", userID).Pluck("password", &hashes).Error; err != nil {
+		return false, err
+	}
+	var previous []string
+	err := db.Model(&models.PasswordHistory{}).Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").Limit(historySize).Pluck("hash", &previous).Error
+	if err != nil {
+		return false, err
+	}
+	for _, hash := range append(hashes, previous...) {
+		if hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Changed records that u's password was just set to u.Password: it stamps
+// PasswordChangedAt, clears MustChangePassword and remembers the hash. The
+// caller saves u.
+func Changed(tx *gorm.DB, u *models.User) error {
+	now := time.Now().UTC()
+	u.PasswordChangedAt = &now
+	u.MustChangePassword = false
+	return Remember(tx, *u)
+}
+
+// Remember adds u's current password hash to their history, keeping only as
+// many entries as the policy of their hospital looks back.
+func Remember(tx *gorm.DB, u models.User) error {
+	policy, err := PolicyFor(tx, u.HospitalID)
+	if err != nil {
+		return err
+	}
+	if policy.HistorySize == 0 {
+		return tx.Where("user_id = ?", u.ID).Delete(&models.PasswordHistory{}).Error
+	}
+	if err := tx.Create(&models.PasswordHistory{UserID: u.ID, Hash: u.Password}).Error; err != nil {
+		return err
+	}
+	var keep []uint
+	err = tx.Model(&models.PasswordHistory{}).Where("user_id = ?", u.ID).
+		Order("created_at DESC, id DESC").Limit(policy.HistorySize).Pluck("id", &keep).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("user_id = ? AND id NOT IN ?", u.ID, keep).Delete(&models.PasswordHistory{}).Error
+}
+
+// MustChange reports whether u has to change their password before logging
+// in, either because an admin asked for it or because it is older than the
+// policy allows. Users who never changed their password count from creation.
+func MustChange(policy models.PasswordPolicy, u models.User, now time.Time) bool {
+	if u.MustChangePassword {
+		return true
+	}
+	if policy.MaxAgeDays <= 0 {
+		return false
+	}
+	changed := u.CreatedAt
+	if u.PasswordChangedAt != nil {
+		changed = *u.PasswordChangedAt
+	}
+	return now.After(changed.AddDate(0, 0, policy.MaxAgeDays))
+}
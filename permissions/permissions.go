@@ -0,0 +1,72 @@
+package permissions
+
+import "github.com/gin-gonic/gin"
+
+// Permission names a capability that is granted to roles rather than checked
+// against a role name directly.
+type Permission string
+
+const (
+	// ViewNationalID reveals full TCKN values instead of masked ones.
+	ViewNationalID Permission = "pii:national_id"
+	// ViewContact reveals full phone numbers and email addresses.
+	ViewContact Permission = "pii:contact"
+	// ManageAdmins creates, imports, changes and deletes admins. API keys
+	// need it on top of WriteUsers for those.
+	ManageAdmins Permission = "users:admin"
+
+	// The permissions below are only granted to API keys, as scopes. Users
+	// are authorized by role on the endpoints they guard.
+
+	// ReadUsers lists, searches and exports staff.
+	ReadUsers Permission = "users:read"
+	// WriteUsers creates, imports, updates and deletes staff.
+	WriteUsers Permission = "users:write"
+	// ReadDepartments lists departments and their doctors.
+	ReadDepartments Permission = "departments:read"
+	// ReadAuditLog reads and verifies the audit log.
+	ReadAuditLog Permission = "audit:read"
+)
+
+// Scopes lists every permission an API key may be granted.
+var Scopes = []Permission{ViewNationalID, ViewContact, ManageAdmins, ReadUsers, WriteUsers, ReadDepartments, ReadAuditLog}
+
+// IsScope reports whether name is a permission API keys can be granted.
+func IsScope(name string) bool {
+	for _, s := range Scopes {
+		if string(s) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rolePermissions lists what each role may do. Roles that aren't listed have
+// no permissions.
+var rolePermissions = map[string][]Permission{
+	"admin": {ViewNationalID, ViewContact, ManageAdmins},
+}
+
+// Has reports whether role grants p.
+func Has(role string, p Permission) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Granted reports whether the authenticated caller's role grants p, or for
+// API keys, whether the key was given p as a scope.
+func Granted(c *gin.Context, p Permission) bool {
+	if c.GetInt("apiKeyID") != 0 {
+		for _, scope := range c.GetStringSlice("apiKeyScopes") {
+			if scope == string(p) {
+				return true
+			}
+		}
+		return false
+	}
+	return Has(c.GetString("userRole"), p)
+}
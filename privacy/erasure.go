@@ -0,0 +1,72 @@
+package privacy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/models"
+	"gorm.io/gorm"
+)
+
+// AnonymizedName replaces the name of an erased data subject.
+const AnonymizedName = "Anonymized"
+
+// AnonymizeUser erases the personal data of u in place and soft-deletes it.
+// The row itself is kept so audit entries and other records that reference
+// the user stay valid.
+func AnonymizeUser(tx *gorm.DB, u *models.User) error {
+	now := time.Now()
+	u.Name = AnonymizedName
+	u.Surname = ""
+	u.Email = fmt.Sprintf("erased-user-%d@anonymized.invalid", u.ID)
+	u.Phone = ""
+	u.TCKN = ""
+	u.Password = ""
+	u.AnonymizedAt = &now
+
+	err := tx.Unscoped().Model(u).
+		Select("name", "surname", "email", "phone", "phone_index", "tckn", "tckn_index", "password", "anonymized_at").
+		Updates(u).Error
+	if err != nil {
+		return err
+	}
+	// Old password hashes, the devices and IPs of past logins and links to
+	// external accounts are personal data too.
+	for _, related := range []interface{}{&models.PasswordHistory{}, &models.Session{}, &models.ExternalIdentity{}} {
+		if err := tx.Where("user_id = ?", u.ID).Delete(related).Error; err != nil {
+			return err
+		}
+	}
+	// So are the values audit entries recorded about the user.
+	if err := audit.ForgetSubject(tx, "user", u.ID); err != nil {
+		return err
+	}
+	if !u.DeletedAt.Valid {
+		return tx.Delete(u).Error
+	}
+	return nil
+}
+
+// AnonymizeDoctor erases the personal data of d in place and soft-deletes it.
+func AnonymizeDoctor(tx *gorm.DB, d *models.Doctor) error {
+	now := time.Now()
+	d.Name = AnonymizedName
+	d.Email = fmt.Sprintf("erased-doctor-%d@anonymized.invalid", d.ID)
+	d.Password = ""
+	d.AnonymizedAt = &now
+
+	err := tx.Unscoped().Model(d).
+		Select("name", "email", "password", "anonymized_at").
+		Updates(d).Error
+	if err != nil {
+		return err
+	}
+	if err := audit.ForgetSubject(tx, "doctor", d.ID); err != nil {
+		return err
+	}
+	if !d.DeletedAt.Valid {
+		return tx.Delete(d).Error
+	}
+	return nil
+}
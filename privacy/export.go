@@ -0,0 +1,167 @@
+package privacy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/models"
+	"gorm.io/gorm"
+)
+
+// Bundle is everything held about one data subject, as handed over on a KVKK
+// access request.
+type Bundle struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	SubjectType string            `json:"subject_type"`
+	SubjectID   uint              `json:"subject_id"`
+	Profile     interface{}       `json:"profile"`
+	AuditTrail  []models.AuditLog `json:"audit_trail"`
+}
+
+// UserProfile is the exported form of a user, with references resolved to names.
+type UserProfile struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Surname         string     `json:"surname"`
+	Email           string     `json:"email"`
+	Phone           string     `json:"phone"`
+	TCKN            string     `json:"tckn"`
+	Role            string     `json:"role"`
+	Language        string     `json:"language"`
+	Hospital        string     `json:"hospital"`
+	ProfessionGroup string     `json:"profession_group"`
+	Title           string     `json:"title"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+	AnonymizedAt    *time.Time `json:"anonymized_at,omitempty"`
+}
+
+// DoctorProfile is the exported form of a doctor.
+type DoctorProfile struct {
+	ID           uint       `json:"id"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	Hospital     string     `json:"hospital"`
+	Department   string     `json:"department"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
+}
+
+// ExportUser collects the profile of u and the audit entries about or made by
+// them. u should have Hospital, ProfessionGroup and Title preloaded.
+func ExportUser(db *gorm.DB, u models.User) (Bundle, error) {
+	profile := UserProfile{
+		ID:           u.ID,
+		Name:         u.Name,
+		Surname:      u.Surname,
+		Email:        u.Email,
+		Phone:        u.Phone,
+		TCKN:         u.TCKN,
+		Role:         u.Role,
+		Language:     u.Language,
+		Hospital:     u.Hospital.Name,
+		CreatedAt:    u.CreatedAt,
+		UpdatedAt:    u.UpdatedAt,
+		AnonymizedAt: u.AnonymizedAt,
+	}
+	if u.ProfessionGroup != nil {
+		profile.ProfessionGroup = u.ProfessionGroup.Name
+	}
+	if u.Title != nil {
+		profile.Title = u.Title.Name
+	}
+	if u.DeletedAt.Valid {
+		profile.DeletedAt = &u.DeletedAt.Time
+	}
+
+	var trail []models.AuditLog
+	err := db.Where("hospital_id = ?", u.HospitalID).
+		Where("(entity_type = ? AND entity_id = ?) OR actor_id = ?", "user", u.ID, u.ID).
+		Order("id").Find(&trail).Error
+	if err != nil {
+		return Bundle{}, err
+	}
+	if err := audit.Reveal(db, trail, nil); err != nil {
+		return Bundle{}, err
+	}
+
+	return newBundle("user", u.ID, profile, trail), nil
+}
+
+// ExportDoctor collects the profile of d and the audit entries about them. d
+// should have Hospital and Department preloaded.
+func ExportDoctor(db *gorm.DB, d models.Doctor) (Bundle, error) {
+	profile := DoctorProfile{
+		ID:           d.ID,
+		Name:         d.Name,
+		Email:        d.Email,
+		Hospital:     d.Hospital.Name,
+		Department:   d.Department.Name,
+		CreatedAt:    d.CreatedAt,
+		UpdatedAt:    d.UpdatedAt,
+		AnonymizedAt: d.AnonymizedAt,
+	}
+	if d.DeletedAt.Valid {
+		profile.DeletedAt = &d.DeletedAt.Time
+	}
+
+	var trail []models.AuditLog
+	err := db.Where("hospital_id = ? AND entity_type = ? AND entity_id = ?", d.HospitalID, "doctor", d.ID).
+		Order("id").Find(&trail).Error
+	if err != nil {
+		return Bundle{}, err
+	}
+	if err := audit.Reveal(db, trail, nil); err != nil {
+		return Bundle{}, err
+	}
+
+	return newBundle("doctor", d.ID, profile, trail), nil
+}
+
+func newBundle(subjectType string, id uint, profile interface{}, trail []models.AuditLog) Bundle {
+	if trail == nil {
+		trail = []models.AuditLog{}
+	}
+	return Bundle{
+		GeneratedAt: time.Now().UTC(),
+		SubjectType: subjectType,
+		SubjectID:   id,
+		Profile:     profile,
+		AuditTrail:  trail,
+	}
+}
+
+// WriteZIP writes b as a ZIP archive with one JSON file per section.
+func WriteZIP(w io.Writer, b Bundle) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"metadata.json", map[string]interface{}{
+			"generated_at": b.GeneratedAt,
+			"subject_type": b.SubjectType,
+			"subject_id":   b.SubjectID,
+		}},
+		{"profile.json", b.Profile},
+		{"audit_trail.json", b.AuditTrail},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,169 @@
+package privacy
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/efecan/vatansoft-case/audit"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/models"
+	"gorm.io/gorm"
+)
+
+// Retention actions.
+const (
+	// ActionAnonymize erases personal data of deleted records once the
+	// retention period has passed.
+	ActionAnonymize = "anonymize"
+	// ActionRetain keeps records for the period and never removes them
+	// automatically.
+	ActionRetain = "retain"
+)
+
+// RetentionPolicy says how long deleted records of an entity type are kept
+// before Action is applied.
+type RetentionPolicy struct {
+	EntityType  string `json:"entity_type"`
+	Action      string `json:"action"`
+	Days        int    `json:"days"`
+	Description string `json:"description"`
+}
+
+// Policies returns the retention policy of every entity type that holds
+// personal data. Periods can be overridden with RETENTION_<ENTITY>_DAYS.
+func Policies() []RetentionPolicy {
+	return []RetentionPolicy{
+		{
+			EntityType:  "user",
+			Action:      ActionAnonymize,
+			Days:        retentionDays("user", 30),
+			Description: "Deleted users are anonymized, and may be purged, after the retention period",
+		},
+		{
+			EntityType:  "doctor",
+			Action:      ActionAnonymize,
+			Days:        retentionDays("doctor", 30),
+			Description: "Deleted doctors are anonymized after the retention period",
+		},
+		{
+			EntityType:  "audit_log",
+			Action:      ActionRetain,
+			Days:        retentionDays("audit_log", 3650),
+			Description: "Audit entries are append-only and kept as a legal record; the personal data they hold is encrypted with a key per person that erasing the person destroys",
+		},
+	}
+}
+
+// PolicyFor returns the retention policy of entityType.
+func PolicyFor(entityType string) (RetentionPolicy, bool) {
+	for _, policy := range Policies() {
+		if policy.EntityType == entityType {
+			return policy, true
+		}
+	}
+	return RetentionPolicy{}, false
+}
+
+// RetainedUntil returns when a record deleted at deletedAt leaves its retention period.
+func (p RetentionPolicy) RetainedUntil(deletedAt time.Time) time.Time {
+	return deletedAt.AddDate(0, 0, p.Days)
+}
+
+func retentionDays(entityType string, fallback int) int {
+	key := "RETENTION_" + strings.ToUpper(entityType) + "_DAYS"
+	value := config.GetEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		log.Printf("⚠️ Ignoring invalid %s=%q", key, value)
+		return fallback
+	}
+	return days
+}
+
+// ApplyRetention anonymizes records whose retention period has ended and
+// returns how many were processed per entity type.
+func ApplyRetention(db *gorm.DB, now time.Time) (map[string]int, error) {
+	counts := map[string]int{}
+	for _, policy := range Policies() {
+		if policy.Action != ActionAnonymize {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -policy.Days)
+
+		var n int
+		var err error
+		switch policy.EntityType {
+		case "user":
+			n, err = anonymizeExpiredUsers(db, cutoff)
+		case "doctor":
+			n, err = anonymizeExpiredDoctors(db, cutoff)
+		}
+		counts[policy.EntityType] = n
+		if err != nil {
+			return counts, fmt.Errorf("privacy: retention of %s: %w", policy.EntityType, err)
+		}
+	}
+	return counts, nil
+}
+
+func anonymizeExpiredUsers(db *gorm.DB, cutoff time.Time) (int, error) {
+	var users []models.User
+	err := db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND anonymized_at IS NULL", cutoff).
+		Find(&users).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i, u := range users {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := AnonymizeUser(tx, &u); err != nil {
+				return err
+			}
+			return audit.RecordSystem(tx, audit.Entry{
+				Action:     "user.anonymize",
+				EntityType: "user",
+				EntityID:   u.ID,
+				HospitalID: u.HospitalID,
+			})
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(users), nil
+}
+
+func anonymizeExpiredDoctors(db *gorm.DB, cutoff time.Time) (int, error) {
+	var doctors []models.Doctor
+	err := db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND anonymized_at IS NULL", cutoff).
+		Find(&doctors).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i, d := range doctors {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := AnonymizeDoctor(tx, &d); err != nil {
+				return err
+			}
+			return audit.RecordSystem(tx, audit.Entry{
+				Action:     "doctor.anonymize",
+				EntityType: "doctor",
+				EntityID:   d.ID,
+				HospitalID: d.HospitalID,
+			})
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(doctors), nil
+}
@@ -0,0 +1,269 @@
+// Package registration verifies the contact details of self-registered
+// hospitals and their admins: email addresses with signed links, phone
+// numbers with SMS codes.
+package registration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/i18n"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/notify"
+	"github.com/efecan/vatansoft-case/otp"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// Contacts a registration confirms.
+const (
+	HospitalEmail = "hospital_email"
+	HospitalPhone = "hospital_phone"
+	AdminEmail    = "admin_email"
+	AdminPhone    = "admin_phone"
+)
+
+// Contacts lists every contact in the order they are asked for.
+var Contacts = []string{HospitalEmail, HospitalPhone, AdminEmail, AdminPhone}
+
+// linkAudience keeps verification links from being accepted as anything
+// else signed with the JWT secret, and login tokens from being accepted as
+// links.
+const linkAudience = "registration-email"
+
+var (
+	ErrNotFound    = errors.New("registration: no unverified registration")
+	ErrInvalidLink = errors.New("registration: invalid or expired link")
+	ErrNotPending  = errors.New("registration: contact already verified")
+)
+
+// LinkTTL is how long an email verification link stays valid.
+func LinkTTL() time.Duration {
+	ttl, err := time.ParseDuration(config.GetEnv("EMAIL_VERIFICATION_TTL", "48h"))
+	if err != nil || ttl <= 0 {
+		return 48 * time.Hour
+	}
+	return ttl
+}
+
+// CodeTTL is how long a phone verification code stays valid.
+func CodeTTL() time.Duration {
+	ttl, err := time.ParseDuration(config.GetEnv("PHONE_VERIFICATION_CODE_TTL", "15m"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
+	}
+	return ttl
+}
+
+// Registration is an unverified hospital and the admin who registered it.
+type Registration struct {
+	Hospital models.Hospital
+	Admin    models.User
+}
+
+// Load returns the unverified registration of hospitalID.
+func Load(db *gorm.DB, hospitalID uint) (Registration, error) {
+	var r Registration
+	err := db.Where("status = ?", models.HospitalStatusUnverified).First(&r.Hospital, hospitalID).Error
+	if err == nil {
+		err = db.Where("hospital_id = ? AND status = ?", hospitalID, models.UserStatusUnverified).First(&r.Admin).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r, ErrNotFound
+	}
+	return r, err
+}
+
+// address returns the channel and address contact is confirmed over.
+func (r Registration) address(contact string) (string, string) {
+	switch contact {
+	case HospitalEmail:
+		return notify.ChannelEmail, r.Hospital.Email
+	case HospitalPhone:
+		return notify.ChannelSMS, r.Hospital.Phone
+	case AdminEmail:
+		return notify.ChannelEmail, r.Admin.Email
+	default:
+		return notify.ChannelSMS, r.Admin.Phone
+	}
+}
+
+func (r *Registration) verifiedAt(contact string) **time.Time {
+	switch contact {
+	case HospitalEmail:
+		return &r.Hospital.EmailVerifiedAt
+	case HospitalPhone:
+		return &r.Hospital.PhoneVerifiedAt
+	case AdminEmail:
+		return &r.Admin.EmailVerifiedAt
+	default:
+		return &r.Admin.PhoneVerifiedAt
+	}
+}
+
+// Pending lists the contacts still to be confirmed.
+func (r Registration) Pending() []string {
+	pending := []string{}
+	for _, contact := range Contacts {
+		if *r.verifiedAt(contact) == nil {
+			pending = append(pending, contact)
+		}
+	}
+	return pending
+}
+
+// IsPending reports whether contact is still to be confirmed.
+func (r Registration) IsPending(contact string) bool {
+	for _, p := range r.Pending() {
+		if p == contact {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkVerified confirms every pending contact reached over channel at to, so
+// an address the hospital and its admin share is confirmed once.
+func (r *Registration) MarkVerified(channel, to string, now time.Time) bool {
+	marked := false
+	for _, contact := range r.Pending() {
+		if ch, addr := r.address(contact); ch == channel && addr == to {
+			*r.verifiedAt(contact) = &now
+			marked = true
+		}
+	}
+	return marked
+}
+
+// Save stores the verification times and, once nothing is pending, activates
+// the hospital and its admin and lifts the restrictions of the admin's
+// sessions. It reports whether the registration is complete.
+func (r *Registration) Save(tx *gorm.DB) (bool, error) {
+	complete := len(r.Pending()) == 0
+	if complete {
+		r.Hospital.Status = models.HospitalStatusActive
+		r.Admin.Status = models.UserStatusActive
+	}
+	err := tx.Model(&r.Hospital).Select("email_verified_at", "phone_verified_at", "status").Updates(&r.Hospital).Error
+	if err != nil {
+		return false, err
+	}
+	err = tx.Model(&r.Admin).Select("email_verified_at", "phone_verified_at", "status").Updates(&r.Admin).Error
+	if err != nil || !complete {
+		return complete, err
+	}
+	err = tx.Model(&models.Session{}).Where("user_id = ? AND unverified = ?", r.Admin.ID, true).Update("unverified", false).Error
+	return complete, err
+}
+
+// Send delivers the link or code confirming contact, in the admin's language.
+// A new code replaces the one sent before.
+func (r Registration) Send(ctx context.Context, contact string) error {
+	channel, to := r.address(contact)
+	lang := r.Admin.Language
+	if !i18n.IsSupported(lang) {
+		lang = i18n.Default()
+	}
+	params := map[string]string{"name": r.Admin.Name, "hospital": r.Hospital.Name}
+
+	msg := notify.Message{Channel: channel, To: to}
+	if channel == notify.ChannelEmail {
+		link, err := r.link(to)
+		if err != nil {
+			return err
+		}
+		params["link"] = link
+		params["hours"] = strconv.Itoa(int(LinkTTL().Hours()))
+		msg.Subject = i18n.Message(lang, "registration_email_subject", params)
+		msg.Body = i18n.Message(lang, "registration_email_body", params)
+	} else {
+		ttl := CodeTTL()
+		code, err := otp.Issue(ctx, codeKey(r.Hospital.ID, to), to, ttl)
+		if err != nil {
+			return err
+		}
+		params["code"] = code
+		params["minutes"] = strconv.Itoa(int(ttl.Minutes()))
+		msg.Body = i18n.Message(lang, "registration_code_body", params)
+	}
+	return notify.Send(ctx, msg)
+}
+
+// SendAll delivers a link or code for every pending contact, once per
+// distinct address.
+func (r Registration) SendAll(ctx context.Context) error {
+	sent := map[string]bool{}
+	for _, contact := range r.Pending() {
+		channel, to := r.address(contact)
+		if sent[channel+":"+to] {
+			continue
+		}
+		sent[channel+":"+to] = true
+		if err := r.Send(ctx, contact); err != nil {
+			return fmt.Errorf("%s: %w", contact, err)
+		}
+	}
+	return nil
+}
+
+func codeKey(hospitalID uint, phone string) string {
+	return fmt.Sprintf("registration_code:%d:%s", hospitalID, phone)
+}
+
+// VerifyCode checks the SMS code sent to the phone of contact and confirms
+// it. Wrong codes count towards otp.MaxAttempts.
+func (r *Registration) VerifyCode(ctx context.Context, contact, code string) error {
+	if !r.IsPending(contact) {
+		return ErrNotPending
+	}
+	channel, to := r.address(contact)
+	if channel != notify.ChannelSMS {
+		return ErrNotPending
+	}
+	if _, err := otp.Verify(ctx, codeKey(r.Hospital.ID, to), code); err != nil {
+		return err
+	}
+	r.MarkVerified(channel, to, time.Now().UTC())
+	return nil
+}
+
+func secret() []byte {
+	return []byte(config.GetEnv("JWT_SECRET", "devsecret"))
+}
+
+// link returns the signed URL confirming email belongs to the registration.
+func (r Registration) link(email string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"aud":         linkAudience,
+		"hospital_id": r.Hospital.ID,
+		"email":       email,
+		"exp":         time.Now().Add(LinkTTL()).Unix(),
+	})
+	signed, err := token.SignedString(secret())
+	if err != nil {
+		return "", err
+	}
+	return config.GetEnv("EMAIL_VERIFICATION_URL", "http://localhost:8080/registration/verify-email?token=") + signed, nil
+}
+
+// ParseLink returns the hospital and email address a verification link
+// token confirms.
+func ParseLink(token string) (uint, string, error) {
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) {
+		return secret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(linkAudience), jwt.WithExpirationRequired())
+	if err != nil || !parsed.Valid {
+		return 0, "", ErrInvalidLink
+	}
+	claims := parsed.Claims.(jwt.MapClaims)
+	hospitalID, _ := claims["hospital_id"].(float64)
+	email, _ := claims["email"].(string)
+	if hospitalID == 0 || email == "" {
+		return 0, "", ErrInvalidLink
+	}
+	return uint(hospitalID), email, nil
+}
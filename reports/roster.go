@@ -0,0 +1,125 @@
+package reports
+
+import (
+	_ "embed"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// The PDF core fonts can't encode Turkish letters such as ğ, ş and ı, so the
+// roster embeds DejaVu Sans Condensed (Bitstream Vera license), as shipped
+// with gofpdf.
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	fontRegular []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	fontBold []byte
+)
+
+const fontFamily = "DejaVu"
+
+// RosterHospital is the header printed on every roster page.
+type RosterHospital struct {
+	Name    string
+	Phone   string
+	Email   string
+	Address string
+}
+
+// RosterDepartment is one section of the roster.
+type RosterDepartment struct {
+	Name    string
+	Type    string
+	Members []RosterMember
+}
+
+// RosterMember is one line of a department section.
+type RosterMember struct {
+	Name  string
+	Email string
+}
+
+// RosterLabels are the translated texts of the roster.
+type RosterLabels struct {
+	Title     string
+	Generated string
+	Name      string
+	Email     string
+	Empty     string
+	Page      string
+}
+
+// WriteRosterPDF renders a printable roster with one section per department.
+func WriteRosterPDF(w io.Writer, hospital RosterHospital, departments []RosterDepartment, labels RosterLabels, generatedAt time.Time) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(fontFamily, "", fontRegular)
+	pdf.AddUTF8FontFromBytes(fontFamily, "B", fontBold)
+	pdf.SetTitle(hospital.Name+" - "+labels.Title, true)
+	pdf.SetAutoPageBreak(true, 20)
+
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont(fontFamily, "B", 14)
+		pdf.CellFormat(0, 7, hospital.Name, "", 1, "L", false, 0, "")
+		pdf.SetFont(fontFamily, "", 9)
+		for _, line := range []string{hospital.Address, joinNonEmpty(" · ", hospital.Phone, hospital.Email)} {
+			if line != "" {
+				pdf.CellFormat(0, 5, line, "", 1, "L", false, 0, "")
+			}
+		}
+		pdf.Ln(2)
+		pdf.SetFont(fontFamily, "B", 12)
+		pdf.CellFormat(0, 7, labels.Title, "B", 1, "L", false, 0, "")
+		pdf.Ln(3)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont(fontFamily, "", 8)
+		pdf.CellFormat(0, 5, labels.Generated+" "+generatedAt.Format("02.01.2006 15:04"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, labels.Page+" "+strconv.Itoa(pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	for _, dept := range departments {
+		// Keep a department heading together with at least its first lines.
+		if pdf.GetY() > 250 {
+			pdf.AddPage()
+		}
+		pdf.SetFont(fontFamily, "B", 11)
+		heading := dept.Name
+		if dept.Type != "" && dept.Type != dept.Name {
+			heading += " (" + dept.Type + ")"
+		}
+		pdf.CellFormat(0, 7, heading, "", 1, "L", false, 0, "")
+
+		pdf.SetFont(fontFamily, "B", 9)
+		pdf.SetFillColor(235, 235, 235)
+		pdf.CellFormat(80, 6, labels.Name, "1", 0, "L", true, 0, "")
+		pdf.CellFormat(0, 6, labels.Email, "1", 1, "L", true, 0, "")
+
+		pdf.SetFont(fontFamily, "", 9)
+		if len(dept.Members) == 0 {
+			pdf.CellFormat(0, 6, labels.Empty, "1", 1, "L", false, 0, "")
+		}
+		for _, m := range dept.Members {
+			pdf.CellFormat(80, 6, m.Name, "1", 0, "L", false, 0, "")
+			pdf.CellFormat(0, 6, m.Email, "1", 1, "L", false, 0, "")
+		}
+		pdf.Ln(4)
+	}
+
+	return pdf.Output(w)
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, sep)
+}
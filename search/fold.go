@@ -0,0 +1,57 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxTerms caps how many words of a query are matched, so a pasted paragraph
+// doesn't turn into dozens of similarity checks per row.
+const maxTerms = 5
+
+// Fold lowercases s and strips Turkish diacritics, so "Işıl Şahin", "ISIL
+// SAHIN" and "isil sahin" compare equal. It maps every rune to exactly one
+// rune and must stay in step with the tr_fold SQL function.
+func Fold(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+func foldRune(r rune) rune {
+	switch r {
+	case 'İ', 'I', 'ı':
+		return 'i'
+	case 'Ş', 'ş':
+		return 's'
+	case 'Ğ', 'ğ':
+		return 'g'
+	case 'Ü', 'ü':
+		return 'u'
+	case 'Ö', 'ö':
+		return 'o'
+	case 'Ç', 'ç':
+		return 'c'
+	}
+	return unicode.ToLower(r)
+}
+
+// Terms splits a query into folded, de-duplicated words. Punctuation separates
+// words, so "ayse.yilmaz@x.com" searches for its parts like pg_trgm does.
+func Terms(q string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(Fold(q), isSeparator) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
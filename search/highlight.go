@@ -0,0 +1,107 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+// Highlight wraps the words of text that match one of the terms in <mark>
+// tags and HTML-escapes the rest. Words match when they contain a term or are
+// within a few typos of it, mirroring the tolerance of the database search.
+// It returns "" when nothing matches.
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	folded := []rune(Fold(text))
+
+	var b strings.Builder
+	marked := false
+	last := 0
+	for start := 0; start < len(runes); {
+		if isSeparator(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && !isSeparator(runes[end]) {
+			end++
+		}
+		if matchesAny(string(folded[start:end]), terms) {
+			b.WriteString(html.EscapeString(string(runes[last:start])))
+			b.WriteString(markOpen)
+			b.WriteString(html.EscapeString(string(runes[start:end])))
+			b.WriteString(markClose)
+			last = end
+			marked = true
+		}
+		start = end
+	}
+	if !marked {
+		return ""
+	}
+	b.WriteString(html.EscapeString(string(runes[last:])))
+	return b.String()
+}
+
+func matchesAny(word string, terms []string) bool {
+	for _, term := range terms {
+		if matches(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a folded word matches a folded term, either
+// literally or, for longer terms, with a small edit distance to the word or to
+// its prefix of the same length (to catch typos in a partially typed word).
+func matches(word, term string) bool {
+	if strings.Contains(word, term) {
+		return true
+	}
+	w, t := []rune(word), []rune(term)
+	allowed := allowedEdits(len(t))
+	if allowed == 0 {
+		return false
+	}
+	if distance(w, t) <= allowed {
+		return true
+	}
+	return len(w) > len(t) && distance(w[:len(t)], t) <= allowed
+}
+
+func allowedEdits(length int) int {
+	switch {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
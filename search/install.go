@@ -0,0 +1,76 @@
+package search
+
+import "gorm.io/gorm"
+
+// Install sets up the database side of search: the pg_trgm extension, the
+// tr_fold function mirroring Fold, triggers that keep the search_document
+// column of users and doctors up to date (including the title and department
+// names they show), and trigram indexes over those columns. It is safe to run
+// on every start.
+func Install(db *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE OR REPLACE FUNCTION tr_fold(text) RETURNS text AS $$
+			SELECT lower(translate($1, 'İIıŞşĞğÜüÖöÇç', 'iiissgguuoocc'))
+		$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE`,
+
+		`CREATE OR REPLACE FUNCTION users_search_document() RETURNS trigger AS $$
+		BEGIN
+			NEW.search_document := tr_fold(concat_ws(' ', NEW.name, NEW.surname, NEW.email,
+				(SELECT name FROM titles WHERE id = NEW.title_id)));
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS users_search_document ON users`,
+		`CREATE TRIGGER users_search_document
+			BEFORE INSERT OR UPDATE OF name, surname, email, title_id ON users
+			FOR EACH ROW EXECUTE FUNCTION users_search_document()`,
+
+		`CREATE OR REPLACE FUNCTION doctors_search_document() RETURNS trigger AS $$
+		BEGIN
+			NEW.search_document := tr_fold(concat_ws(' ', NEW.name, NEW.email,
+				(SELECT name FROM departments WHERE id = NEW.department_id)));
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS doctors_search_document ON doctors`,
+		`CREATE TRIGGER doctors_search_document
+			BEFORE INSERT OR UPDATE OF name, email, department_id ON doctors
+			FOR EACH ROW EXECUTE FUNCTION doctors_search_document()`,
+
+		// Renaming a title or department refreshes the documents that embed it.
+		`CREATE OR REPLACE FUNCTION titles_refresh_search() RETURNS trigger AS $$
+		BEGIN
+			UPDATE users SET title_id = title_id WHERE title_id = NEW.id;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS titles_refresh_search ON titles`,
+		`CREATE TRIGGER titles_refresh_search
+			AFTER UPDATE OF name ON titles
+			FOR EACH ROW EXECUTE FUNCTION titles_refresh_search()`,
+		`CREATE OR REPLACE FUNCTION departments_refresh_search() RETURNS trigger AS $$
+		BEGIN
+			UPDATE doctors SET department_id = department_id WHERE department_id = NEW.id;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS departments_refresh_search ON departments`,
+		`CREATE TRIGGER departments_refresh_search
+			AFTER UPDATE OF name ON departments
+			FOR EACH ROW EXECUTE FUNCTION departments_refresh_search()`,
+
+		`CREATE INDEX IF NOT EXISTS idx_users_search_document ON users USING gin (search_document gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_doctors_search_document ON doctors USING gin (search_document gin_trgm_ops)`,
+
+		// Fill in rows written before the triggers existed.
+		`UPDATE users SET name = name WHERE search_document IS NULL`,
+		`UPDATE doctors SET name = name WHERE search_document IS NULL`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
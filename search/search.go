@@ -0,0 +1,103 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Record types a search can cover.
+const (
+	TypeUser   = "user"
+	TypeDoctor = "doctor"
+)
+
+// Types lists every searchable record type.
+var Types = []string{TypeUser, TypeDoctor}
+
+// sources describes how each record type is searched. Every query selects the
+// same columns so the results can be merged with UNION ALL.
+var sources = map[string]struct {
+	from   string
+	fields string
+}{
+	TypeUser: {
+		from:   "users AS r LEFT JOIN titles AS t ON t.id = r.title_id",
+		fields: "COALESCE(r.surname, '') AS surname, COALESCE(t.name, '') AS title, '' AS department",
+	},
+	TypeDoctor: {
+		from:   "doctors AS r LEFT JOIN departments AS d ON d.id = r.department_id",
+		fields: "'' AS surname, '' AS title, COALESCE(d.name, '') AS department",
+	},
+}
+
+// Hit is a single search result.
+type Hit struct {
+	Type       string
+	ID         uint
+	Name       string
+	Surname    string
+	Email      string
+	Title      string
+	Department string
+	Score      float64
+}
+
+// Options narrows a search.
+type Options struct {
+	HospitalID uint
+	Types      []string
+	Limit      int
+	// Threshold is the pg_trgm word similarity (0-1) every term must reach.
+	// Lower values tolerate more typos.
+	Threshold float64
+}
+
+// Run searches the non-deleted records of a hospital for terms (see Terms)
+// and returns them best match first. Every term has to match the record's
+// name, surname, email, title or department, allowing for typos; the score is
+// the summed word similarity of the terms.
+func Run(db *gorm.DB, terms []string, opts Options) ([]Hit, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var selects []string
+	var args []interface{}
+	for _, typ := range opts.Types {
+		source, ok := sources[typ]
+		if !ok {
+			return nil, fmt.Errorf("search: unknown type %q", typ)
+		}
+		scores := make([]string, len(terms))
+		conds := make([]string, len(terms))
+		var scoreArgs, condArgs []interface{}
+		for i, term := range terms {
+			scores[i] = "word_similarity(?, r.search_document)"
+			conds[i] = "? <% r.search_document"
+			scoreArgs = append(scoreArgs, term)
+			condArgs = append(condArgs, term)
+		}
+		selects = append(selects, fmt.Sprintf(
+			"SELECT '%s' AS type, r.id AS id, r.name AS name, r.email AS email, %s, %s AS score "+
+				"FROM %s WHERE r.deleted_at IS NULL AND r.hospital_id = ? AND %s",
+			typ, source.fields, strings.Join(scores, " + "), source.from, strings.Join(conds, " AND "),
+		))
+		args = append(args, scoreArgs...)
+		args = append(args, opts.HospitalID)
+		args = append(args, condArgs...)
+	}
+	query := strings.Join(selects, " UNION ALL ") + " ORDER BY score DESC, type, id LIMIT ?"
+	args = append(args, opts.Limit)
+
+	var hits []Hit
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// SET LOCAL only takes literals; the threshold is a float we format.
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL pg_trgm.word_similarity_threshold = %g", opts.Threshold)).Error; err != nil {
+			return err
+		}
+		return tx.Raw(query, args...).Scan(&hits).Error
+	})
+	return hits, err
+}
@@ -0,0 +1,199 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/models"
+	"gorm.io/gorm"
+)
+
+// touchInterval is how stale LastSeenAt may get before a request refreshes
+// it, so busy clients don't write on every call.
+const touchInterval = time.Minute
+
+// maxUserAgent bounds what a client can make us store.
+const maxUserAgent = 512
+
+var (
+	ErrNotFound = errors.New("session: not found")
+	ErrRevoked  = errors.New("session: revoked")
+	ErrExpired  = errors.New("session: expired")
+)
+
+// TTL is how long a login lasts. The token expires together with its session.
+func TTL() time.Duration {
+	ttl, err := time.ParseDuration(config.GetEnv("SESSION_TTL", "72h"))
+	if err != nil || ttl <= 0 {
+		return 72 * time.Hour
+	}
+	return ttl
+}
+
+// ImpersonationTTL is how long a platform admin may act as another user
+// before having to start over.
+func ImpersonationTTL() time.Duration {
+	ttl, err := time.ParseDuration(config.GetEnv("IMPERSONATION_TTL", "30m"))
+	if err != nil || ttl <= 0 {
+		return 30 * time.Minute
+	}
+	return ttl
+}
+
+// Start records a new login of u. device is the name the client gave itself;
+// without one it is derived from the user agent.
+func Start(db *gorm.DB, u models.User, device, userAgent, ip string) (models.Session, error) {
+	if device = strings.TrimSpace(device); device == "" {
+		device = DeviceName(userAgent)
+	}
+	s := models.Session{
+		UserID:     u.ID,
+		HospitalID: u.HospitalID,
+		Device:     device,
+		Unverified: u.Status == models.UserStatusUnverified,
+	}
+	return start(db, s, userAgent, ip, TTL())
+}
+
+// StartImpersonation records a session in which impersonator acts as u. It
+// lasts ImpersonationTTL and shows up among u's sessions.
+func StartImpersonation(db *gorm.DB, u, impersonator models.User, userAgent, ip string) (models.Session, error) {
+	s := models.Session{
+		UserID:         u.ID,
+		HospitalID:     u.HospitalID,
+		Device:         "Support (" + DeviceName(userAgent) + ")",
+		ImpersonatorID: impersonator.ID,
+	}
+	return start(db, s, userAgent, ip, ImpersonationTTL())
+}
+
+func start(db *gorm.DB, s models.Session, userAgent, ip string, ttl time.Duration) (models.Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return s, err
+	}
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
+	now := time.Now().UTC()
+	s.TokenID = hex.EncodeToString(id)
+	s.UserAgent = userAgent
+	s.IP = ip
+	s.CreatedAt = now
+	s.LastSeenAt = now
+	s.ExpiresAt = now.Add(ttl)
+	return s, db.Create(&s).Error
+}
+
+// Check returns the live session of userID with the given token ID and
+// refreshes its last-seen time and IP.
+func Check(db *gorm.DB, tokenID string, userID uint, ip string) (models.Session, error) {
+	var s models.Session
+	err := db.Where("token_id = ? AND user_id = ?", tokenID, userID).First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s, ErrNotFound
+	}
+	if err != nil {
+		return s, err
+	}
+	now := time.Now().UTC()
+	if s.RevokedAt != nil {
+		return s, ErrRevoked
+	}
+	if now.After(s.ExpiresAt) {
+		return s, ErrExpired
+	}
+	if now.Sub(s.LastSeenAt) >= touchInterval || s.IP != ip {
+		s.LastSeenAt, s.IP = now, ip
+		// A failed refresh only makes the session look idle; don't fail the request.
+		if err := db.Model(&s).Select("last_seen_at", "ip").Updates(&s).Error; err != nil {
+			log.Printf("⚠️ Could not update session %d: %v", s.ID, err)
+		}
+	}
+	return s, nil
+}
+
+// Active scopes a query to sessions that are neither revoked nor expired.
+func Active(db *gorm.DB) *gorm.DB {
+	return db.Where("revoked_at IS NULL AND expires_at > ?", time.Now().UTC())
+}
+
+// Revoke ends the active sessions matched by query on behalf of revokedBy and
+// returns them as they were before. Run it in the caller's transaction.
+func Revoke(query *gorm.DB, revokedBy uint) ([]models.Session, error) {
+	var sessions []models.Session
+	if err := query.Scopes(Active).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return sessions, nil
+	}
+	ids := make([]uint, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.ID
+	}
+	err := query.Session(&gorm.Session{NewDB: true}).Model(&models.Session{}).Where("id IN ?", ids).
+		Updates(map[string]interface{}{"revoked_at": time.Now().UTC(), "revoked_by_id": revokedBy}).Error
+	return sessions, err
+}
+
+// RevokeUser ends every active session of userID except keep, which may be 0.
+func RevokeUser(tx *gorm.DB, userID, keep, revokedBy uint) ([]models.Session, error) {
+	query := tx.Where("user_id = ?", userID)
+	if keep != 0 {
+		query = query.Where("id <> ?", keep)
+	}
+	return Revoke(query, revokedBy)
+}
+
+// DeviceName gives a short description like "Chrome on Windows" of the client
+// behind userAgent.
+func DeviceName(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	browser := ""
+	for _, b := range []struct{ token, name string }{
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"firefox/", "Firefox"},
+		{"chrome/", "Chrome"},
+		{"crios/", "Chrome"},
+		{"safari/", "Safari"},
+		{"okhttp", "Android app"},
+		{"curl/", "curl"},
+		{"postman", "Postman"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	system := ""
+	for _, o := range []struct{ token, name string }{
+		{"windows", "Windows"},
+		{"iphone", "iPhone"},
+		{"ipad", "iPad"},
+		{"android", "Android"},
+		{"mac os x", "macOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			system = o.name
+			break
+		}
+	}
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
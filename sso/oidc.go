@@ -0,0 +1,307 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/efecan/vatansoft-case/netguard"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+)
+
+// ProviderOIDC names OIDC logins in models.ExternalIdentity.
+const ProviderOIDC = "oidc"
+
+// stateTTL is how long a user has to finish signing in at their IdP.
+const stateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownState = errors.New("sso: unknown or expired login state")
+	ErrNoRole       = errors.New("sso: no role maps to the user")
+	ErrNoEmail      = errors.New("sso: the ID token has no verified email")
+)
+
+// Identity is what the IdP told us about the user who signed in.
+type Identity struct {
+	Issuer  string
+	Subject string
+	Email   string
+	Name    string
+	Surname string
+	Phone   string
+	Roles   []string
+}
+
+// pending is the login state kept between Begin and Finish.
+type pending struct {
+	HospitalID uint   `json:"hospital_id"`
+	Verifier   string `json:"verifier"`
+	Nonce      string `json:"nonce"`
+}
+
+// failureTTL is how long a failed discovery is remembered, so an IdP that is
+// down isn't asked again on every login attempt.
+const failureTTL = 30 * time.Second
+
+// failure is a discovery that failed recently.
+type failure struct {
+	err   error
+	until time.Time
+}
+
+var (
+	providersMu sync.Mutex
+	providers   = map[string]*oidc.Provider{}
+	failures    = map[string]failure{}
+	// fetches shares one discovery request among concurrent logins to the
+	// same issuer.
+	fetches singleflight.Group
+)
+
+// httpClient bounds calls to identity providers and keeps them off our own
+// network, since hospital admins choose the issuer.
+var httpClient = netguard.HTTPClient(10 * time.Second)
+
+// discover returns the provider of issuer, fetching its discovery document
+// once. The fetch runs outside the lock so a slow IdP only holds up logins to
+// itself. The background context keeps the provider's key set usable after
+// the request that created it ends.
+func discover(issuer string) (*oidc.Provider, error) {
+	providersMu.Lock()
+	p, ok := providers[issuer]
+	f, failed := failures[issuer]
+	providersMu.Unlock()
+	if ok {
+		return p, nil
+	}
+	if failed && time.Now().Before(f.until) {
+		return nil, f.err
+	}
+
+	v, err, _ := fetches.Do(issuer, func() (interface{}, error) {
+		p, err := oidc.NewProvider(oidc.ClientContext(context.Background(), httpClient), issuer)
+		providersMu.Lock()
+		defer providersMu.Unlock()
+		if err != nil {
+			failures[issuer] = failure{err: err, until: time.Now().Add(failureTTL)}
+			return nil, err
+		}
+		delete(failures, issuer)
+		providers[issuer] = p
+		return p, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*oidc.Provider), nil
+}
+
+// RedirectURL is our callback the IdP sends users back to. It has to be
+// registered with every hospital's IdP.
+func RedirectURL() string {
+	return config.GetEnv("OIDC_REDIRECT_URL", "http://localhost:8080/auth/oidc/callback")
+}
+
+func oauthConfig(p *oidc.Provider, cfg models.OIDCProvider) oauth2.Config {
+	scopes := []string{oidc.ScopeOpenID, "email", "profile"}
+	for _, s := range cfg.Scopes {
+		if s != oidc.ScopeOpenID && s != "email" && s != "profile" {
+			scopes = append(scopes, s)
+		}
+	}
+	return oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Endpoint:     p.Endpoint(),
+		RedirectURL:  RedirectURL(),
+		Scopes:       scopes,
+	}
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func stateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+// Begin starts an authorization code login with PKCE at the hospital's IdP
+// and returns the URL to send the user to.
+func Begin(ctx context.Context, cfg models.OIDCProvider) (string, error) {
+	p, err := discover(cfg.Issuer)
+	if err != nil {
+		return "", err
+	}
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	data, err := json.Marshal(pending{HospitalID: cfg.HospitalID, Verifier: verifier, Nonce: nonce})
+	if err != nil {
+		return "", err
+	}
+	if err := config.REDIS.Set(ctx, stateKey(state), data, stateTTL).Err(); err != nil {
+		return "", err
+	}
+
+	oc := oauthConfig(p, cfg)
+	return oc.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// HospitalOf returns the hospital a login state belongs to, so the caller can
+// load its provider before calling Finish. The state stays valid.
+func HospitalOf(ctx context.Context, state string) (uint, error) {
+	p, err := loadState(ctx, state, false)
+	return p.HospitalID, err
+}
+
+func loadState(ctx context.Context, state string, consume bool) (pending, error) {
+	var p pending
+	var data []byte
+	var err error
+	if consume {
+		data, err = config.REDIS.GetDel(ctx, stateKey(state)).Bytes()
+	} else {
+		data, err = config.REDIS.Get(ctx, stateKey(state)).Bytes()
+	}
+	if errors.Is(err, redis.Nil) {
+		return p, ErrUnknownState
+	}
+	if err != nil {
+		return p, err
+	}
+	return p, json.Unmarshal(data, &p)
+}
+
+// Finish completes the login started by Begin: it consumes the state, trades
+// code for tokens with the PKCE verifier and verifies the ID token's
+// signature, audience, expiry and nonce.
+func Finish(ctx context.Context, cfg models.OIDCProvider, state, code string) (Identity, error) {
+	var id Identity
+	st, err := loadState(ctx, state, true)
+	if err != nil {
+		return id, err
+	}
+	if st.HospitalID != cfg.HospitalID {
+		return id, ErrUnknownState
+	}
+	p, err := discover(cfg.Issuer)
+	if err != nil {
+		return id, err
+	}
+
+	ctx = oidc.ClientContext(ctx, httpClient)
+	oc := oauthConfig(p, cfg)
+	token, err := oc.Exchange(ctx, code, oauth2.VerifierOption(st.Verifier))
+	if err != nil {
+		return id, fmt.Errorf("sso: code exchange: %w", err)
+	}
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		return id, errors.New("sso: no id_token in token response")
+	}
+	idToken, err := p.Verifier(&oidc.Config{ClientID: cfg.ClientID}).Verify(ctx, raw)
+	if err != nil {
+		return id, fmt.Errorf("sso: verify id_token: %w", err)
+	}
+	if idToken.Nonce != st.Nonce {
+		return id, errors.New("sso: nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return id, err
+	}
+	return mapClaims(cfg.Claims, idToken.Issuer, idToken.Subject, claims)
+}
+
+func mapClaims(m models.OIDCClaimMapping, issuer, subject string, claims map[string]interface{}) (Identity, error) {
+	name := func(configured, standard string) string {
+		if configured != "" {
+			return configured
+		}
+		return standard
+	}
+	str := func(claim string) string {
+		s, _ := claims[claim].(string)
+		return strings.TrimSpace(s)
+	}
+
+	id := Identity{
+		Issuer:  issuer,
+		Subject: subject,
+		Email:   strings.ToLower(str(name(m.Email, "email"))),
+		Name:    str(name(m.Name, "given_name")),
+		Surname: str(name(m.Surname, "family_name")),
+		Phone:   str(name(m.Phone, "phone_number")),
+	}
+	// An IdP that says the address is unverified can't vouch for it.
+	if verified, ok := claims["email_verified"].(bool); id.Email == "" || (ok && !verified) {
+		return id, ErrNoEmail
+	}
+	switch roles := claims[name(m.Roles, "groups")].(type) {
+	case string:
+		id.Roles = strings.Fields(roles)
+	case []interface{}:
+		for _, r := range roles {
+			if s, ok := r.(string); ok {
+				id.Roles = append(id.Roles, s)
+			}
+		}
+	}
+	return id, nil
+}
+
+// Role picks the user's role from their IdP roles using the provider's
+// mapping, falling back to its default role.
+func Role(cfg models.OIDCProvider, id Identity) (string, error) {
+	for _, r := range id.Roles {
+		if role, ok := cfg.RoleMapping[r]; ok && role != "" {
+			return strings.ToLower(role), nil
+		}
+	}
+	if cfg.DefaultRole != "" {
+		return strings.ToLower(cfg.DefaultRole), nil
+	}
+	return "", ErrNoRole
+}
+
+// Forget drops the cached discovery document or failure of issuer, e.g.
+// after a hospital changed its provider settings.
+func Forget(issuer string) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	delete(providers, issuer)
+	delete(failures, issuer)
+	fetches.Forget(issuer)
+}
+
+// Check fetches the discovery document of issuer afresh, so a misconfigured
+// provider is caught when it is saved rather than at the next login.
+func Check(issuer string) error {
+	Forget(issuer)
+	_, err := discover(issuer)
+	return err
+}
@@ -0,0 +1,516 @@
+package sso
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/efecan/vatansoft-case/config"
+	"github.com/efecan/vatansoft-case/models"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	testClientID     = "hbys"
+	testClientSecret = "secret"
+)
+
+// grant is an authorization the stub IdP handed out a code for.
+type grant struct {
+	challenge string
+	nonce     string
+	claims    map[string]interface{}
+}
+
+// stubIdP serves discovery, a key set and a token endpoint that checks the
+// PKCE verifier, signing ID tokens with its own key.
+type stubIdP struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]grant
+	issued int
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &stubIdP{key: key, codes: map[string]grant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/keys", idp.keys)
+	mux.HandleFunc("/token", idp.token)
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	// The stub runs on loopback, which netguard refuses otherwise.
+	t.Setenv("INTERNAL_NETWORKS_ALLOWED", "127.0.0.1")
+	return idp
+}
+
+func (idp *stubIdP) issuer() string {
+	return idp.srv.URL
+}
+
+func (idp *stubIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                idp.issuer(),
+		"authorization_endpoint":                idp.issuer() + "/authorize",
+		"token_endpoint":                        idp.issuer() + "/token",
+		"jwks_uri":                              idp.issuer() + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (idp *stubIdP) keys(w http.ResponseWriter, r *http.Request) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   b64(idp.key.N.Bytes()),
+			"e":   b64(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (idp *stubIdP) token(w http.ResponseWriter, r *http.Request) {
+	fail := func(code string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+	if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "authorization_code" {
+		fail("invalid_request")
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.Form.Get("client_id"), r.Form.Get("client_secret")
+	}
+	if clientID != testClientID || secret != testClientSecret {
+		fail("invalid_client")
+		return
+	}
+
+	idp.mu.Lock()
+	g, ok := idp.codes[r.Form.Get("code")]
+	delete(idp.codes, r.Form.Get("code"))
+	idp.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		fail("invalid_grant")
+		return
+	}
+
+	claims := map[string]interface{}{
+		"iss":   idp.issuer(),
+		"sub":   "user-1",
+		"aud":   testClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": g.nonce,
+	}
+	for k, v := range g.claims {
+		claims[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idp.sign(claims),
+	})
+}
+
+func (idp *stubIdP) sign(claims map[string]interface{}) string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+	sum := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, sum[:])
+	if err != nil {
+		panic(err)
+	}
+	return input + "." + b64(sig)
+}
+
+// authorize plays the user signing in at the IdP: it records the PKCE
+// challenge and nonce of the authorization URL and returns the state and the
+// code the IdP would redirect back with. edit can tamper with the grant.
+func (idp *stubIdP) authorize(t *testing.T, authURL string, claims map[string]interface{}, edit func(*grant)) (string, string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("response_type") != "code" || q.Get("client_id") != testClientID {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL %s doesn't use PKCE with S256", authURL)
+	}
+	if q.Get("state") == "" || q.Get("nonce") == "" || q.Get("redirect_uri") != RedirectURL() {
+		t.Fatalf("authorization URL %s lacks state, nonce or redirect_uri", authURL)
+	}
+
+	g := grant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), claims: claims}
+	if edit != nil {
+		edit(&g)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.issued++
+	code := fmt.Sprintf("code-%d", idp.issued)
+	idp.codes[code] = g
+	return q.Get("state"), code
+}
+
+func setupRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	previous := config.REDIS
+	config.REDIS = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		config.REDIS.Close()
+		config.REDIS = previous
+	})
+}
+
+func testProvider(idp *stubIdP) models.OIDCProvider {
+	return models.OIDCProvider{
+		HospitalID:   7,
+		Enabled:      true,
+		Issuer:       idp.issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+	}
+}
+
+var verifiedClaims = map[string]interface{}{
+	"email":          "Ayse.Yilmaz@Example.com",
+	"email_verified": true,
+	"given_name":     "Ayşe",
+	"family_name":    "Yılmaz",
+	"groups":         []string{"staff", "doctors"},
+}
+
+func TestBeginFinish(t *testing.T) {
+	setupRedis(t)
+	idp := newStubIdP(t)
+	cfg := testProvider(idp)
+	ctx := context.Background()
+
+	authURL, err := Begin(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := idp.authorize(t, authURL, verifiedClaims, nil)
+
+	hospitalID, err := HospitalOf(ctx, state)
+	if err != nil || hospitalID != cfg.HospitalID {
+		t.Fatalf("HospitalOf = %d, %v; want %d", hospitalID, err, cfg.HospitalID)
+	}
+
+	id, err := Finish(ctx, cfg, state, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{
+		Issuer:  idp.issuer(),
+		Subject: "user-1",
+		Email:   "ayse.yilmaz@example.com",
+		Name:    "Ayşe",
+		Surname: "Yılmaz",
+		Roles:   []string{"staff", "doctors"},
+	}
+	if !reflect.DeepEqual(id, want) {
+		t.Fatalf("Finish = %+v, want %+v", id, want)
+	}
+}
+
+func TestFinishConsumesState(t *testing.T) {
+	setupRedis(t)
+	idp := newStubIdP(t)
+	cfg := testProvider(idp)
+	ctx := context.Background()
+
+	authURL, err := Begin(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := idp.authorize(t, authURL, verifiedClaims, nil)
+	if _, err := Finish(ctx, cfg, state, code); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replaying the callback fails even with a code the IdP would accept.
+	_, replay := idp.authorize(t, authURL, verifiedClaims, nil)
+	if _, err := Finish(ctx, cfg, state, replay); !errors.Is(err, ErrUnknownState) {
+		t.Fatalf("replayed state: got %v, want ErrUnknownState", err)
+	}
+	if _, err := HospitalOf(ctx, state); !errors.Is(err, ErrUnknownState) {
+		t.Fatalf("HospitalOf after Finish: got %v, want ErrUnknownState", err)
+	}
+	if _, err := Finish(ctx, cfg, "unknown", code); !errors.Is(err, ErrUnknownState) {
+		t.Fatalf("unknown state: got %v, want ErrUnknownState", err)
+	}
+}
+
+func TestFinishOtherHospital(t *testing.T) {
+	setupRedis(t)
+	idp := newStubIdP(t)
+	cfg := testProvider(idp)
+	ctx := context.Background()
+
+	authURL, err := Begin(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := idp.authorize(t, authURL, verifiedClaims, nil)
+	other := cfg
+	other.HospitalID = 8
+	if _, err := Finish(ctx, other, state, code); !errors.Is(err, ErrUnknownState) {
+		t.Fatalf("got %v, want ErrUnknownState", err)
+	}
+}
+
+func TestFinishRejects(t *testing.T) {
+	unverified := map[string]interface{}{"email": "ayse@example.com", "email_verified": false}
+	tests := []struct {
+		name    string
+		claims  map[string]interface{}
+		edit    func(*grant)
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:   "PKCE verifier doesn't match the challenge",
+			claims: verifiedClaims,
+			edit: func(g *grant) {
+				sum := sha256.Sum256([]byte("another verifier"))
+				g.challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+			},
+			wantMsg: "code exchange",
+		},
+		{
+			name:    "nonce mismatch",
+			claims:  verifiedClaims,
+			edit:    func(g *grant) { g.nonce = "replayed-nonce" },
+			wantMsg: "nonce mismatch",
+		},
+		{
+			name:    "unverified email",
+			claims:  unverified,
+			wantErr: ErrNoEmail,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRedis(t)
+			idp := newStubIdP(t)
+			cfg := testProvider(idp)
+			ctx := context.Background()
+
+			authURL, err := Begin(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, code := idp.authorize(t, authURL, tt.claims, tt.edit)
+			_, err = Finish(ctx, cfg, state, code)
+			if err == nil {
+				t.Fatal("Finish succeeded")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantMsg != "" && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Fatalf("got %v, want an error about %s", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestMapClaims(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping models.OIDCClaimMapping
+		claims  map[string]interface{}
+		want    Identity
+		wantErr error
+	}{
+		{
+			name: "standard claims",
+			claims: map[string]interface{}{
+				"email":        " Ali@Example.com ",
+				"given_name":   "Ali",
+				"family_name":  "Kaya",
+				"phone_number": "+905551112233",
+				"groups":       []interface{}{"nurses", 42, "staff"},
+			},
+			want: Identity{Email: "ali@example.com", Name: "Ali", Surname: "Kaya", Phone: "+905551112233", Roles: []string{"nurses", "staff"}},
+		},
+		{
+			name:    "configured claims",
+			mapping: models.OIDCClaimMapping{Email: "mail", Name: "first", Surname: "last", Phone: "mobile", Roles: "roles"},
+			claims: map[string]interface{}{
+				"mail":           "ali@example.com",
+				"email_verified": true,
+				"first":          "Ali",
+				"last":           "Kaya",
+				"mobile":         "+905551112233",
+				"roles":          "admin staff",
+				"groups":         []interface{}{"ignored"},
+			},
+			want: Identity{Email: "ali@example.com", Name: "Ali", Surname: "Kaya", Phone: "+905551112233", Roles: []string{"admin", "staff"}},
+		},
+		{
+			name:    "no email",
+			claims:  map[string]interface{}{"given_name": "Ali"},
+			wantErr: ErrNoEmail,
+		},
+		{
+			name:    "unverified email",
+			claims:  map[string]interface{}{"email": "ali@example.com", "email_verified": false},
+			wantErr: ErrNoEmail,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := mapClaims(tt.mapping, "https://idp.example.com", "sub-1", tt.claims)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			tt.want.Issuer, tt.want.Subject = "https://idp.example.com", "sub-1"
+			if !reflect.DeepEqual(id, tt.want) {
+				t.Fatalf("got %+v, want %+v", id, tt.want)
+			}
+		})
+	}
+}
+
+func TestRole(t *testing.T) {
+	mapping := map[string]string{"hbys-admins": "Admin", "hbys-staff": "staff", "disabled": ""}
+	tests := []struct {
+		name        string
+		defaultRole string
+		roles       []string
+		want        string
+		wantErr     error
+	}{
+		{name: "first mapped role wins", roles: []string{"other", "hbys-staff", "hbys-admins"}, want: "staff"},
+		{name: "mapped roles are lower-cased", roles: []string{"hbys-admins"}, want: "admin"},
+		{name: "empty mappings are skipped", roles: []string{"disabled", "hbys-staff"}, want: "staff"},
+		{name: "default role", defaultRole: "Staff", roles: []string{"other"}, want: "staff"},
+		{name: "no role", roles: []string{"other", "disabled"}, wantErr: ErrNoRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := models.OIDCProvider{RoleMapping: mapping, DefaultRole: tt.defaultRole}
+			role, err := Role(cfg, Identity{Roles: tt.roles})
+			if !errors.Is(err, tt.wantErr) || role != tt.want {
+				t.Fatalf("got %q, %v; want %q, %v", role, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiscoverSharesAndRemembersFetches(t *testing.T) {
+	var mu sync.Mutex
+	hits, down := 0, true
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		failing := down
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		if failing {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/keys",
+		})
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("INTERNAL_NETWORKS_ALLOWED", "127.0.0.1")
+	t.Cleanup(func() { Forget(srv.URL) })
+	discoverAll := func() []error {
+		errs := make([]error, 5)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = discover(srv.URL)
+			}(i)
+		}
+		wg.Wait()
+		return errs
+	}
+	wantHits := func(t *testing.T, want int) {
+		t.Helper()
+		mu.Lock()
+		defer mu.Unlock()
+		if hits != want {
+			t.Fatalf("discovery was fetched %d times, want %d", hits, want)
+		}
+	}
+
+	for _, err := range discoverAll() {
+		if err == nil {
+			t.Fatal("discovery of an issuer that is down succeeded")
+		}
+	}
+	wantHits(t, 1)
+	// The failure is remembered rather than fetched again.
+	if _, err := discover(srv.URL); err == nil {
+		t.Fatal("remembered failure was lost")
+	}
+	wantHits(t, 1)
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	Forget(srv.URL)
+	for _, err := range discoverAll() {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wantHits(t, 2)
+	if _, err := discover(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	wantHits(t, 2)
+}
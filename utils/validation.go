@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// IsValidTCKN checks a Turkish national ID number: 11 digits, no leading zero,
+// and the two trailing check digits from the official algorithm.
+func IsValidTCKN(tckn string) bool {
+	if len(tckn) != 11 || tckn[0] == '0' {
+		return false
+	}
+
+	var d [11]int
+	for i, r := range tckn {
+		if r < '0' || r > '9' {
+			return false
+		}
+		d[i] = int(r - '0')
+	}
+
+	odd := d[0] + d[2] + d[4] + d[6] + d[8]
+	even := d[1] + d[3] + d[5] + d[7]
+	if ((odd*7-even)%10+10)%10 != d[9] {
+		return false
+	}
+
+	sum := 0
+	for _, digit := range d[:10] {
+		sum += digit
+	}
+	return sum%10 == d[10]
+}
+
+// IsValidVKN checks a 10-digit Turkish tax ID number against its check digit.
+func IsValidVKN(vkn string) bool {
+	if len(vkn) != 10 {
+		return false
+	}
+
+	var d [10]int
+	for i, r := range vkn {
+		if r < '0' || r > '9' {
+			return false
+		}
+		d[i] = int(r - '0')
+	}
+
+	sum := 0
+	for i := 0; i < 9; i++ {
+		v := (d[i] + 9 - i) % 10
+		l := (v << (9 - i)) % 9
+		if v != 0 && l == 0 {
+			l = 9
+		}
+		sum += l
+	}
+	return (10-sum%10)%10 == d[9]
+}
+
+// NormalizePhone converts a Turkish phone number written in any of the common
+// forms ("0532 123 45 67", "(532) 123-4567", "+90 532 ...") to E.164, e.g.
+// "+905321234567". It reports false if the input isn't a Turkish number.
+func NormalizePhone(phone string) (string, bool) {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+		default:
+			return "", false
+		}
+	}
+
+	digits := b.String()
+	switch {
+	case strings.HasPrefix(digits, "+90"):
+		digits = digits[3:]
+	case strings.HasPrefix(digits, "0090"):
+		digits = digits[4:]
+	case strings.HasPrefix(digits, "90") && len(digits) == 12:
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0") && len(digits) == 11:
+		digits = digits[1:]
+	}
+
+	if len(digits) != 10 || strings.ContainsRune(digits, '+') {
+		return "", false
+	}
+	// Subscriber numbers start with an area code (2xx-4xx), a mobile
+	// operator prefix (5xx) or a nationwide 850 number.
+	if !strings.ContainsRune("23458", rune(digits[0])) {
+		return "", false
+	}
+
+	return "+90" + digits, true
+}
+
+// NormalizePhoneOrKeep returns the E.164 form of phone, or phone unchanged if
+// it can't be normalized. Use it once validation has already run.
+func NormalizePhoneOrKeep(phone string) string {
+	if normalized, ok := NormalizePhone(phone); ok {
+		return normalized
+	}
+	return phone
+}
+
+func registerValidations(v *validator.Validate) {
+	v.RegisterValidation("tckn", func(fl validator.FieldLevel) bool {
+		return IsValidTCKN(fl.Field().String())
+	})
+	v.RegisterValidation("vkn", func(fl validator.FieldLevel) bool {
+		return IsValidVKN(fl.Field().String())
+	})
+	v.RegisterValidation("tr_phone", func(fl validator.FieldLevel) bool {
+		_, ok := NormalizePhone(fl.Field().String())
+		return ok
+	})
+}